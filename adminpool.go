@@ -0,0 +1,91 @@
+package go_test_pg
+
+import (
+	"context"
+	"log"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Close drops databases kept for reuse by ResetTruncate strategy and
+// WithTx, and closes connections to the master database kept for creating
+// and dropping databases. Call it from TestMain after all tests are done.
+// The Pgpool may still be used after Close, connections are opened again
+// when needed.
+func (p *Pgpool) Close() {
+	for _, dbName := range p.takeFreeDBs() {
+		if err := p.dropTestDB(dbName); err != nil {
+			log.Printf("go-test-pg: can't drop database %v: %v", dbName,
+				err)
+		}
+	}
+
+	p.adminPoolM.Lock()
+	defer p.adminPoolM.Unlock()
+
+	if p.adminPool != nil {
+		p.adminPool.Close()
+		p.adminPool = nil
+	}
+}
+
+// Returns pool of connections to the master database. The pool is created
+// on first call, connections are opened on demand.
+func (p *Pgpool) getAdminPool() (*pgxpool.Pool, error) {
+	p.adminPoolM.Lock()
+	defer p.adminPoolM.Unlock()
+
+	if p.adminPool != nil {
+		return p.adminPool, nil
+	}
+
+	connConfig, err := p.connConfig("")
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := p.poolConfig(connConfig)
+	if err != nil {
+		return nil, err
+	}
+	// Template creation may hold one connection while other tests create
+	// and drop databases.
+	cfg.MaxConns = int32(p.adminConcurrency() + 1)
+	cfg.MinConns = 0
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout())
+	defer cancel()
+
+	p.adminPool, err = pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return p.adminPool, nil
+}
+
+// Calls fn with a connection to the master database from the admin pool.
+// If fn fails, the connection is closed instead of being returned to the
+// pool, as it may hold advisory locks or be in a broken state.
+func (p *Pgpool) withMasterConnection(
+	fn func(context.Context, *pgx.Conn) error) error {
+
+	pool, err := p.getAdminPool()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout())
+	defer cancel()
+
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	err = fn(ctx, conn.Conn())
+	if err != nil {
+		closeConn(conn.Conn())
+	}
+	return err
+}
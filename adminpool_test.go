@@ -0,0 +1,32 @@
+package go_test_pg
+
+import (
+	"testing"
+)
+
+func TestPgpool_Close(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+	}
+	// no-op before first use
+	dbPool.Close()
+
+	t.Run("test", func(t *testing.T) {
+		dbPool.WithEmpty(t)
+	})
+	if dbPool.adminPool == nil {
+		t.Fatal("admin pool is not created")
+	}
+	if n := dbPool.adminPool.Stat().TotalConns(); n != 1 {
+		t.Fatalf("want 1 admin connection reused, got %v", n)
+	}
+
+	dbPool.Close()
+	if dbPool.adminPool != nil {
+		t.Fatal("admin pool is not closed")
+	}
+
+	// Pgpool is usable after Close
+	dbPool.WithEmpty(t)
+}
@@ -0,0 +1,100 @@
+package go_test_pg
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AnotherPool opens a second independent pool to the test database of
+// pool, e.g. to simulate two instances of a service sharing the database.
+// Connections of the new pool report applicationName as application_name
+// and, if role is not empty, run SET ROLE role. Either may be empty.
+//
+// The new pool is closed together with pool, and its connections are
+// counted by the unreleased connections check of pool cleanup. If pool is
+// not returned by WithEmpty, NewEmpty or WithFixtures, the new pool is
+// closed when the test finishes.
+func (p *Pgpool) AnotherPool(t testing.TB, pool *pgxpool.Pool,
+	applicationName, role string) *pgxpool.Pool {
+
+	t.Helper()
+
+	cfg := pool.Config()
+	if applicationName != "" {
+		if cfg.ConnConfig.RuntimeParams == nil {
+			cfg.ConnConfig.RuntimeParams = make(map[string]string)
+		}
+		cfg.ConnConfig.RuntimeParams["application_name"] = applicationName
+	}
+	sibling := newRolePool(t, cfg, role, nil)
+	p.addSibling(pool, sibling)
+
+	t.Cleanup(func() {
+		if !p.takeSibling(pool, sibling) {
+			// Closed by cleanup of pool.
+			return
+		}
+		acquiredConns := sibling.Stat().AcquiredConns()
+		if acquiredConns > 0 {
+			t.Errorf("unreleased connections exists in another pool of "+
+				"%v: %v", pool.Config().ConnConfig.Database, acquiredConns)
+			return
+		}
+		sibling.Close()
+	})
+	return sibling
+}
+
+func (p *Pgpool) addSibling(pool, sibling *pgxpool.Pool) {
+	p.siblingsM.Lock()
+	defer p.siblingsM.Unlock()
+
+	if p.siblings == nil {
+		p.siblings = make(map[*pgxpool.Pool][]*pgxpool.Pool)
+	}
+	p.siblings[pool] = append(p.siblings[pool], sibling)
+}
+
+// Removes sibling of pool from the registry. Returns false if it is not
+// there.
+func (p *Pgpool) takeSibling(pool, sibling *pgxpool.Pool) bool {
+	p.siblingsM.Lock()
+	defer p.siblingsM.Unlock()
+
+	siblings := p.siblings[pool]
+	for i, s := range siblings {
+		if s == sibling {
+			siblings = append(siblings[:i:i], siblings[i+1:]...)
+			if len(siblings) == 0 {
+				delete(p.siblings, pool)
+			} else {
+				p.siblings[pool] = siblings
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// Removes all siblings of pool from the registry and returns them.
+func (p *Pgpool) takeSiblings(pool *pgxpool.Pool) []*pgxpool.Pool {
+	p.siblingsM.Lock()
+	defer p.siblingsM.Unlock()
+
+	siblings := p.siblings[pool]
+	delete(p.siblings, pool)
+	return siblings
+}
+
+// Returns the number of acquired connections of pool and its siblings.
+func (p *Pgpool) acquiredConns(pool *pgxpool.Pool) int32 {
+	p.siblingsM.Lock()
+	defer p.siblingsM.Unlock()
+
+	n := pool.Stat().AcquiredConns()
+	for _, s := range p.siblings[pool] {
+		n += s.Stat().AcquiredConns()
+	}
+	return n
+}
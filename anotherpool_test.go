@@ -0,0 +1,55 @@
+package go_test_pg
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPgpool_AnotherPool(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+	}
+	pool := dbPool.WithEmpty(t)
+	pool2 := dbPool.AnotherPool(t, pool, "instance-2", "")
+
+	ctx := context.Background()
+	_, err := pool.Exec(ctx, `INSERT INTO table1 (id) VALUES (1)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertRowCount(t, pool2, "table1", 1)
+
+	var appName string
+	err = pool2.QueryRow(ctx, `SELECT current_setting('application_name')`).
+		Scan(&appName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if appName != "instance-2" {
+		t.Fatalf("want application_name instance-2, got %v", appName)
+	}
+}
+
+// connections of another pool are counted by cleanup of the pool
+func TestPgpool_AnotherPool_InuseConnections(t *testing.T) {
+	var dbPool = Pgpool{}
+	pool, cleanupFn := dbPool.NewEmpty(t)
+	pool2 := dbPool.AnotherPool(t, pool, "", "")
+
+	conn, err := pool2.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = cleanupFn()
+	if !errors.Is(err, ErrUnreleasedConnections) {
+		t.Errorf("want ErrUnreleasedConnections, got %v", err)
+	}
+
+	conn.Release()
+	if err = cleanupFn(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,116 @@
+package go_test_pg
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// application_name of connections made by the library. Connections of
+// test databases append the test name and the identifier of the database.
+const appNamePrefix = "go-test-pg"
+
+// Maximum length of application_name, longer values are truncated by the
+// server.
+const maxAppNameLen = 63
+
+// Returns application_name of connections of test to database dbName, e.g.
+// "go-test-pg TestFoo/bar db 1298498081". The identifier of the database is
+// the random suffix of its name.
+func testAppName(test, dbName string) string {
+	id := dbName
+	if i := strings.LastIndexByte(dbName, '_'); i >= 0 {
+		id = dbName[i+1:]
+	}
+	suffix := " db " + id
+
+	// The server replaces other characters with question marks.
+	test = strings.Map(func(r rune) rune {
+		if r < 32 || r > 126 {
+			return '?'
+		}
+		return r
+	}, test)
+	if n := maxAppNameLen - len(appNamePrefix) - 1 - len(suffix); len(test) > n {
+		test = test[:n]
+	}
+	return appNamePrefix + " " + test + suffix
+}
+
+// Sets application_name of cfg to name unless the application name was set
+// by the user, e.g. with PGAPPNAME.
+func setAppName(cfg *pgx.ConnConfig, name string) {
+	if cfg.RuntimeParams == nil {
+		cfg.RuntimeParams = make(map[string]string)
+	}
+	current, ok := cfg.RuntimeParams["application_name"]
+	if !ok || current == appNamePrefix {
+		cfg.RuntimeParams["application_name"] = name
+	}
+}
+
+// Activity is a backend connected by the library, listed by Activity.
+type Activity struct {
+	PID int
+	// Database the backend is connected to.
+	Database string
+	// ApplicationName includes the name of the test and the identifier of
+	// its database.
+	ApplicationName string
+	// State is the state of the backend, e.g. active or idle in
+	// transaction.
+	State string
+	// Query is the current or the last query of the backend.
+	Query        string
+	BackendStart time.Time
+}
+
+// Activity returns backends of the server connected by go-test-pg from any
+// process, e.g. to find out which test left connections behind. If test is
+// not empty, only backends of tests with that name or its subtests are
+// returned. Backends are ordered by application_name.
+func (p *Pgpool) Activity(test string) ([]Activity, error) {
+	pattern := similarEscape(appNamePrefix) + "%"
+	if test != "" {
+		pattern = similarEscape(appNamePrefix+" "+test) + "[ /]%"
+	}
+
+	var activity []Activity
+	err := p.withMasterConnection(
+		func(ctx context.Context, conn *pgx.Conn) error {
+			rows, err := conn.Query(ctx, `
+SELECT pid, coalesce(datname, ''), application_name, coalesce(state, ''),
+	query, backend_start
+FROM pg_stat_activity
+WHERE application_name SIMILAR TO $1
+	AND pid <> pg_backend_pid()
+ORDER BY application_name, pid`, pattern)
+			if err != nil {
+				return err
+			}
+			var a Activity
+			_, err = pgx.ForEachRow(rows,
+				[]any{&a.PID, &a.Database, &a.ApplicationName, &a.State,
+					&a.Query, &a.BackendStart},
+				func() error {
+					activity = append(activity, a)
+					return nil
+				})
+			return err
+		})
+	return activity, err
+}
+
+// Escapes special characters of SIMILAR TO patterns in s.
+func similarEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`\%_|*+?{}()[]^$.`, r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
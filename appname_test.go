@@ -0,0 +1,78 @@
+package go_test_pg
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestTestAppName(t *testing.T) {
+	got := testAppName("TestFoo/bar", "go_test_pg_0123abcd_1298498081")
+	if want := "go-test-pg TestFoo/bar db 1298498081"; got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+
+	got = testAppName("TestFoo/"+strings.Repeat("ж", 40), "db_1")
+	if len(got) != maxAppNameLen || !strings.HasSuffix(got, "?? db 1") {
+		t.Fatalf("unexpected application name %q", got)
+	}
+}
+
+func TestSetAppName(t *testing.T) {
+	cfg, err := pgx.ParseConfig("application_name=billing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	setAppName(cfg, appNamePrefix)
+	setAppName(cfg, "go-test-pg TestFoo db 1")
+	if got := cfg.RuntimeParams["application_name"]; got != "billing" {
+		t.Fatalf("application name of the user is replaced with %q", got)
+	}
+
+	cfg, err = pgx.ParseConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	delete(cfg.RuntimeParams, "application_name")
+	setAppName(cfg, appNamePrefix)
+	setAppName(cfg, "go-test-pg TestFoo db 1")
+	if got := cfg.RuntimeParams["application_name"]; got !=
+		"go-test-pg TestFoo db 1" {
+
+		t.Fatalf("unexpected application name %q", got)
+	}
+}
+
+func TestSimilarEscape(t *testing.T) {
+	got := similarEscape("go-test-pg TestA_b/c%(1)")
+	if want := `go-test-pg TestA\_b/c\%\(1\)`; got != want {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+}
+
+func TestActivity(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+	}
+	pool := dbPool.WithEmpty(t)
+
+	conn, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Release()
+
+	activity, err := dbPool.Activity(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbName := pool.Config().ConnConfig.Database
+	if len(activity) != 1 || activity[0].Database != dbName ||
+		activity[0].PID != int(conn.Conn().PgConn().PID()) {
+
+		t.Fatalf("unexpected activity: %+v", activity)
+	}
+}
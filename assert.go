@@ -0,0 +1,154 @@
+package go_test_pg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Number of rows dumped in failure messages of assertions.
+const dumpRowsLimit = 10
+
+// Querier is the interface of *pgxpool.Pool, *pgxpool.Conn, *pgx.Conn and
+// pgx.Tx used by assertion helpers.
+type Querier interface {
+	Exec(ctx context.Context, sql string,
+		args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// AssertRowCount fails the test if table does not contain exactly n rows.
+func AssertRowCount(t testing.TB, q Querier, table string, n int) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var count int
+	err := q.QueryRow(ctx,
+		`SELECT count(*) FROM `+quoteQualified(table)).Scan(&count)
+	if err != nil {
+		t.Fatalf("can't count rows in %v: %v%v", table, err,
+			querierHint(q))
+	}
+	if count != n {
+		t.Fatalf("want %v rows in %v, got %v%v%v", n, table, count,
+			dumpRows(ctx, q, `SELECT * FROM `+quoteQualified(table)),
+			querierHint(q))
+	}
+}
+
+// AssertExists fails the test if table has no rows matching SQL condition
+// where, e.g. AssertExists(t, pool, "users", "email = $1", email).
+func AssertExists(t testing.TB, q Querier, table, where string,
+	args ...any) {
+
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	if !rowExists(t, ctx, q, table, where, args) {
+		t.Fatalf("no rows in %v where %v %v%v%v", table, where, args,
+			dumpRows(ctx, q, `SELECT * FROM `+quoteQualified(table)),
+			querierHint(q))
+	}
+}
+
+// AssertNotExists fails the test if table has rows matching SQL condition
+// where.
+func AssertNotExists(t testing.TB, q Querier, table, where string,
+	args ...any) {
+
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	if rowExists(t, ctx, q, table, where, args) {
+		t.Fatalf("unexpected rows in %v where %v %v%v%v", table, where,
+			args, dumpRows(ctx, q, `SELECT * FROM `+quoteQualified(table)+
+				` WHERE `+where, args...), querierHint(q))
+	}
+}
+
+func rowExists(t testing.TB, ctx context.Context, q Querier, table,
+	where string, args []any) bool {
+
+	t.Helper()
+
+	var exists bool
+	err := q.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM `+
+		quoteQualified(table)+` WHERE `+where+`)`, args...).Scan(&exists)
+	if err != nil {
+		t.Fatalf("can't query %v: %v%v", table, err, querierHint(q))
+	}
+	return exists
+}
+
+// Returns up to dumpRowsLimit rows of query formatted for failure message.
+func dumpRows(ctx context.Context, q Querier, query string,
+	args ...any) string {
+
+	rows, err := q.Query(ctx,
+		`SELECT * FROM (`+query+`) AS t LIMIT `+
+			fmt.Sprint(dumpRowsLimit+1), args...)
+	if err != nil {
+		return fmt.Sprintf("\ncan't dump rows: %v", err)
+	}
+	defer rows.Close()
+
+	var b strings.Builder
+	fields := rows.FieldDescriptions()
+	var n int
+	for rows.Next() {
+		n++
+		if n > dumpRowsLimit {
+			b.WriteString("\n  ...")
+			break
+		}
+		values, err := rows.Values()
+		if err != nil {
+			return fmt.Sprintf("\ncan't dump rows: %v", err)
+		}
+		b.WriteString("\n  ")
+		for i, v := range values {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(fields[i].Name)
+			b.WriteString("=")
+			b.WriteString(formatValue(v))
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Sprintf("\ncan't dump rows: %v", err)
+	}
+
+	if n == 0 {
+		return "\nno rows in table"
+	}
+	return "\nrows in table:" + b.String()
+}
+
+// Formats value returned by pgx for human-readable output.
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return fmt.Sprintf("%q", v)
+	case []byte:
+		return fmt.Sprintf(`\x%x`, v)
+	case [16]byte:
+		return fmt.Sprintf("%x-%x-%x-%x-%x", v[0:4], v[4:6], v[6:8],
+			v[8:10], v[10:16])
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,42 @@
+package go_test_pg
+
+import (
+	"testing"
+)
+
+func TestFormatValue(t *testing.T) {
+	testCases := []struct {
+		v    any
+		want string
+	}{
+		{nil, "NULL"},
+		{"it's", `"it's"`},
+		{int32(42), "42"},
+		{[]byte{0xde, 0xad}, `\xdead`},
+		{
+			[16]byte{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0,
+				0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0},
+			"12345678-9abc-def0-1234-56789abcdef0",
+		},
+	}
+	for _, tc := range testCases {
+		if got := formatValue(tc.v); got != tc.want {
+			t.Errorf("want %v, got %v", tc.want, got)
+		}
+	}
+}
+
+func TestAssertRows(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+	}
+	pool := dbPool.WithSQLs(t, []string{
+		`INSERT INTO table1 (id, name) VALUES (1, 'one'), (2, 'two')`,
+	})
+
+	AssertRowCount(t, pool, "table1", 2)
+	AssertRowCount(t, pool, "public.table1", 2)
+	AssertExists(t, pool, "table1", "name = $1", "one")
+	AssertNotExists(t, pool, "table1", "name = $1", "three")
+}
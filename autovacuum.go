@@ -0,0 +1,44 @@
+package go_test_pg
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Disables autovacuum and autoanalyze of all user tables of the database
+// with storage parameters. The autovacuum setting itself applies to the
+// whole server and can't be changed per database.
+func disableAutovacuum(ctx context.Context, conn *pgx.Conn) error {
+	rows, err := conn.Query(ctx, `
+SELECT c.oid::regclass::text
+FROM pg_class c
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE c.relkind = 'r'
+	AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+	AND n.nspname NOT LIKE 'pg\_toast%'
+	AND NOT EXISTS(
+		SELECT 1 FROM pg_depend d
+		WHERE d.classid = 'pg_class'::regclass
+			AND d.objid = c.oid
+			AND d.deptype = 'e')
+ORDER BY 1`)
+	if err != nil {
+		return err
+	}
+	tables, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		_, err = conn.Exec(ctx, `ALTER TABLE `+table+` SET (
+	autovacuum_enabled = off, toast.autovacuum_enabled = off)`)
+		if err != nil {
+			return fmt.Errorf("can't disable autovacuum of table %v: %w",
+				table, err)
+		}
+	}
+	return nil
+}
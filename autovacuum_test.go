@@ -0,0 +1,32 @@
+package go_test_pg
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDisableAutovacuum(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:          "go_test_pg",
+		SchemaFile:        "./testdata/schema1.sql",
+		DisableAutovacuum: true,
+	}
+	pool := dbPool.WithEmpty(t)
+
+	var options []string
+	err := pool.QueryRow(context.Background(),
+		`SELECT reloptions FROM pg_class WHERE oid = 'table1'::regclass`).
+		Scan(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, o := range options {
+		if o == "autovacuum_enabled=off" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("autovacuum is not disabled: %v", options)
+	}
+}
@@ -0,0 +1,75 @@
+package go_test_pg
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Backend creates and drops test databases and tells how to connect to
+// them. It allows to plug in engines that create databases differently,
+// e.g. CockroachDB or an embedded server. Template database is created by
+// Pgpool on the server from Hosts, Port and SocketDir regardless of
+// Backend.
+type Backend interface {
+	// CreateFromTemplate creates database name as a copy of template
+	// database tmpl. If tmpl is "template1", the database is empty.
+	CreateFromTemplate(ctx context.Context, name, tmpl string) error
+	// Drop drops database name created by CreateFromTemplate.
+	Drop(ctx context.Context, name string) error
+	// Connect returns configuration of connections to database name.
+	// BeforePasswordConnect and SessionSettings are applied on top of it.
+	Connect(ctx context.Context, name string) (*pgx.ConnConfig, error)
+}
+
+// postgresBackend is the default Backend. It creates databases with
+// CREATE DATABASE according to CloneStrategy.
+type postgresBackend struct {
+	p *Pgpool
+}
+
+func (b postgresBackend) CreateFromTemplate(_ context.Context, name,
+	tmpl string) error {
+
+	return b.p.createDB(name, tmpl)
+}
+
+func (b postgresBackend) Drop(_ context.Context, name string) error {
+	return b.p.dropDatabase(name)
+}
+
+func (b postgresBackend) Connect(_ context.Context,
+	name string) (*pgx.ConnConfig, error) {
+
+	return b.p.connConfig(name)
+}
+
+func (p *Pgpool) backend() Backend {
+	if p.Backend != nil {
+		return p.Backend
+	}
+	return postgresBackend{p: p}
+}
+
+// Returns configuration of connections to test database dbName.
+func (p *Pgpool) testConnConfig(dbName string) (*pgx.ConnConfig, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout())
+	defer cancel()
+
+	cfg, err := p.backend().Connect(ctx, dbName)
+	if err != nil {
+		return nil, err
+	}
+	o := p.opts()
+	if o.pgBouncer {
+		cfg.DefaultQueryExecMode = pgx.QueryExecModeSimpleProtocol
+	}
+	if o.appUser != "" {
+		cfg.User = o.appUser
+		cfg.Password = o.appPassword
+		if cfg.Password == "" {
+			cfg.Password = passfilePassword(cfg)
+		}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,65 @@
+package go_test_pg
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type fakeBackend struct {
+	created   []string
+	dropped   []string
+	connected []string
+}
+
+func (b *fakeBackend) CreateFromTemplate(_ context.Context, name,
+	tmpl string) error {
+
+	b.created = append(b.created, name+"<"+tmpl)
+	return nil
+}
+
+func (b *fakeBackend) Drop(_ context.Context, name string) error {
+	b.dropped = append(b.dropped, name)
+	return nil
+}
+
+func (b *fakeBackend) Connect(_ context.Context,
+	name string) (*pgx.ConnConfig, error) {
+
+	b.connected = append(b.connected, name)
+	return pgx.ParseConfig("host=embedded.local port=15432 dbname=" + name)
+}
+
+func TestPgpool_Backend(t *testing.T) {
+	b := &fakeBackend{}
+	p := &Pgpool{Backend: b, PgBouncer: true}
+
+	cfg, err := p.testConnConfig("db_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Host != "embedded.local" || cfg.Database != "db_1" {
+		t.Fatalf("unexpected config: %v, %v", cfg.Host, cfg.Database)
+	}
+	if cfg.DefaultQueryExecMode != pgx.QueryExecModeSimpleProtocol {
+		t.Fatal("want simple protocol in PgBouncer mode")
+	}
+
+	p.rnd = rand.New(rand.NewSource(1))
+	dbName, err := p.newDB("tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.dropDB(dbName); err != nil {
+		t.Fatal(err)
+	}
+	if len(b.created) != 1 || b.created[0] != dbName+"<tmpl" {
+		t.Fatalf("unexpected created databases: %v", b.created)
+	}
+	if len(b.dropped) != 1 || b.dropped[0] != dbName {
+		t.Fatalf("unexpected dropped databases: %v", b.dropped)
+	}
+}
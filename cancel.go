@@ -0,0 +1,88 @@
+package go_test_pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Interval between polls of pg_stat_activity.
+const activityPollInterval = 10 * time.Millisecond
+
+// SetDatabaseStatementTimeout sets statement_timeout for the whole test
+// database of pool, created by p. The setting is stored with ALTER
+// DATABASE over the admin connection, so new connections of every pool to
+// the database get it, including other pools of the test. Existing
+// connections of pool are closed, so all following queries of pool get
+// it. Zero d disables the timeout.
+func (p *Pgpool) SetDatabaseStatementTimeout(t testing.TB,
+	pool *pgxpool.Pool, d time.Duration) {
+
+	t.Helper()
+
+	dbName := pool.Config().ConnConfig.Database
+	err := p.withMasterConnection(
+		func(ctx context.Context, conn *pgx.Conn) error {
+			_, err := conn.Exec(ctx, fmt.Sprintf(
+				`ALTER DATABASE %v SET statement_timeout TO %v`,
+				quote(dbName), d.Milliseconds()))
+			return err
+		})
+	if err != nil {
+		t.Fatalf("can't set statement_timeout: %v", err)
+	}
+
+	pool.Reset()
+}
+
+// CancelQuery waits for an active query containing substring match to
+// appear in the database of pool and cancels it with pg_cancel_backend.
+// Returns PID of the backend running the query. Test fails if there is no
+// such query within timeout.
+func CancelQuery(t testing.TB, pool *pgxpool.Pool, match string,
+	timeout time.Duration) int32 {
+
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	for {
+		var pid int32
+		err := pool.QueryRow(ctx, `
+SELECT pid
+FROM pg_stat_activity
+WHERE datname = current_database()
+	AND pid <> pg_backend_pid()
+	AND state = 'active'
+	AND strpos(query, $1) > 0
+ORDER BY query_start
+LIMIT 1`, match).Scan(&pid)
+		switch {
+		case err == nil:
+			var canceled bool
+			err = pool.QueryRow(ctx, `SELECT pg_cancel_backend($1)`,
+				pid).Scan(&canceled)
+			if err != nil {
+				t.Fatalf("can't cancel query of backend %v: %v", pid, err)
+			}
+			if canceled {
+				return pid
+			}
+		case !errors.Is(err, pgx.ErrNoRows):
+			t.Fatalf("no active query matching %q: %v", match, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			t.Fatalf("no active query matching %q within %v",
+				match, timeout)
+		case <-time.After(activityPollInterval):
+		}
+	}
+}
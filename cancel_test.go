@@ -0,0 +1,52 @@
+package go_test_pg
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Error code of query_canceled error.
+const pgErrQueryCanceled = "57014"
+
+func isQueryCanceled(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgErrQueryCanceled
+}
+
+func TestCancelQuery(t *testing.T) {
+	var dbPool = Pgpool{}
+	pool := dbPool.WithEmpty(t)
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := pool.Exec(context.Background(), `SELECT pg_sleep(30)`)
+		errCh <- err
+	}()
+
+	CancelQuery(t, pool, "pg_sleep", 5*time.Second)
+
+	if err := <-errCh; !isQueryCanceled(err) {
+		t.Fatalf("want query_canceled error, got %v", err)
+	}
+}
+
+func TestSetDatabaseStatementTimeout(t *testing.T) {
+	var dbPool = Pgpool{}
+	pool := dbPool.WithEmpty(t)
+
+	dbPool.SetDatabaseStatementTimeout(t, pool, 100*time.Millisecond)
+	_, err := pool.Exec(context.Background(), `SELECT pg_sleep(5)`)
+	if !isQueryCanceled(err) {
+		t.Fatalf("want query_canceled error, got %v", err)
+	}
+
+	dbPool.SetDatabaseStatementTimeout(t, pool, 0)
+	_, err = pool.Exec(context.Background(), `SELECT pg_sleep(0.2)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,377 @@
+package go_test_pg
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Change is a row change decoded from a logical replication slot.
+type Change struct {
+	// Table is the schema-qualified name of the table, e.g. public.users.
+	Table string
+	// Op is INSERT, UPDATE, DELETE or TRUNCATE.
+	Op string
+	// Old holds the key or old values of updated and deleted rows, as far
+	// as replica identity of the table provides them.
+	Old map[string]any
+	// New holds values of inserted and updated rows.
+	New map[string]any
+}
+
+// ChangesSince consumes changes accumulated in logical replication slot
+// of the database of pool since the slot was created or the previous call,
+// and returns row changes, so a test may assert exactly which rows were
+// written. Column values are returned in text format as strings, or nil
+// for NULL; unchanged TOASTed values are omitted. The slot must use
+// test_decoding or pgoutput plugin, pgoutput changes are decoded for all
+// publications of the database. See NewLogicalReplication.
+func ChangesSince(t testing.TB, pool *pgxpool.Pool, slot string) []Change {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var plugin string
+	err := pool.QueryRow(ctx,
+		`SELECT plugin FROM pg_replication_slots WHERE slot_name = $1`,
+		slot).Scan(&plugin)
+	if err != nil {
+		t.Fatalf("can't find replication slot %v: %v", slot, err)
+	}
+
+	var changes []Change
+	switch plugin {
+	case "test_decoding":
+		changes, err = testDecodingChanges(ctx, pool, slot)
+	case "pgoutput":
+		changes, err = pgoutputChanges(ctx, pool, slot)
+	default:
+		t.Fatalf("can't decode changes of slot %v with %v plugin", slot,
+			plugin)
+	}
+	if err != nil {
+		t.Fatalf("can't get changes from slot %v: %v", slot, err)
+	}
+	return changes
+}
+
+func testDecodingChanges(ctx context.Context, pool *pgxpool.Pool,
+	slot string) ([]Change, error) {
+
+	rows, err := pool.Query(ctx,
+		`SELECT data FROM pg_logical_slot_get_changes($1, NULL, NULL)`,
+		slot)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	for _, line := range lines {
+		c, ok, err := parseTestDecoding(line)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			changes = append(changes, c)
+		}
+	}
+	return changes, nil
+}
+
+// Parses a test_decoding line like
+//
+//	table public.users: INSERT: id[integer]:1 name[text]:'alice'
+//
+// Returns false for BEGIN and COMMIT lines.
+func parseTestDecoding(line string) (Change, bool, error) {
+	if !strings.HasPrefix(line, "table ") {
+		return Change{}, false, nil
+	}
+	rest := line[len("table "):]
+
+	// The table name may contain ": " only if quoted, which is rare
+	// enough to not be supported.
+	i := strings.Index(rest, ": ")
+	if i < 0 {
+		return Change{}, false, fmt.Errorf("invalid change: %v", line)
+	}
+	c := Change{Table: rest[:i]}
+	rest = rest[i+2:]
+	i = strings.Index(rest, ":")
+	if i < 0 {
+		return Change{}, false, fmt.Errorf("invalid change: %v", line)
+	}
+	c.Op = rest[:i]
+	rest = strings.TrimPrefix(rest[i+1:], " ")
+
+	var err error
+	switch c.Op {
+	case "INSERT":
+		c.New, err = parseTestDecodingColumns(rest)
+	case "UPDATE":
+		if strings.HasPrefix(rest, "old-key: ") {
+			rest = rest[len("old-key: "):]
+			i = strings.Index(rest, " new-tuple: ")
+			if i < 0 {
+				return Change{}, false, fmt.Errorf("invalid change: %v",
+					line)
+			}
+			c.Old, err = parseTestDecodingColumns(rest[:i])
+			if err != nil {
+				break
+			}
+			rest = rest[i+len(" new-tuple: "):]
+		}
+		c.New, err = parseTestDecodingColumns(rest)
+	case "DELETE":
+		c.Old, err = parseTestDecodingColumns(rest)
+	case "TRUNCATE":
+	default:
+		return Change{}, false, fmt.Errorf("invalid change: %v", line)
+	}
+	if err != nil {
+		return Change{}, false, fmt.Errorf("invalid change: %v: %w", line,
+			err)
+	}
+	return c, true, nil
+}
+
+// Parses columns like id[integer]:1 name[text]:'alice' note[text]:null.
+func parseTestDecodingColumns(s string) (map[string]any, error) {
+	columns := make(map[string]any)
+	if s == "(no-tuple-data)" {
+		return columns, nil
+	}
+	for s != "" {
+		i := strings.Index(s, "[")
+		if i < 0 {
+			return nil, fmt.Errorf("no type of column in %q", s)
+		}
+		name := s[:i]
+		if strings.HasPrefix(name, `"`) {
+			name = strings.ReplaceAll(strings.Trim(name, `"`), `""`, `"`)
+		}
+		j := strings.Index(s[i:], "]:")
+		if j < 0 {
+			return nil, fmt.Errorf("no value of column %v", name)
+		}
+		s = s[i+j+2:]
+
+		var value any
+		var n int
+		switch {
+		case strings.HasPrefix(s, "'"):
+			v, size, err := parseQuoted(s)
+			if err != nil {
+				return nil, fmt.Errorf("column %v: %w", name, err)
+			}
+			value, n = v, size
+		default:
+			n = strings.Index(s, " ")
+			if n < 0 {
+				n = len(s)
+			}
+			switch v := s[:n]; v {
+			case "null":
+				value = nil
+			case "unchanged-toast-datum":
+				s = strings.TrimPrefix(s[n:], " ")
+				continue
+			default:
+				value = v
+			}
+		}
+		columns[name] = value
+		s = strings.TrimPrefix(s[n:], " ")
+	}
+	return columns, nil
+}
+
+// Parses string quoted with single quotes at the start of s. Returns the
+// string and the length of its quoted form.
+func parseQuoted(s string) (string, int, error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		if s[i] != '\'' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == '\'' {
+			b.WriteByte('\'')
+			i++
+			continue
+		}
+		return b.String(), i + 1, nil
+	}
+	return "", 0, errors.New("unterminated quoted value")
+}
+
+func pgoutputChanges(ctx context.Context, pool *pgxpool.Pool,
+	slot string) ([]Change, error) {
+
+	var publications *string
+	err := pool.QueryRow(ctx,
+		`SELECT string_agg(quote_ident(pubname), ',') FROM pg_publication`).
+		Scan(&publications)
+	if err != nil {
+		return nil, err
+	}
+	if publications == nil {
+		return nil, errors.New("no publications in the database")
+	}
+
+	rows, err := pool.Query(ctx, `
+SELECT data
+FROM pg_logical_slot_get_binary_changes($1, NULL, NULL,
+	'proto_version', '1', 'publication_names', $2)`, slot, *publications)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := pgx.CollectRows(rows, pgx.RowTo[[]byte])
+	if err != nil {
+		return nil, err
+	}
+	return decodePgoutput(messages)
+}
+
+// Relation of pgoutput messages.
+type pgoutputRelation struct {
+	table   string
+	columns []string
+}
+
+// Decodes pgoutput protocol version 1 messages to row changes.
+func decodePgoutput(messages [][]byte) ([]Change, error) {
+	relations := make(map[uint32]pgoutputRelation)
+	var changes []Change
+	for _, msg := range messages {
+		if len(msg) == 0 {
+			continue
+		}
+		r := &pgoutputReader{buf: msg[1:]}
+		switch msg[0] {
+		case 'R':
+			id := r.uint32()
+			ns, name := r.string(), r.string()
+			r.skip(1) // replica identity
+			rel := pgoutputRelation{table: ns + "." + name}
+			for n := r.uint16(); n > 0; n-- {
+				r.skip(1) // flags
+				rel.columns = append(rel.columns, r.string())
+				r.skip(8) // type OID and modifier
+			}
+			relations[id] = rel
+		case 'I', 'U', 'D':
+			rel, ok := relations[r.uint32()]
+			if !ok && r.err == nil {
+				return nil, errors.New("change of unknown relation")
+			}
+			c := Change{Table: rel.table}
+			for r.err == nil && len(r.buf) != 0 {
+				kind := r.byte()
+				tuple := r.tuple(rel.columns)
+				if kind == 'N' {
+					c.New = tuple
+				} else {
+					c.Old = tuple
+				}
+			}
+			c.Op = map[byte]string{
+				'I': "INSERT", 'U': "UPDATE", 'D': "DELETE"}[msg[0]]
+			changes = append(changes, c)
+		case 'T':
+			n := r.uint32()
+			r.skip(1) // options
+			for ; n > 0 && r.err == nil; n-- {
+				changes = append(changes, Change{
+					Table: relations[r.uint32()].table,
+					Op:    "TRUNCATE",
+				})
+			}
+		}
+		if r.err != nil {
+			return nil, fmt.Errorf("invalid pgoutput message %q: %w",
+				msg[0], r.err)
+		}
+	}
+	return changes, nil
+}
+
+// Reads pgoutput messages. The first error is kept in err, following
+// reads return zero values.
+type pgoutputReader struct {
+	buf []byte
+	err error
+}
+
+func (r *pgoutputReader) next(n int) []byte {
+	if r.err != nil {
+		return make([]byte, n)
+	}
+	if len(r.buf) < n {
+		r.err = errors.New("message is too short")
+		return make([]byte, n)
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *pgoutputReader) skip(n int) { r.next(n) }
+
+func (r *pgoutputReader) byte() byte { return r.next(1)[0] }
+
+func (r *pgoutputReader) uint16() uint16 {
+	return binary.BigEndian.Uint16(r.next(2))
+}
+
+func (r *pgoutputReader) uint32() uint32 {
+	return binary.BigEndian.Uint32(r.next(4))
+}
+
+func (r *pgoutputReader) string() string {
+	if r.err != nil {
+		return ""
+	}
+	i := strings.IndexByte(string(r.buf), 0)
+	if i < 0 {
+		r.err = errors.New("unterminated string")
+		return ""
+	}
+	s := string(r.buf[:i])
+	r.buf = r.buf[i+1:]
+	return s
+}
+
+// Reads TupleData with values of columns.
+func (r *pgoutputReader) tuple(columns []string) map[string]any {
+	values := make(map[string]any)
+	n := int(r.uint16())
+	for i := 0; i < n && r.err == nil; i++ {
+		name := fmt.Sprintf("column%v", i+1)
+		if i < len(columns) {
+			name = columns[i]
+		}
+		switch kind := r.byte(); kind {
+		case 'n':
+			values[name] = nil
+		case 'u':
+		case 't':
+			values[name] = string(r.next(int(r.uint32())))
+		default:
+			r.err = fmt.Errorf("unknown column kind %q", kind)
+		}
+	}
+	return values
+}
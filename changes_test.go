@@ -0,0 +1,167 @@
+package go_test_pg
+
+import (
+	"context"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestParseTestDecoding(t *testing.T) {
+	testCases := []struct {
+		line string
+		want Change
+		ok   bool
+	}{
+		{line: "BEGIN 741"},
+		{
+			line: `table public.table1: INSERT: id[integer]:1 ` +
+				`name[character varying]:'it''s' "Note"[text]:null`,
+			want: Change{Table: "public.table1", Op: "INSERT",
+				New: map[string]any{"id": "1", "name": "it's",
+					"Note": nil}},
+			ok: true,
+		},
+		{
+			line: `table public.table1: UPDATE: old-key: id[integer]:1 ` +
+				`new-tuple: id[integer]:2 ` +
+				`body[text]:unchanged-toast-datum`,
+			want: Change{Table: "public.table1", Op: "UPDATE",
+				Old: map[string]any{"id": "1"},
+				New: map[string]any{"id": "2"}},
+			ok: true,
+		},
+		{
+			line: `table public.table1: DELETE: id[integer]:2`,
+			want: Change{Table: "public.table1", Op: "DELETE",
+				Old: map[string]any{"id": "2"}},
+			ok: true,
+		},
+		{
+			line: `table public.table1: TRUNCATE: (no-flags)`,
+			want: Change{Table: "public.table1", Op: "TRUNCATE"},
+			ok:   true,
+		},
+	}
+	for _, tc := range testCases {
+		got, ok, err := parseTestDecoding(tc.line)
+		if err != nil {
+			t.Fatalf("%v: %v", tc.line, err)
+		}
+		if ok != tc.ok || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%v: want %+v, got %+v", tc.line, tc.want, got)
+		}
+	}
+
+	_, _, err := parseTestDecoding(`table public.t: INSERT: id[text]:'x`)
+	if err == nil {
+		t.Error("want error for unterminated value")
+	}
+}
+
+func TestDecodePgoutput(t *testing.T) {
+	msg := func(parts ...any) []byte {
+		var b []byte
+		for _, p := range parts {
+			switch v := p.(type) {
+			case byte:
+				b = append(b, v)
+			case uint16:
+				b = binary.BigEndian.AppendUint16(b, v)
+			case uint32:
+				b = binary.BigEndian.AppendUint32(b, v)
+			case string:
+				b = append(append(b, v...), 0)
+			case []byte:
+				b = append(b, v...)
+			}
+		}
+		return b
+	}
+	text := func(v string) []byte {
+		b := binary.BigEndian.AppendUint32([]byte{'t'}, uint32(len(v)))
+		return append(b, v...)
+	}
+
+	messages := [][]byte{
+		msg(byte('B'), make([]byte, 20)),
+		msg(byte('R'), uint32(16384), "public", "table1", byte('d'),
+			uint16(2),
+			byte(1), "id", uint32(23), uint32(0xffffffff),
+			byte(0), "name", uint32(1043), uint32(259)),
+		msg(byte('I'), uint32(16384), byte('N'), uint16(2), text("1"),
+			byte('n')),
+		msg(byte('U'), uint32(16384), byte('K'), uint16(2), text("1"),
+			byte('n'), byte('N'), uint16(2), text("2"), text("bob")),
+		msg(byte('D'), uint32(16384), byte('K'), uint16(2), text("2"),
+			byte('n')),
+		msg(byte('T'), uint32(1), byte(0), uint32(16384)),
+		msg(byte('C'), make([]byte, 25)),
+	}
+	got, err := decodePgoutput(messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Change{
+		{Table: "public.table1", Op: "INSERT",
+			New: map[string]any{"id": "1", "name": nil}},
+		{Table: "public.table1", Op: "UPDATE",
+			Old: map[string]any{"id": "1", "name": nil},
+			New: map[string]any{"id": "2", "name": "bob"}},
+		{Table: "public.table1", Op: "DELETE",
+			Old: map[string]any{"id": "2", "name": nil}},
+		{Table: "public.table1", Op: "TRUNCATE"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %+v, got %+v", want, got)
+	}
+
+	_, err = decodePgoutput([][]byte{msg(byte('I'), uint32(1))})
+	if err == nil {
+		t.Fatal("want error for change of unknown relation")
+	}
+}
+
+func TestChangesSince(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+	}
+	skipUnlessLogicalWAL(t, &dbPool)
+	pool := dbPool.WithEmpty(t)
+
+	for _, plugin := range []string{"test_decoding", "pgoutput"} {
+		r := NewLogicalReplication(t, pool, LogicalReplicationOptions{
+			Plugin:      plugin,
+			Publication: "changes_" + plugin,
+		})
+
+		_, err := pool.Exec(context.Background(),
+			`INSERT INTO table1 (id, name) VALUES (1, 'alice')`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = pool.Exec(context.Background(), `DELETE FROM table1`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []Change{
+			{Table: "public.table1", Op: "INSERT",
+				New: map[string]any{"id": "1", "name": "alice"}},
+			{Table: "public.table1", Op: "DELETE",
+				Old: map[string]any{"id": "1"}},
+		}
+		got := ChangesSince(t, pool, r.SlotName)
+		if plugin == "pgoutput" {
+			// pgoutput sends nulls for non-key columns of old tuple.
+			want[1].Old["name"] = nil
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%v: want %+v, got %+v", plugin, want, got)
+		}
+		if got = ChangesSince(t, pool, r.SlotName); len(got) != 0 {
+			t.Fatalf("%v: changes must be consumed, got %+v", plugin, got)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package go_test_pg
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Sequence number of savepoints created by Checkpoint.
+var checkpointSeq atomic.Int64
+
+// TxCheckpoint is a savepoint in a transaction returned by WithTx.
+type TxCheckpoint struct {
+	tx   pgx.Tx
+	name string
+}
+
+// Checkpoint creates a savepoint in tx, e.g. a transaction returned by
+// WithTx, so the test can explore several branches of state with
+// RollbackTo without separate databases.
+func Checkpoint(t testing.TB, tx pgx.Tx) *TxCheckpoint {
+	t.Helper()
+
+	cp := &TxCheckpoint{
+		tx:   tx,
+		name: fmt.Sprintf("go_test_pg_cp_%d", checkpointSeq.Add(1)),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	if _, err := tx.Exec(ctx, `SAVEPOINT `+cp.name); err != nil {
+		t.Fatalf("can't create checkpoint: %v", err)
+	}
+	return cp
+}
+
+// RollbackTo undoes changes made in the transaction after checkpoint cp
+// was created. The transaction may be used again even if a statement
+// failed after the checkpoint. cp is kept, so RollbackTo may be called
+// again, while checkpoints created after cp are removed.
+func RollbackTo(t testing.TB, cp *TxCheckpoint) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	_, err := cp.tx.Exec(ctx, `ROLLBACK TO SAVEPOINT `+cp.name)
+	if err != nil {
+		t.Fatalf("can't roll back to checkpoint: %v", err)
+	}
+}
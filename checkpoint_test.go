@@ -0,0 +1,35 @@
+package go_test_pg
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCheckpoint(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+	}
+	defer dbPool.Close()
+	tx := dbPool.WithTx(t)
+	ctx := context.Background()
+
+	if _, err := tx.Exec(ctx, `INSERT INTO table1 (id) VALUES (1)`); err != nil {
+		t.Fatal(err)
+	}
+	cp := Checkpoint(t, tx)
+
+	if _, err := tx.Exec(ctx, `INSERT INTO table1 (id) VALUES (2)`); err != nil {
+		t.Fatal(err)
+	}
+	AssertRowCount(t, tx, "table1", 2)
+	RollbackTo(t, cp)
+	AssertRowCount(t, tx, "table1", 1)
+
+	// The transaction is usable after a failed statement.
+	if _, err := tx.Exec(ctx, `INSERT INTO table1 (id) VALUES (1)`); err == nil {
+		t.Fatal("want unique violation")
+	}
+	RollbackTo(t, cp)
+	AssertRowCount(t, tx, "table1", 1)
+}
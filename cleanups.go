@@ -0,0 +1,62 @@
+package go_test_pg
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Test databases returned with cleanup functions which were not called yet,
+// mapped to names of tests which created them.
+var (
+	pendingCleanupsM sync.Mutex
+	pendingCleanups  = make(map[string]string)
+)
+
+// Remembers that cleanup function of database dbName created by test is
+// not called yet.
+func trackCleanup(dbName, test string) {
+	pendingCleanupsM.Lock()
+	defer pendingCleanupsM.Unlock()
+
+	pendingCleanups[dbName] = test
+}
+
+// Marks cleanup function of database dbName as called.
+func untrackCleanup(dbName string) {
+	pendingCleanupsM.Lock()
+	defer pendingCleanupsM.Unlock()
+
+	delete(pendingCleanups, dbName)
+}
+
+// CheckCleanups returns an error naming test databases created by NewEmpty
+// and NewStdEmpty whose cleanup functions were never called, together with
+// tests which created them. Call it from TestMain after m.Run to find tests
+// leaving databases behind.
+func CheckCleanups() error {
+	pendingCleanupsM.Lock()
+	defer pendingCleanupsM.Unlock()
+
+	return cleanupsError(pendingCleanups)
+}
+
+// Formats databases with not called cleanup functions as ErrCleanupNotCalled.
+func cleanupsError(pending map[string]string) error {
+	if len(pending) == 0 {
+		return nil
+	}
+	dbs := make([]string, 0, len(pending))
+	for dbName := range pending {
+		dbs = append(dbs, dbName)
+	}
+	sort.Strings(dbs)
+
+	var b strings.Builder
+	for _, dbName := range dbs {
+		fmt.Fprintf(&b, "\n\t%v created by %v", dbName, pending[dbName])
+	}
+	return fmt.Errorf("%w for %v databases:%v", ErrCleanupNotCalled,
+		len(dbs), b.String())
+}
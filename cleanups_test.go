@@ -0,0 +1,40 @@
+package go_test_pg
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCleanupsError(t *testing.T) {
+	if err := cleanupsError(nil); err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	err := cleanupsError(map[string]string{
+		"db_2": "TestB",
+		"db_1": "TestA/sub",
+	})
+	if !errors.Is(err, ErrCleanupNotCalled) {
+		t.Fatalf("want ErrCleanupNotCalled, got %v", err)
+	}
+	want := "cleanup function is not called for 2 databases:\n" +
+		"\tdb_1 created by TestA/sub\n" +
+		"\tdb_2 created by TestB"
+	if err.Error() != want {
+		t.Fatalf("want %q, got %q", want, err.Error())
+	}
+}
+
+func TestCheckCleanups(t *testing.T) {
+	var dbPool = Pgpool{BaseName: "go_test_pg"}
+	_, cleanupFn := dbPool.NewEmpty(t)
+
+	if err := CheckCleanups(); !errors.Is(err, ErrCleanupNotCalled) {
+		t.Errorf("want ErrCleanupNotCalled, got %v", err)
+	}
+	if err := cleanupFn(); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckCleanups(); err != nil {
+		t.Fatal(err)
+	}
+}
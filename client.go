@@ -0,0 +1,49 @@
+package go_test_pg
+
+import (
+	"context"
+	"testing"
+)
+
+// WithClient creates database from template database and passes its
+// connection string to newClient, so any client library (sqlx, gorm, bun)
+// may be used with the test database. newClient returns the client and a
+// function closing it. The client is closed and the database is dropped
+// when the test finishes.
+func WithClient[T any](t testing.TB, p *Pgpool,
+	newClient func(connString string) (T, func(), error)) T {
+
+	t.Helper()
+
+	dbName, err := p.createRndDB(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout())
+	defer cancel()
+
+	dsn, err := p.dsn(ctx, dbName)
+	if err != nil {
+		_ = p.dropDB(dbName)
+		t.Fatalf("can't build connection string: %v", err)
+	}
+
+	client, closeClient, err := newClient(dsn)
+	if err != nil {
+		_ = p.dropDB(dbName)
+		t.Fatalf("can't create client: %v%v", err, p.dbHint(dbName))
+	}
+
+	p.startWatchdog(t, dbName)
+	t.Cleanup(func() {
+		if closeClient != nil {
+			closeClient()
+		}
+		if err := p.releaseDB(dbName); err != nil {
+			t.Errorf("Can't drop DB %v: %v%v", dbName, err,
+				p.activityHint(dbName))
+		}
+	})
+	return client
+}
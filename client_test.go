@@ -0,0 +1,35 @@
+package go_test_pg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestWithClient(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+	}
+	var closed bool
+	// Cleanups run in reverse order, so this one runs after WithClient's.
+	t.Cleanup(func() {
+		if !closed {
+			t.Error("client is not closed")
+		}
+	})
+	conn := WithClient(t, &dbPool,
+		func(connString string) (*pgx.Conn, func(), error) {
+			conn, err := pgx.Connect(context.Background(), connString)
+			if err != nil {
+				return nil, nil, err
+			}
+			return conn, func() {
+				closed = true
+				_ = conn.Close(context.Background())
+			}, nil
+		})
+
+	AssertRowCount(t, conn, "table1", 0)
+}
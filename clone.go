@@ -0,0 +1,142 @@
+package go_test_pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// CloneStrategy defines how temporary databases are created.
+type CloneStrategy int
+
+const (
+	// CloneAuto creates databases with CREATE DATABASE ... WITH TEMPLATE.
+	// If the server refuses to clone the template with permission error
+	// (e.g. on Amazon Aurora), it switches to CloneReplay.
+	CloneAuto CloneStrategy = iota
+	// CloneTemplate always creates databases with
+	// CREATE DATABASE ... WITH TEMPLATE.
+	CloneTemplate
+	// CloneReplay creates empty databases and applies the schema file to
+	// every one of them. It is slow, but works on servers where cloning
+	// of databases is not allowed.
+	CloneReplay
+)
+
+// Error code of insufficient_privilege error.
+const pgErrInsufficientPrivilege = "42501"
+
+func (p *Pgpool) createDB(name, tmplName string) error {
+	p.m.RLock()
+	replay := p.replay || p.CloneStrategy == CloneReplay || p.Yugabyte
+	p.m.RUnlock()
+
+	if replay {
+		return p.replayDB(name)
+	}
+
+	err := p.cloneDB(name, tmplName)
+	if err == nil || p.CloneStrategy != CloneAuto || !isPermissionError(err) {
+		return err
+	}
+
+	p.m.Lock()
+	if !p.replay {
+		log.Printf("go-test-pg: can't clone template database %v: %v; "+
+			"switching to schema replay", tmplName, err)
+		p.replay = true
+	}
+	p.m.Unlock()
+
+	return p.replayDB(name)
+}
+
+func (p *Pgpool) cloneDB(name, tmplName string) error {
+	query := p.createDatabaseSQL(name, tmplName)
+
+	defer p.acquireAdmin()()
+
+	return p.withNewConnection(
+		"",
+		func(ctx context.Context, conn *pgx.Conn) error {
+			_, err := conn.Exec(ctx, query)
+			return err
+		},
+	)
+}
+
+// Creates an empty database and populates it with the schema file the
+// template database was created from.
+func (p *Pgpool) replayDB(name string) error {
+	err := p.cloneDB(name, "")
+	if err != nil {
+		return err
+	}
+
+	if !p.hasSchema() {
+		return nil
+	}
+
+	err = p.withNewConnection(
+		name,
+		func(ctx context.Context, conn *pgx.Conn) error {
+			return p.loadSchema(ctx, conn)
+		},
+	)
+	if err != nil {
+		_ = p.dropDB(name)
+		return err
+	}
+
+	return nil
+}
+
+// Blocks until the number of running CREATE DATABASE and DROP DATABASE
+// statements is below MaxAdminConcurrency. Returns function that must be
+// called when the statement is done.
+func (p *Pgpool) acquireAdmin() func() {
+	p.adminSemOnce.Do(func() {
+		p.adminSem = make(chan struct{}, p.adminConcurrency())
+	})
+
+	p.adminSem <- struct{}{}
+	return func() { <-p.adminSem }
+}
+
+// Returns error if Tablespace does not exist.
+func (p *Pgpool) checkTablespace() error {
+	return p.withNewConnection(
+		"",
+		func(ctx context.Context, conn *pgx.Conn) error {
+			var exists bool
+			err := conn.QueryRow(ctx, `
+SELECT EXISTS(SELECT 1 FROM pg_tablespace WHERE spcname = $1)`,
+				p.opts().tablespace).Scan(&exists)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				return fmt.Errorf("tablespace %v does not exist",
+					p.opts().tablespace)
+			}
+			return nil
+		},
+	)
+}
+
+func (p *Pgpool) adminConcurrency() int {
+	if n := p.opts().maxAdminConcurrency; n > 0 {
+		return n
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+func isPermissionError(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgErrInsufficientPrivilege
+}
@@ -0,0 +1,71 @@
+package go_test_pg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestPgpool_CloneReplay(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:      "go_test_pg",
+		SchemaFile:    "./testdata/schema1.sql",
+		CloneStrategy: CloneReplay,
+	}
+	db := dbPool.WithEmpty(t)
+	err := db.QueryRow(context.Background(), `SELECT id FROM table1`).Scan()
+	if err != pgx.ErrNoRows {
+		t.Fatalf("Want pgx.ErrNoRows error, got %v", err)
+	}
+}
+
+func TestPgpool_acquireAdmin(t *testing.T) {
+	p := &Pgpool{MaxAdminConcurrency: 1}
+	release := p.acquireAdmin()
+
+	acquired := make(chan struct{})
+	go func() {
+		p.acquireAdmin()()
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("semaphore is acquired twice")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	release()
+	<-acquired
+}
+
+func TestPgpool_Tablespace(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+		Tablespace: "pg_default",
+	}
+	pool := dbPool.WithEmpty(t)
+	var tablespace string
+	err := pool.QueryRow(context.Background(), `
+SELECT t.spcname
+FROM pg_database d JOIN pg_tablespace t ON t.oid = d.dattablespace
+WHERE d.datname = current_database()`).Scan(&tablespace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tablespace != "pg_default" {
+		t.Fatalf("want tablespace pg_default, got %v", tablespace)
+	}
+
+	var missing = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+		Tablespace: "go_test_pg_no_such_tablespace",
+	}
+	if _, err = missing.prepareTmpl(); err == nil {
+		t.Fatal("want error for missing tablespace")
+	}
+}
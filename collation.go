@@ -0,0 +1,192 @@
+package go_test_pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Collation is a default collation of databases created by RunCollations.
+type Collation struct {
+	// Name of the subtest.
+	Name string
+	// Locale is the libc locale of the database, e.g. "en_US.UTF-8".
+	Locale string
+	// ICULocale makes ICU the locale provider of the database, e.g. "und".
+	// It requires PostgreSQL 15 or later built with ICU. Locale is still
+	// used for character classification, if set.
+	ICULocale string
+}
+
+// Collations are the collations used by RunCollations.
+var Collations = []Collation{
+	{Name: "C", Locale: "C"},
+	{Name: "en_US", Locale: "en_US.UTF-8"},
+	{Name: "und-x-icu", ICULocale: "und"},
+}
+
+// Error codes returned by CREATE DATABASE for unknown locales and missing
+// ICU support.
+const (
+	pgErrInvalidParameterValue = "22023"
+	pgErrWrongObjectType       = "42809"
+	pgErrFeatureNotSupported   = "0A000"
+)
+
+// The first server_version_num that supports LOCALE_PROVIDER of CREATE
+// DATABASE. Earlier versions reject it with a syntax error.
+const minICUVersionNum = 150000
+
+// RunCollations runs fn as a subtest for every collation of Collations.
+// Every subtest gets a new UTF8 database with the collation as default,
+// so ORDER BY, comparisons and unique constraints on text follow it. The
+// collation of a database can't differ from its template, so databases
+// are created from template0 and the schema is loaded into every one of
+// them. Subtests of collations the server does not support are skipped.
+func (p *Pgpool) RunCollations(t *testing.T,
+	fn func(t *testing.T, pool *pgxpool.Pool, c Collation)) {
+
+	t.Helper()
+
+	for _, c := range Collations {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			pool := p.withCollation(t, c)
+			fn(t, pool, c)
+		})
+	}
+}
+
+// Creates a database with collation c and the schema, and returns a pool
+// of connections to it. The database is dropped when the test finishes.
+func (p *Pgpool) withCollation(t testing.TB, c Collation) *pgxpool.Pool {
+	t.Helper()
+
+	p.getTmpl(t)
+	if p.Backend != nil {
+		t.Fatal("collations are not supported with custom Backend")
+	}
+
+	if c.ICULocale != "" {
+		version, err := p.serverVersionNum()
+		if err != nil {
+			t.Fatalf("can't get server version: %v", err)
+		}
+		if version < minICUVersionNum {
+			t.Skipf("collation %v requires PostgreSQL 15 or later",
+				c.Name)
+		}
+	}
+
+	dbName := fmt.Sprintf("%v_collation_%x", p.baseName(), randomUint64())
+	start := time.Now()
+	err := p.createCollationDB(dbName, c)
+	if err == nil {
+		p.dbTests.Store(dbName, t.Name())
+	}
+	p.emit(EventCreate, dbName, t.Name(), start, err)
+	if isUnsupportedLocale(err) {
+		t.Skipf("collation %v is not supported: %v", c.Name, err)
+	}
+	if err != nil {
+		t.Fatalf("can't create database with collation %v: %v", c.Name,
+			err)
+	}
+	p.captureServerLog(t, dbName)
+
+	pool := p.openTestPool(t, dbName)
+	// Databases can't be reused by ResetTruncate, as other tests expect
+	// the default collation.
+	cleanupFn := p.poolCleanup(t, pool, dbName, p.dropTestDB)
+	t.Cleanup(func() {
+		if err := cleanupFn(); err != nil {
+			t.Error(err)
+		}
+	})
+	return pool
+}
+
+func (p *Pgpool) createCollationDB(dbName string, c Collation) error {
+	query := p.collationDatabaseSQL(dbName, c)
+	err := func() error {
+		defer p.acquireAdmin()()
+		return p.withNewConnection(
+			"",
+			func(ctx context.Context, conn *pgx.Conn) error {
+				_, err := conn.Exec(ctx, query)
+				return err
+			},
+		)
+	}()
+	if err != nil {
+		return err
+	}
+	registerCreatedDB(dbName, p)
+
+	if p.hasSchema() {
+		err = p.withNewConnection(
+			dbName,
+			func(ctx context.Context, conn *pgx.Conn) error {
+				return p.loadSchema(ctx, conn)
+			},
+		)
+	}
+	if err == nil {
+		err = p.applyDatabaseSettings(dbName)
+	}
+	if err != nil {
+		_ = p.dropDB(dbName)
+		return err
+	}
+	return nil
+}
+
+// Returns CREATE DATABASE statement of database name with collation c.
+func (p *Pgpool) collationDatabaseSQL(name string, c Collation) string {
+	query := `CREATE DATABASE ` + quote(name) +
+		` WITH TEMPLATE template0 ENCODING 'UTF8'`
+	if c.Locale != "" {
+		query += ` LOCALE ` + quoteLiteral(c.Locale)
+	}
+	if c.ICULocale != "" {
+		query += ` LOCALE_PROVIDER icu ICU_LOCALE ` +
+			quoteLiteral(c.ICULocale)
+	}
+	if tablespace := p.opts().tablespace; tablespace != "" {
+		query += ` TABLESPACE ` + quote(tablespace)
+	}
+	return query
+}
+
+// Returns server_version_num of the server.
+func (p *Pgpool) serverVersionNum() (int, error) {
+	var version int
+	err := p.withNewConnection(
+		"",
+		func(ctx context.Context, conn *pgx.Conn) error {
+			return conn.QueryRow(ctx,
+				`SELECT current_setting('server_version_num')::int`).
+				Scan(&version)
+		},
+	)
+	return version, err
+}
+
+func isUnsupportedLocale(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	switch pgErr.Code {
+	case pgErrInvalidParameterValue, pgErrWrongObjectType,
+		pgErrFeatureNotSupported:
+		return true
+	}
+	return false
+}
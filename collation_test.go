@@ -0,0 +1,52 @@
+package go_test_pg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestCollationDatabaseSQL(t *testing.T) {
+	p := &Pgpool{Tablespace: "fast"}
+	testCases := []struct {
+		c    Collation
+		want string
+	}{
+		{
+			Collation{Name: "C", Locale: "C"},
+			`CREATE DATABASE "db" WITH TEMPLATE template0 ENCODING 'UTF8' ` +
+				`LOCALE 'C' TABLESPACE "fast"`,
+		},
+		{
+			Collation{Name: "icu", ICULocale: "und"},
+			`CREATE DATABASE "db" WITH TEMPLATE template0 ENCODING 'UTF8' ` +
+				`LOCALE_PROVIDER icu ICU_LOCALE 'und' TABLESPACE "fast"`,
+		},
+	}
+	for _, tc := range testCases {
+		if got := p.collationDatabaseSQL("db", tc.c); got != tc.want {
+			t.Errorf("%v: want %v, got %v", tc.c.Name, tc.want, got)
+		}
+	}
+}
+
+func TestRunCollations(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+	}
+	dbPool.RunCollations(t,
+		func(t *testing.T, pool *pgxpool.Pool, c Collation) {
+			var less bool
+			err := pool.QueryRow(context.Background(),
+				`SELECT 'a'::text < 'B'::text`).Scan(&less)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := c.Name != "C"; less != want {
+				t.Fatalf("want 'a' < 'B' to be %v, got %v", want, less)
+			}
+			AssertRowCount(t, pool, "table1", 0)
+		})
+}
@@ -0,0 +1,240 @@
+package go_test_pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Returns configuration of a connection to database dbName. If dbName is
+// empty, connect to the default database from the environment (master
+// database).
+func (p *Pgpool) connConfig(dbName string) (*pgx.ConnConfig, error) {
+	connString, err := p.connString()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := pgx.ParseConfig(connString)
+	if err != nil {
+		return nil, err
+	}
+
+	if dbName != "" {
+		cfg.Database = dbName
+	}
+	p.applyGSS(cfg)
+	setAppName(cfg, appNamePrefix)
+
+	if p.opts().pgBouncer {
+		// Prepared statements do not survive switching of server
+		// connections in transaction pooling mode.
+		cfg.DefaultQueryExecMode = pgx.QueryExecModeSimpleProtocol
+	}
+
+	return cfg, nil
+}
+
+// Returns configuration of a pool of connections with configuration
+// connConfig.
+func (p *Pgpool) poolConfig(
+	connConfig *pgx.ConnConfig) (*pgxpool.Config, error) {
+
+	cfg, err := pgxpool.ParseConfig("")
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.ConnConfig = connConfig
+	cfg.BeforeConnect = p.beforeConnect
+
+	return cfg, nil
+}
+
+// Sets MinConns and MaxConns of returned pools to cfg.
+func (p *Pgpool) setPoolSize(cfg *pgxpool.Config) {
+	cfg.MinConns = p.opts().minConns
+	cfg.MaxConns = p.opts().maxConns
+	if cfg.MaxConns <= 0 {
+		cfg.MaxConns = defaultMaxConns
+	}
+	if cfg.MinConns > cfg.MaxConns {
+		cfg.MinConns = cfg.MaxConns
+	}
+}
+
+// Called before every new connection is established.
+func (p *Pgpool) beforeConnect(ctx context.Context,
+	cfg *pgx.ConnConfig) error {
+
+	if p.BeforeGSSConnect != nil {
+		if err := p.BeforeGSSConnect(ctx); err != nil {
+			return fmt.Errorf("can't refresh GSS credentials: %w", err)
+		}
+	}
+	if p.BeforePasswordConnect != nil && !p.isAppUser(cfg) {
+		password, err := p.BeforePasswordConnect(ctx)
+		if err != nil {
+			return fmt.Errorf("can't get password: %w", err)
+		}
+		cfg.Password = password
+	}
+	return nil
+}
+
+// Reports whether cfg is a configuration of AppUser connections.
+func (p *Pgpool) isAppUser(cfg *pgx.ConnConfig) bool {
+	appUser := p.opts().appUser
+	return appUser != "" && cfg.User == appUser
+}
+
+// Returns connection string built from Service, the profile DSN and Hosts,
+// Port and SocketDir fields. Settings not defined here are taken from
+// libpq environment variables.
+func (p *Pgpool) connString() (string, error) {
+	o := p.opts()
+	if p.optsErr != nil {
+		return "", p.optsErr
+	}
+	connString, err := o.hostsConnString()
+	if err != nil {
+		return "", err
+	}
+	if o.dsn != "" {
+		connString = strings.TrimSpace(o.dsn + " " + connString)
+	}
+	if o.service != "" {
+		connString = strings.TrimSpace("service=" +
+			quoteConnValue(o.service) + " " + connString)
+	}
+	return connString, nil
+}
+
+// Returns connection string built from Hosts, Port and SocketDir options.
+func (o *poolOptions) hostsConnString() (string, error) {
+	var hosts, ports []string
+
+	addHost := func(host string, port uint16) {
+		hosts = append(hosts, host)
+		if port == 0 {
+			port = o.port
+		}
+		if port == 0 {
+			ports = append(ports, "")
+		} else {
+			ports = append(ports, strconv.Itoa(int(port)))
+		}
+	}
+
+	if o.socketDir != "" {
+		if err := checkSocketDir(o.socketDir); err != nil {
+			return "", err
+		}
+		addHost(o.socketDir, 0)
+	}
+
+	for _, h := range o.hosts {
+		host, port, err := parseHost(h)
+		if err != nil {
+			return "", err
+		}
+		if strings.HasPrefix(host, "/") {
+			if err = checkSocketDir(host); err != nil {
+				return "", err
+			}
+		}
+		addHost(host, port)
+	}
+
+	if len(hosts) == 0 {
+		if o.port == 0 {
+			return "", nil
+		}
+		return "port=" + strconv.Itoa(int(o.port)), nil
+	}
+
+	connString := "host=" + quoteConnValue(strings.Join(hosts, ","))
+
+	// If all ports are empty, leave it to PGPORT or default port.
+	// Otherwise set it explicitly for hosts without port.
+	defaultPort := os.Getenv("PGPORT")
+	if defaultPort == "" {
+		defaultPort = "5432"
+	}
+	var hasPort bool
+	for i := range ports {
+		if ports[i] != "" {
+			hasPort = true
+		} else {
+			ports[i] = defaultPort
+		}
+	}
+	if hasPort {
+		connString += " port=" + quoteConnValue(strings.Join(ports, ","))
+	}
+
+	return connString, nil
+}
+
+// Parses Hosts entry. Entry is a host name, an IP address or an absolute
+// path to a directory with unix socket, optionally followed by :port.
+// IPv6 address with port must be enclosed in square brackets.
+func parseHost(h string) (string, uint16, error) {
+	if h == "" {
+		return "", 0, errors.New("empty host in Hosts")
+	}
+	if strings.ContainsAny(h, ", \t\n'\\=") {
+		return "", 0, fmt.Errorf("invalid host %q in Hosts", h)
+	}
+
+	if strings.HasPrefix(h, "/") {
+		return h, 0, nil
+	}
+
+	host, portStr, err := net.SplitHostPort(h)
+	if err != nil {
+		// No port or IPv6 address without port.
+		return strings.Trim(h, "[]"), 0, nil
+	}
+	if host == "" {
+		return "", 0, fmt.Errorf("empty host name in %q", h)
+	}
+
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil || port == 0 {
+		return "", 0, fmt.Errorf("invalid port in host %q", h)
+	}
+
+	return host, uint16(port), nil
+}
+
+func checkSocketDir(dir string) error {
+	if !filepath.IsAbs(dir) {
+		return fmt.Errorf("unix socket directory must be absolute "+
+			"path: %v", dir)
+	}
+	st, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("can't use unix socket directory %v: %w", dir, err)
+	}
+	if !st.IsDir() {
+		return fmt.Errorf("unix socket directory %v is not a directory",
+			dir)
+	}
+	return nil
+}
+
+// Quotes value for key=value connection string.
+func quoteConnValue(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `'`, `\'`)
+	return `'` + v + `'`
+}
@@ -0,0 +1,180 @@
+package go_test_pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestPgpool_connString(t *testing.T) {
+	t.Setenv("PGPORT", "")
+	sockDir := t.TempDir()
+
+	testCases := []struct {
+		name    string
+		p       *Pgpool
+		want    string
+		wantErr bool
+	}{
+		{name: "empty", p: &Pgpool{}, want: ""},
+		{name: "port only", p: &Pgpool{Port: 6432}, want: "port=6432"},
+		{
+			name: "hosts without ports",
+			p:    &Pgpool{Hosts: []string{"db1", "db2"}},
+			want: "host='db1,db2'",
+		},
+		{
+			name: "hosts with ports",
+			p: &Pgpool{
+				Hosts: []string{"db1:5433", "[::1]:5434", "db3"},
+				Port:  6432,
+			},
+			want: "host='db1,::1,db3' port='5433,5434,6432'",
+		},
+		{
+			name: "mixed ports",
+			p:    &Pgpool{Hosts: []string{"db1:5433", "db2"}},
+			want: "host='db1,db2' port='5433,5432'",
+		},
+		{
+			name: "socket dir",
+			p:    &Pgpool{SocketDir: sockDir, Hosts: []string{"::1"}},
+			want: "host='" + sockDir + ",::1'",
+		},
+		{
+			name: "service",
+			p:    &Pgpool{Service: "test", Port: 6432},
+			want: "service='test' port=6432",
+		},
+		{
+			name:    "relative socket dir",
+			p:       &Pgpool{SocketDir: "tmp"},
+			wantErr: true,
+		},
+		{
+			name:    "missing socket dir",
+			p:       &Pgpool{Hosts: []string{sockDir + "/missing"}},
+			wantErr: true,
+		},
+		{
+			name:    "bad port",
+			p:       &Pgpool{Hosts: []string{"db1:http"}},
+			wantErr: true,
+		},
+		{
+			name:    "comma in host",
+			p:       &Pgpool{Hosts: []string{"db1,db2"}},
+			wantErr: true,
+		},
+		{
+			name:    "empty host",
+			p:       &Pgpool{Hosts: []string{""}},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.p.connString()
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Fatalf("want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestPgpool_connConfig_Hosts(t *testing.T) {
+	p := Pgpool{Hosts: []string{"db1:5433", "db2:5434"}}
+	cfg, err := p.connConfig("db_name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Host != "db1" || cfg.Port != 5433 || cfg.Database != "db_name" {
+		t.Fatalf("unexpected config: %v:%v/%v",
+			cfg.Host, cfg.Port, cfg.Database)
+	}
+	if len(cfg.Fallbacks) == 0 ||
+		cfg.Fallbacks[len(cfg.Fallbacks)-1].Host != "db2" {
+		t.Fatal("expected fallback to db2")
+	}
+}
+
+func TestPgpool_beforeConnect(t *testing.T) {
+	var calls int
+	p := Pgpool{
+		BeforePasswordConnect: func(ctx context.Context) (string, error) {
+			calls++
+			return fmt.Sprintf("token%v", calls), nil
+		},
+	}
+
+	connConfig, err := p.testConnConfig("db_name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := p.poolConfig(connConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 2; i++ {
+		connCfg := cfg.ConnConfig.Copy()
+		if err = cfg.BeforeConnect(context.Background(), connCfg); err != nil {
+			t.Fatal(err)
+		}
+		if want := fmt.Sprintf("token%v", i); connCfg.Password != want {
+			t.Fatalf("want password %v, got %v", want, connCfg.Password)
+		}
+	}
+
+	p.BeforePasswordConnect = func(ctx context.Context) (string, error) {
+		return "", errors.New("vault is sealed")
+	}
+	err = p.beforeConnect(context.Background(), cfg.ConnConfig)
+	if err == nil || err.Error() != "can't get password: vault is sealed" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestPgpool_PoolSize(t *testing.T) {
+	var dbPool = Pgpool{BaseName: "go_test_pg"}
+	pool := dbPool.WithEmpty(t)
+	if got := pool.Config().MaxConns; got != defaultMaxConns {
+		t.Fatalf("want MaxConns %v, got %v", defaultMaxConns, got)
+	}
+	if got := pool.Stat().TotalConns(); got != 0 {
+		t.Fatalf("want no connections opened eagerly, got %v", got)
+	}
+
+	var sizedPool = Pgpool{BaseName: "go_test_pg", MinConns: 2,
+		MaxConns: 8}
+	pool = sizedPool.WithEmpty(t)
+	cfg := pool.Config()
+	if cfg.MinConns != 2 || cfg.MaxConns != 8 {
+		t.Fatalf("want MinConns/MaxConns 2/8, got %v/%v", cfg.MinConns,
+			cfg.MaxConns)
+	}
+}
+
+func TestPgpool_setPoolSize(t *testing.T) {
+	cfg, err := pgxpool.ParseConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	(&Pgpool{MinConns: 10}).setPoolSize(cfg)
+	if cfg.MinConns != defaultMaxConns || cfg.MaxConns != defaultMaxConns {
+		t.Fatalf("want MinConns/MaxConns %v/%v, got %v/%v",
+			defaultMaxConns, defaultMaxConns, cfg.MinConns, cfg.MaxConns)
+	}
+}
@@ -0,0 +1,339 @@
+// Package container starts PostgreSQL servers in Docker containers for
+// tests that need a whole cluster rather than a test database, like
+// physical streaming replication. The docker command must be in PATH.
+package container
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os/exec"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// DefaultImage is the image of servers when ReplicationOptions.Image
+	// is empty.
+	DefaultImage = "postgres:14.2"
+	// Password of the postgres user of started servers.
+	password = "postgres"
+	// Time given to start or promote a server.
+	startTimeout = 2 * time.Minute
+	// Interval between readiness checks.
+	pollInterval = 200 * time.Millisecond
+)
+
+// ReplicationOptions configures StartStreamingReplication.
+type ReplicationOptions struct {
+	// Image is the Docker image of the primary and standby servers.
+	// Default is DefaultImage.
+	Image string
+	// Settings are passed to both servers as -c name=value arguments.
+	Settings map[string]string
+}
+
+// StreamingReplication is a primary server and its hot standby replicating
+// it with physical streaming replication.
+type StreamingReplication struct {
+	// PrimaryDSN is the connection string of the postgres database of the
+	// primary server.
+	PrimaryDSN string
+	// StandbyDSN is the connection string of the postgres database of the
+	// standby server. It is read only until Promote is called.
+	StandbyDSN string
+
+	network string
+	primary string
+	standby string
+}
+
+// StartStreamingReplication starts a primary server and a hot standby
+// created from its base backup in containers on a private Docker network,
+// and waits until the standby accepts connections. Ports of both servers
+// are published on 127.0.0.1. Containers and the network are removed when
+// the test finishes.
+func StartStreamingReplication(t testing.TB,
+	opts ReplicationOptions) *StreamingReplication {
+
+	t.Helper()
+
+	image := opts.Image
+	if image == "" {
+		image = DefaultImage
+	}
+	prefix := "gotestpg_" + randomHex()
+	r := &StreamingReplication{
+		network: prefix,
+		primary: prefix + "_primary",
+		standby: prefix + "_standby",
+	}
+	t.Cleanup(func() { r.remove(t) })
+
+	if _, err := docker("network", "create", r.network); err != nil {
+		t.Fatalf("can't create network: %v", err)
+	}
+
+	args := []string{"run", "--detach", "--name", r.primary,
+		"--network", r.network, "--network-alias", "primary",
+		"--publish", "127.0.0.1::5432",
+		"--env", "POSTGRES_PASSWORD=" + password,
+		image, "postgres", "-c", "wal_level=replica",
+		"-c", "max_wal_senders=10", "-c", "hot_standby=on"}
+	args = append(args, settingArgs(opts.Settings)...)
+	if _, err := docker(args...); err != nil {
+		t.Fatalf("can't start primary: %v", err)
+	}
+
+	var err error
+	r.PrimaryDSN, err = containerDSN(r.primary)
+	if err != nil {
+		t.Fatalf("can't get address of primary: %v", err)
+	}
+	if err = waitReady(r.PrimaryDSN, false); err != nil {
+		t.Fatalf("primary is not ready: %v%v", err,
+			containerLogs(r.primary))
+	}
+	if err = allowReplication(r.primary, r.PrimaryDSN); err != nil {
+		t.Fatalf("can't allow replication connections: %v", err)
+	}
+
+	// The entrypoint of the image initializes an empty data directory, so
+	// the standby is started with a shell that fills it with a base backup
+	// of the primary first.
+	script := `until pg_basebackup -h primary -U postgres -D "$PGDATA" ` +
+		`-R -X stream; do rm -rf "$PGDATA"/*; sleep 1; done; ` +
+		`chmod 700 "$PGDATA"; exec postgres -c hot_standby=on`
+	for _, arg := range settingArgs(opts.Settings) {
+		script += " " + shellQuote(arg)
+	}
+	args = []string{"run", "--detach", "--name", r.standby,
+		"--network", r.network, "--publish", "127.0.0.1::5432",
+		"--env", "PGPASSWORD=" + password,
+		"--user", "postgres", "--entrypoint", "sh",
+		image, "-c", script}
+	if _, err = docker(args...); err != nil {
+		t.Fatalf("can't start standby: %v", err)
+	}
+
+	r.StandbyDSN, err = containerDSN(r.standby)
+	if err != nil {
+		t.Fatalf("can't get address of standby: %v", err)
+	}
+	if err = waitReady(r.StandbyDSN, true); err != nil {
+		t.Fatalf("standby is not ready: %v%v", err,
+			containerLogs(r.standby))
+	}
+	return r
+}
+
+// WaitReplicated waits until the standby replays all WAL written by the
+// primary so far, so changes committed on the primary are visible on the
+// standby.
+func (r *StreamingReplication) WaitReplicated(t testing.TB) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), startTimeout)
+	defer cancel()
+
+	var lsn string
+	err := queryRow(ctx, r.PrimaryDSN, `SELECT pg_current_wal_lsn()::text`,
+		&lsn)
+	if err != nil {
+		t.Fatalf("can't get WAL position of primary: %v", err)
+	}
+
+	for {
+		var replayed bool
+		err = queryRow(ctx, r.StandbyDSN, `
+SELECT pg_last_wal_replay_lsn() >= $1::pg_lsn`, &replayed, lsn)
+		if err != nil {
+			t.Fatalf("can't get WAL position of standby: %v", err)
+		}
+		if replayed {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			t.Fatalf("standby did not replay WAL up to %v", lsn)
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// StopPrimary stops the primary server, e.g. to test failover.
+func (r *StreamingReplication) StopPrimary(t testing.TB) {
+	t.Helper()
+
+	if _, err := docker("stop", r.primary); err != nil {
+		t.Fatalf("can't stop primary: %v", err)
+	}
+}
+
+// Promote promotes the standby to a primary and waits until it accepts
+// writes.
+func (r *StreamingReplication) Promote(t testing.TB) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), startTimeout)
+	defer cancel()
+
+	var promoted bool
+	err := queryRow(ctx, r.StandbyDSN, `SELECT pg_promote(true, $1)`,
+		&promoted, int(startTimeout.Seconds()))
+	if err != nil {
+		t.Fatalf("can't promote standby: %v", err)
+	}
+	if !promoted {
+		t.Fatal("standby is not promoted")
+	}
+}
+
+// Removes containers and the network. Errors are reported, as leftovers
+// keep running on the host.
+func (r *StreamingReplication) remove(t testing.TB) {
+	for _, name := range []string{r.standby, r.primary} {
+		out, err := docker("rm", "--force", "--volumes", name)
+		if err != nil && !strings.Contains(out, "No such container") {
+			t.Errorf("can't remove container %v: %v", name, err)
+		}
+	}
+	out, err := docker("network", "rm", r.network)
+	if err != nil && !strings.Contains(out, "not found") {
+		t.Errorf("can't remove network %v: %v", r.network, err)
+	}
+}
+
+// Allows replication connections from other containers to the server in
+// container name, which pg_hba.conf of the image allows only locally. md5
+// uses SCRAM if the password is stored as SCRAM verifier.
+func allowReplication(name, dsn string) error {
+	_, err := docker("exec", "--user", "postgres", name, "sh", "-c",
+		`echo "host replication all all md5" `+
+			`>> "$PGDATA/pg_hba.conf"`)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), startTimeout)
+	defer cancel()
+
+	var reloaded bool
+	return queryRow(ctx, dsn, `SELECT pg_reload_conf()`, &reloaded)
+}
+
+// Runs docker with args and returns its trimmed standard output.
+func docker(args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), startTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		return msg, fmt.Errorf("docker %v failed: %v: %w", args[0], msg,
+			err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// Returns the connection string of the postgres database of the server in
+// container name by its published port.
+func containerDSN(name string) (string, error) {
+	out, err := docker("port", name, "5432/tcp")
+	if err != nil {
+		return "", err
+	}
+	// Every published address is on its own line.
+	host, port, err := net.SplitHostPort(strings.Split(out, "\n")[0])
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("host=%v port=%v user=postgres password=%v "+
+		"dbname=postgres sslmode=disable", host, port, password), nil
+}
+
+// Waits until the server of dsn accepts connections and is in recovery if
+// standby is true, or is not in recovery otherwise.
+func waitReady(dsn string, standby bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), startTimeout)
+	defer cancel()
+
+	for {
+		var inRecovery bool
+		err := queryRow(ctx, dsn, `SELECT pg_is_in_recovery()`, &inRecovery)
+		if err == nil && inRecovery == standby {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if err == nil {
+				err = fmt.Errorf("pg_is_in_recovery() is %v", inRecovery)
+			}
+			return err
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// Runs query on a new connection to dsn and scans its only row to dest.
+func queryRow(ctx context.Context, dsn, query string, dest any,
+	args ...any) error {
+
+	conn, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	return conn.QueryRow(ctx, query, args...).Scan(dest)
+}
+
+// Returns logs of container name to add to error messages.
+func containerLogs(name string) string {
+	out, err := exec.Command("docker", "logs", "--tail", "20",
+		name).CombinedOutput()
+	if err != nil {
+		return ""
+	}
+	return "\n" + strings.TrimSpace(string(out))
+}
+
+// Returns -c arguments of postgres for settings in order of names.
+func settingArgs(settings map[string]string) []string {
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	args := make([]string, 0, 2*len(settings))
+	for _, name := range names {
+		args = append(args, "-c", name+"="+settings[name])
+	}
+	return args
+}
+
+// Quotes s for sh.
+func shellQuote(s string) string {
+	return `'` + strings.ReplaceAll(s, `'`, `'\''`) + `'`
+}
+
+func randomHex() string {
+	var b [6]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b[:])
+}
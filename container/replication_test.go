@@ -0,0 +1,72 @@
+package container
+
+import (
+	"context"
+	"os/exec"
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestSettingArgs(t *testing.T) {
+	got := settingArgs(map[string]string{"work_mem": "8MB", "fsync": "off"})
+	want := []string{"-c", "fsync=off", "-c", "work_mem=8MB"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	if got, want := shellQuote("a='b c'"), `'a='\''b c'\'''`; got != want {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+}
+
+func TestStartStreamingReplication(t *testing.T) {
+	if _, err := exec.LookPath("docker"); err != nil {
+		t.Skip("docker is not found")
+	}
+
+	r := StartStreamingReplication(t, ReplicationOptions{})
+	ctx := context.Background()
+
+	primary, err := pgx.Connect(ctx, r.PrimaryDSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer primary.Close(ctx)
+	_, err = primary.Exec(ctx, `
+CREATE TABLE t (id INT PRIMARY KEY);
+INSERT INTO t VALUES (1)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.WaitReplicated(t)
+
+	standby, err := pgx.Connect(ctx, r.StandbyDSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer standby.Close(ctx)
+	var inRecovery bool
+	var n int
+	err = standby.QueryRow(ctx,
+		`SELECT pg_is_in_recovery(), (SELECT count(*) FROM t)`).
+		Scan(&inRecovery, &n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !inRecovery || n != 1 {
+		t.Fatalf("want standby in recovery with 1 row, got %v, %v",
+			inRecovery, n)
+	}
+
+	r.StopPrimary(t)
+	r.Promote(t)
+	_, err = standby.Exec(ctx, `INSERT INTO t VALUES (2)`)
+	if err != nil {
+		t.Fatalf("promoted standby must accept writes: %v", err)
+	}
+}
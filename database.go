@@ -4,9 +4,10 @@ import (
 	"context"
 	"crypto/md5"
 	"database/sql"
-	"encoding/binary"
+	"database/sql/driver"
 	"encoding/hex"
 	"fmt"
+	"io"
 	"log"
 	"math/rand"
 	"os"
@@ -15,63 +16,360 @@ import (
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/jackc/pgx/v5/tracelog"
-	"github.com/pkg/errors"
 )
 
 const defaultTimeout = 30 * time.Second
 
+// Default maximum number of connections of returned pools.
+const defaultMaxConns = 4
+
+// Random generator for names of cluster-wide objects, like replication
+// slots, that must not clash between test processes.
+var (
+	globalRandM sync.Mutex
+	globalRand  = rand.New(rand.NewSource(
+		time.Now().UnixNano() + int64(os.Getpid())))
+)
+
+func randomUint64() uint64 {
+	globalRandM.Lock()
+	defer globalRandM.Unlock()
+	return globalRand.Uint64()
+}
+
 type Fixture struct {
 	Query  string
 	Params []interface{}
+	// ExpectError is a regular expression the error of the query must
+	// match, e.g. `violates check constraint` or `SQLSTATE 23505`. If set,
+	// the fixture fails if the query succeeds, which allows to test
+	// constraints and row level security declaratively.
+	ExpectError string
 }
 
 type Pgpool struct {
 	// BaseName is the prefix of template and temporary databases.
 	// Default is dbtestpg.
 	BaseName string
+	// Shard identifies the CI job or shard when several of them share a
+	// server. It is appended to BaseName, so jobs do not share databases
+	// and KeepTemplates drops only templates of the same shard. Default is
+	// the value of GO_TEST_PG_SHARD environment variable.
+	Shard string
+	// Profile is the name of the profile in go-test-pg.toml file at the
+	// root of the module, which fills connection settings and options left
+	// empty, so they may differ between developers and CI without code
+	// changes. Default is the value of GO_TEST_PG_PROFILE environment
+	// variable or default key of the file. The profile is resolved once,
+	// before the first connection; fields of Pgpool are not modified.
+	Profile string
 	// Name of schema file. If empty, create empty database.
 	SchemaFile string // schema file name
+	// SchemaParts are applied to the template database after SchemaFile.
+	// Parts that do not depend on each other are applied concurrently
+	// over separate connections.
+	SchemaParts []SchemaPart
+	// TemplateFrom copies the schema and data of selected tables from
+	// another running database to the template database before SchemaFile
+	// is applied.
+	TemplateFrom *TemplateFromDatabase
+	// SchemaLints are checks run over the loaded schema before the
+	// template database is created, e.g. LintForeignKeyIndexes. If any
+	// problems are found, the template is not created and tests fail with
+	// ErrSchemaLint listing them.
+	SchemaLints []SchemaLint
 	// If true, skip all database tests.
 	Skip bool
+	// RolesFile is an SQL file with cluster-level objects (roles, grants)
+	// applied on master database before template creation. It is applied
+	// once per test process, so it must be idempotent.
+	RolesFile string
+	// Roles are created on master database before template creation if
+	// they do not exist yet.
+	Roles []RoleSpec
+	// If true, all tables of the template database are turned to UNLOGGED
+	// after the schema is loaded. It reduces WAL overhead in tests.
+	UnloggedTables bool
+	// If true, autovacuum and autoanalyze are disabled for all tables of
+	// the template database with storage parameters, so they do not kick
+	// in during timing-sensitive tests and benchmarks. Tables created by
+	// tests are not affected.
+	DisableAutovacuum bool
+	// MockNow installs testclock schema with now() function into the
+	// template database and puts it first in search_path of returned pools.
+	// now() may be frozen with SetTestTime.
+	MockNow bool
+	// CaptureDDL installs event triggers into the template database
+	// recording DDL executed by tests, e.g. dynamically created partitions
+	// or tenant schemas. Recorded commands are returned by DDLLog. Event
+	// triggers may be created by superuser only.
+	CaptureDDL bool
+	// SessionSettings are set on every connection of returned pools,
+	// e.g. {"timezone": "UTC", "app.tenant_id": "42"}.
+	SessionSettings map[string]string
+	// SearchPath is the search_path of connections of returned pools and
+	// of the connection loading the schema, e.g. {"app", "public"}, so
+	// tables of a non-public schema are found without qualification.
+	// search_path in SessionSettings takes precedence.
+	SearchPath []string
+	// Hosts is a list of servers to connect to. Every entry is a host name,
+	// an IP address or an absolute path to a directory with unix socket,
+	// optionally followed by ":port". If empty, PGHOST environment
+	// variable is used.
+	Hosts []string
+	// Port is used for Hosts without explicit port. If zero, PGPORT
+	// environment variable or default port is used.
+	Port uint16
+	// SocketDir is a directory with server unix socket. It is tried
+	// before Hosts.
+	SocketDir string
+	// Service is the name of a libpq connection service defined in
+	// pg_service.conf, e.g. by DBAs. Hosts, Port and SocketDir take
+	// precedence over the service. Default is the value of PGSERVICE
+	// environment variable.
+	Service string
+	// BeforePasswordConnect is called before every new connection to get
+	// a password, e.g. a short-lived IAM token or a credential issued by
+	// Vault. It is used for both administrative connections and connections
+	// of returned pools, unless AppUser is set. If nil, password from the
+	// environment is used.
+	BeforePasswordConnect func(ctx context.Context) (string, error)
+	// KerberosSrvName and KerberosSpn are the Kerberos service name and
+	// service principal name of the server for GSSAPI authentication, like
+	// krbsrvname and krbspn connection parameters. Default is "postgres".
+	KerberosSrvName string
+	KerberosSpn     string
+	// GSSProvider enables GSSAPI authentication, e.g. with
+	// github.com/otan/gopgkrb5, or SSPI authentication on Windows with a
+	// provider implementing pgconn.GSS on top of SSPI. pgx supports one
+	// provider per process, the first one set on any Pgpool is registered
+	// on first connection.
+	GSSProvider pgconn.NewGSSFunc
+	// BeforeGSSConnect is called before every new connection, including
+	// administrative ones, e.g. to renew the Kerberos ticket with kinit
+	// when it is about to expire. The connection fails if it returns an
+	// error.
+	BeforeGSSConnect func(ctx context.Context) error
+	// AppUser and AppPassword are credentials of connections of returned
+	// pools and handles, so tests run with privileges of the application
+	// rather than of the user creating databases. Grant privileges on
+	// tables to AppUser in the schema file. BeforePasswordConnect is not
+	// used for AppUser connections. If AppPassword is empty, it is looked
+	// up in the password file (.pgpass). If AppUser is empty, credentials
+	// from the environment are used for all connections.
+	AppUser     string
+	AppPassword string
+	// PgBouncer enables compatibility with PgBouncer in transaction
+	// pooling mode. Queries are sent using simple protocol, and
+	// SessionSettings are set as database defaults instead of being set on
+	// every connection.
+	PgBouncer bool
+	// SlowQueryThreshold enables the report of queries running longer than
+	// the threshold. The report is logged with t.Log when the test
+	// finishes.
+	SlowQueryThreshold time.Duration
+	// LogSQL logs every statement run on returned pools and transactions
+	// with t.Log, with its duration and arguments. Handles returned by
+	// WithStd* functions log statements regardless of this option.
+	LogSQL bool
+	// RedactSQLArgs hides arguments of statements logged with LogSQL.
+	RedactSQLArgs bool
+	// CloneStrategy defines how temporary databases are created from the
+	// template database. Default is CloneAuto.
+	CloneStrategy CloneStrategy
+	// Tablespace in which temporary databases are created, e.g. one on
+	// tmpfs. The tablespace must exist. Template database is created in
+	// the default tablespace.
+	Tablespace string
+	// ResetStrategy defines how databases are cleaned between tests.
+	// Default is ResetClone.
+	ResetStrategy ResetStrategy
+	// DatabaseSettings are set with ALTER DATABASE ... SET on every
+	// created database, e.g. {"synchronous_commit": "off"}.
+	DatabaseSettings map[string]string
+	// ConnectionLimit sets CONNECTION LIMIT of created databases, so a
+	// runaway test can't exhaust max_connections of the server. It should
+	// leave room for MaxConns connections of every pool of the test
+	// database. Superusers are not limited. Default is no limit.
+	ConnectionLimit int
+	// IdleInTransactionTimeout sets idle_in_transaction_session_timeout of
+	// created databases, so sessions of a test stuck in a transaction are
+	// terminated and release their locks. DatabaseSettings take
+	// precedence.
+	IdleInTransactionTimeout time.Duration
+	// AsyncDrop makes test cleanup return without waiting for DROP
+	// DATABASE. Databases are dropped in background, call FlushDrops from
+	// TestMain to wait for them before the process exits.
+	AsyncDrop bool
+	// MaxAdminConcurrency limits the number of CREATE DATABASE and DROP
+	// DATABASE statements run at the same time, so parallel tests do not
+	// exhaust server connections. Default is GOMAXPROCS.
+	MaxAdminConcurrency int
+	// MinConns is the number of connections returned pools keep open.
+	// Default is 0: connections are opened when the test needs them. A
+	// non-zero default would keep reopening connections in background
+	// after the pool is reset, which races with Snapshot, Restore and
+	// CloneDB terminating connections to the database.
+	MinConns int32
+	// MaxConns is the maximum number of connections of returned pools.
+	// Default is 4.
+	MaxConns int32
+	// KeepTemplates is the number of template databases with BaseName
+	// kept on the server. When the schema changes, e.g. after switching
+	// git branches, templates not used recently are dropped. Templates
+	// used by any process within the last hour are kept, as other test
+	// processes may still clone them. Default is 0: templates are never
+	// dropped.
+	KeepTemplates int
+	// DetectLeaks fails the test if prepared statements or cursors are
+	// left open on connections of the returned pool when the test
+	// finishes. It is not supported for handles returned by WithStd*
+	// functions.
+	DetectLeaks bool
+	// RollbackLingeringTx makes cleanup terminate sessions left idle in
+	// transaction by the test, which rolls the transactions back, and drop
+	// the database with a warning instead of failing on unreleased
+	// connections. Connections in use for other reasons still fail it.
+	RollbackLingeringTx bool
+	// ServerLogStatement sets log_statement of test databases, e.g. "all",
+	// so statements run by the test are written to the server log.
+	ServerLogStatement string
+	// ServerLogMinDuration sets log_min_duration_statement of test
+	// databases. Default is the server setting.
+	ServerLogMinDuration time.Duration
+	// CaptureServerLogs attaches lines of the server log written for the
+	// test database to the output of failed tests. The server must run
+	// with logging_collector, preferably with csvlog destination, and the
+	// user must be allowed to call pg_read_file.
+	CaptureServerLogs bool
+	// OnEvent is called on lifecycle events: template readiness, creation
+	// of test databases, loading of fixtures and drops. It is called
+	// concurrently from parallel tests and background drops.
+	OnEvent func(Event)
+	// OnSchemaProgress is called every 30 seconds while a schema file is
+	// applied, and once when it is applied. If nil, progress of schema
+	// files taking longer than that is logged, so CI jobs building large
+	// templates do not look hung.
+	OnSchemaProgress func(SchemaProgress)
+	// StdConnector opens connections of sql.DB handles returned by WithStd*
+	// functions with a driver other than pgx, e.g. lib/pq:
+	//
+	//	func(dsn string) (driver.Connector, error) {
+	//		return pq.NewConnector(dsn)
+	//	}
+	//
+	// dsn is a keyword/value connection string of the test database with
+	// SessionSettings passed in options parameter. Query logging and
+	// SlowQueryThreshold are not supported for custom connectors.
+	StdConnector func(dsn string) (driver.Connector, error)
+	// Yugabyte tunes the pool for YugabyteDB, which can't clone databases:
+	// the template database is not created, and every test database is
+	// created empty and populated with the schema. Timeouts of database
+	// creation and fixture loading are raised to 5 minutes.
+	Yugabyte bool
+	// TimescaleDB creates timescaledb extension in the template database
+	// before the schema is loaded. The version of the extension is a part
+	// of the template checksum, so the template is rebuilt when the
+	// extension is upgraded. Test databases are dropped WITH (FORCE), as
+	// the background job scheduler of the extension connects to them.
+	TimescaleDB bool
+	// Backend creates and drops test databases. Default is CREATE DATABASE
+	// on the server the template database is created on.
+	Backend Backend
+	// PostGIS creates postgis extension in the template database before
+	// the schema is loaded. Its version is a part of the template
+	// checksum.
+	PostGIS bool
+	// PostGISSRIDs limits spatial_ref_sys table of the template database to
+	// the listed spatial reference systems. The table has thousands of rows
+	// otherwise, which are copied to every test database.
+	PostGISSRIDs []int
+	// PgVector creates vector extension in the template database before
+	// the schema is loaded.
+	PgVector bool
+	// Colocated creates test databases with COLOCATION = true in Yugabyte
+	// mode, so all tables of a database share one tablet and DDL is faster.
+	Colocated bool
 
 	m    sync.RWMutex
 	err  error
 	tmpl string
 	rnd  *rand.Rand
+	// Set when template cloning failed with permission error in CloneAuto
+	// mode. All following databases are created with schema replay.
+	replay bool
+	// Schema dump of TemplateFrom database the template is built from.
+	sourceSchema []byte
+	// Options with the profile applied, resolved by opts.
+	optsOnce sync.Once
+	options  *poolOptions
+	optsErr  error
+
+	// Limits concurrency of CREATE DATABASE and DROP DATABASE.
+	adminSemOnce sync.Once
+	adminSem     chan struct{}
+
+	// ResetStrategy resolved on first use.
+	strategyOnce sync.Once
+	strategy     ResetStrategy
+	// Clean databases that may be reused by following tests: truncated by
+	// ResetTruncate and left by WithTx.
+	freeDBsM  sync.Mutex
+	freeDBs   []string
+	freeTxDBs []string
+
+	// Pool of connections to the master database.
+	adminPoolM sync.Mutex
+	adminPool  *pgxpool.Pool
+
+	// Names of tests by names of their databases, for events.
+	dbTests sync.Map
+
+	// Pools opened with AnotherPool by pools they share the database with.
+	siblingsM sync.Mutex
+	siblings  map[*pgxpool.Pool][]*pgxpool.Pool
 }
 
 // WithFixtures creates database from template database, and initializes it
 // with fixtures from `fixtures` array
 func (p *Pgpool) WithFixtures(t testing.TB, fixtures []Fixture) *pgxpool.Pool {
 	pool := p.WithEmpty(t)
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout())
 	defer cancel()
+	start := time.Now()
 	for i, f := range fixtures {
-		if _, err := pool.Exec(ctx, f.Query, f.Params...); err != nil {
+		_, err := pool.Exec(ctx, f.Query, f.Params...)
+		if err = f.check(err); err != nil {
 			t.Fatalf(
-				"can't load fixture at idx %v: %+v",
-				i, errors.WithStack(err),
+				"can't load fixture at idx %v: %v%v",
+				i, err, querierHint(pool),
 			)
 		}
 	}
+	p.emit(EventFixtures, pool.Config().ConnConfig.Database, t.Name(),
+		start, nil)
 	return pool
 }
 
 // WithStdFixtures creates database from template database, and initializes it
 // with fixtures from `fixtures` array
 func (p *Pgpool) WithStdFixtures(t testing.TB, fixtures []Fixture) *sql.DB {
-	db := p.WithStdEmpty(t)
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	db, dbName := p.withStdEmpty(t)
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout())
 	defer cancel()
+	start := time.Now()
 	for i, f := range fixtures {
-		if _, err := db.ExecContext(ctx, f.Query, f.Params...); err != nil {
-			t.Fatalf("can't load fixture at idx %v: %+v",
-				i, errors.WithStack(err))
+		_, err := db.ExecContext(ctx, f.Query, f.Params...)
+		if err = f.check(err); err != nil {
+			t.Fatalf("can't load fixture at idx %v: %v%v",
+				i, err, p.dbHint(dbName))
 		}
 	}
+	p.emit(EventFixtures, dbName, t.Name(), start, nil)
 	return db
 }
 
@@ -79,31 +377,36 @@ func (p *Pgpool) WithStdFixtures(t testing.TB, fixtures []Fixture) *sql.DB {
 // with fixtures from `sqls` array
 func (p *Pgpool) WithSQLs(t testing.TB, sqls []string) *pgxpool.Pool {
 	pool := p.WithEmpty(t)
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout())
 	defer cancel()
+	start := time.Now()
 	for i, s := range sqls {
 		if _, err := pool.Exec(ctx, s); err != nil {
 			t.Fatalf(
-				"can't load fixture at idx %v: %+v",
-				i, errors.WithStack(err),
+				"can't load fixture at idx %v: %v%v",
+				i, err, querierHint(pool),
 			)
 		}
 	}
+	p.emit(EventFixtures, pool.Config().ConnConfig.Database, t.Name(),
+		start, nil)
 	return pool
 }
 
 // WithStdSQLs creates database from template database, and initializes it
 // with fixtures from `sqls` array
 func (p *Pgpool) WithStdSQLs(t testing.TB, sqls []string) *sql.DB {
-	db := p.WithStdEmpty(t)
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	db, dbName := p.withStdEmpty(t)
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout())
 	defer cancel()
+	start := time.Now()
 	for i, s := range sqls {
 		if _, err := db.ExecContext(ctx, s); err != nil {
-			t.Fatalf("can't load fixture at idx %v: %+v",
-				i, errors.WithStack(err))
+			t.Fatalf("can't load fixture at idx %v: %v%v",
+				i, err, p.dbHint(dbName))
 		}
 	}
+	p.emit(EventFixtures, dbName, t.Name(), start, nil)
 	return db
 }
 
@@ -114,53 +417,162 @@ func (p *Pgpool) getTmpl(t testing.TB) string {
 		t.Skip("Skip database tests")
 	}
 
+	tmpl, err := p.prepareTmpl()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	return tmpl
+}
+
+// Creates template database on first call and returns its name. The error
+// of the first call is returned on all following calls.
+func (p *Pgpool) prepareTmpl() (string, error) {
 	p.m.RLock()
 	err := p.err
 	tmpl := p.tmpl
 	p.m.RUnlock()
 
-	if err != nil {
-		t.Fatal(err)
+	if err != nil || tmpl != "" {
+		return tmpl, err
 	}
 
-	if tmpl != "" {
-		return tmpl
-	}
 	p.m.Lock()
-	p.rnd = rand.New(rand.NewSource(time.Now().UnixNano() + int64(os.Getpid())))
-	p.tmpl, p.err = p.createTemplateDB()
-	err = p.err
-	p.m.Unlock()
+	defer p.m.Unlock()
 
-	if err != nil {
-		t.Fatalf("%+v", err)
+	// Template may be created while waiting for the lock.
+	if p.err != nil || p.tmpl != "" {
+		return p.tmpl, p.err
 	}
 
-	return p.tmpl
+	start := time.Now()
+	p.rnd = rand.New(rand.NewSource(time.Now().UnixNano() + int64(os.Getpid())))
+	p.opts()
+	p.err = p.optsErr
+	if p.err == nil {
+		p.err = p.checkPrivileges()
+	}
+	if p.err == nil {
+		p.err = p.bootstrapRoles()
+	}
+	if p.err == nil {
+		p.err = p.checkAppUser()
+	}
+	if p.err == nil && p.opts().tablespace != "" {
+		p.err = p.checkTablespace()
+	}
+	if p.err == nil {
+		p.tmpl, p.err = p.createTemplateDB()
+	}
+	if p.err == nil && len(p.DatabaseSettings) != 0 {
+		p.warnFsync()
+	}
+	if p.err == nil && p.opts().keepTemplates > 0 && p.hasSchema() &&
+		!p.Yugabyte {
+
+		p.pruneTemplates(p.tmpl)
+	}
+	if p.err != nil {
+		p.err = &templateError{err: p.err}
+	}
+	p.emit(EventTemplateReady, p.tmpl, "", start, p.err)
+	return p.tmpl, p.err
 }
 
-// Register pgx.ConnConfig with std driver.
-// Return connection string for database/sql and error.
-func (p *Pgpool) registerStdConfig(t testing.TB,
-	dbName string) (string, error) {
+// Open database/sql handle to database dbName using pgx std driver or
+// StdConnector.
+func (p *Pgpool) openStdDB(t testing.TB, dbName string) (*sql.DB, error) {
+	if p.StdConnector != nil {
+		ctx, cancel := context.WithTimeout(context.Background(),
+			p.timeout())
+		defer cancel()
+		dsn, err := p.dsn(ctx, dbName)
+		if err != nil {
+			return nil, err
+		}
+		connector, err := p.StdConnector(dsn)
+		if err != nil {
+			return nil, fmt.Errorf("can't create std connector: %w", err)
+		}
+		return sql.OpenDB(connector), nil
+	}
 
-	connConfig, err := pgx.ParseConfig("")
+	connConfig, err := p.testConnConfig(dbName)
 	if err != nil {
-		return "", errors.WithStack(err)
+		return nil, err
 	}
-	connConfig.Tracer = &tracelog.TraceLog{
-		Logger:   newLogger(t),
-		LogLevel: tracelog.LogLevelTrace,
-	}
-	connConfig.Database = dbName
-	return stdlib.RegisterConnConfig(connConfig), nil
+	setAppName(connConfig, testAppName(t.Name(), dbName))
+	connConfig.Tracer = newMultiTracer(
+		&tracelog.TraceLog{
+			Logger:   newLogger(t),
+			LogLevel: tracelog.LogLevelTrace,
+		},
+		p.slowQueryTracer(t),
+	)
+	return stdlib.OpenDB(*connConfig,
+		stdlib.OptionBeforeConnect(p.beforeConnect),
+		stdlib.OptionAfterConnect(p.afterConnect)), nil
 }
 
 func (p *Pgpool) createRndDB(t testing.TB) (string, error) {
 	tmpl := p.getTmpl(t)
-	dbName := fmt.Sprintf("%v_%v", tmpl, p.rnd.Int31())
 
-	return dbName, p.createDB(dbName, tmpl)
+	if p.resetStrategy(tmpl) == ResetTruncate {
+		if dbName, ok := p.takeFreeDB(&p.freeDBs); ok {
+			p.dbTests.Store(dbName, t.Name())
+			p.captureServerLog(t, dbName)
+			return dbName, nil
+		}
+	}
+
+	dbName, err := p.newTestDB(t, tmpl)
+	if err == nil {
+		p.captureServerLog(t, dbName)
+	}
+	return dbName, err
+}
+
+// Creates a new database from template tmpl for test t and sends
+// EventCreate.
+func (p *Pgpool) newTestDB(t testing.TB, tmpl string) (string, error) {
+	return p.newNamedTestDB(t, fmt.Sprintf("%v_%v", tmpl, p.rnd.Int31()),
+		tmpl)
+}
+
+// Creates database dbName from template tmpl for test t and sends
+// EventCreate.
+func (p *Pgpool) newNamedTestDB(t testing.TB, dbName,
+	tmpl string) (string, error) {
+
+	start := time.Now()
+	dbName, err := p.newNamedDB(dbName, tmpl)
+	if err == nil {
+		p.dbTests.Store(dbName, t.Name())
+	}
+	p.emit(EventCreate, dbName, t.Name(), start, err)
+	return dbName, err
+}
+
+// Creates a new database from template tmpl.
+func (p *Pgpool) newDB(tmpl string) (string, error) {
+	return p.newNamedDB(fmt.Sprintf("%v_%v", tmpl, p.rnd.Int31()), tmpl)
+}
+
+// Creates database dbName from template tmpl.
+func (p *Pgpool) newNamedDB(dbName, tmpl string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout())
+	defer cancel()
+	err := p.backend().CreateFromTemplate(ctx, dbName, tmpl)
+	if err != nil {
+		return "", err
+	}
+	registerCreatedDB(dbName, p)
+
+	if err := p.applyDatabaseSettings(dbName); err != nil {
+		_ = p.dropDB(dbName)
+		return "", err
+	}
+
+	return dbName, nil
 }
 
 func (p *Pgpool) createRndDBPool(
@@ -171,56 +583,74 @@ func (p *Pgpool) createRndDBPool(
 	if err != nil {
 		t.Fatal(err)
 	}
+	return p.openTestPool(t, dbName), dbName
+}
 
+// Opens a pool of connections to test database dbName. The database is
+// dropped if the pool can't be opened.
+func (p *Pgpool) openTestPool(t testing.TB, dbName string) *pgxpool.Pool {
 	var cfg *pgxpool.Config
-	cfg, err = pgxpool.ParseConfig("")
+	connConfig, err := p.testConnConfig(dbName)
+	if err == nil {
+		cfg, err = p.poolConfig(connConfig)
+	}
 	if err != nil {
-		_ = dropDB(dbName)
+		_ = p.dropDB(dbName)
 		t.Fatal(err)
 	}
-	cfg.ConnConfig.Database = dbName
+	setAppName(cfg.ConnConfig, testAppName(t.Name(), dbName))
+	cfg.AfterConnect = p.afterConnect
+	cfg.ConnConfig.Tracer = p.queryTracer(t)
+	p.setPoolSize(cfg)
 
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout())
 	defer cancel()
 
-	pool, err = pgxpool.NewWithConfig(ctx, cfg)
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
 	if err != nil {
-		_ = dropDB(dbName)
+		_ = p.dropDB(dbName)
 		t.Fatal()
 	}
 
-	return pool, dbName
+	return pool
 }
 
-func withNewConnection(
+// Calls fn with a new connection to database dbName. If dbName is empty,
+// a connection from the pool of connections to the master database is
+// used.
+func (p *Pgpool) withNewConnection(
 	dbName string,
 	fn func(context.Context, *pgx.Conn) error,
 ) (err error) {
-	var cfg *pgx.ConnConfig
-	cfg, err = pgx.ParseConfig("")
-	if err != nil {
-		return errors.WithStack(err)
+	if dbName == "" {
+		return p.withMasterConnection(fn)
 	}
 
-	if dbName != "" {
-		cfg.Database = dbName
+	var cfg *pgx.ConnConfig
+	cfg, err = p.connConfig(dbName)
+	if err != nil {
+		return err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout())
 	defer cancel()
 
+	if err = p.beforeConnect(ctx, cfg); err != nil {
+		return err
+	}
+
 	conn, err := pgx.ConnectConfig(ctx, cfg)
 	if err != nil {
-		return errors.WithStack(err)
+		return err
 	}
 
 	defer func() {
-		ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+		ctx, cancel := context.WithTimeout(context.Background(), p.timeout())
 		err2 := conn.Close(ctx)
 		cancel()
 		if err2 != nil {
 			if err == nil {
-				err = errors.WithStack(err2)
+				err = err2
 			} else {
 				log.Printf("error closing DB connection: %v", err2)
 			}
@@ -232,41 +662,64 @@ func withNewConnection(
 	return err
 }
 
-func dropDB(dbName string) error {
-	return withNewConnection(
+func (p *Pgpool) dropDB(dbName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout())
+	defer cancel()
+	err := p.backend().Drop(ctx, dbName)
+	if err == nil {
+		unregisterCreatedDB(dbName)
+	}
+	return err
+}
+
+func (p *Pgpool) dropDatabase(dbName string) error {
+	defer p.acquireAdmin()()
+
+	return p.withNewConnection(
 		"",
 		func(ctx context.Context, conn *pgx.Conn) error {
-			_, err := conn.Exec(ctx, "DROP DATABASE "+quote(dbName))
-			return errors.WithStack(err)
+			query := "DROP DATABASE " + quote(dbName)
+			if p.TimescaleDB {
+				query += " WITH (FORCE)"
+			}
+			_, err := conn.Exec(ctx, query)
+			return err
 		},
 	)
 }
 
 // WithEmpty creates empty database from template database, that was
-// created from `schema` file.
+// created from `schema` file. The database is dropped when the test
+// finishes. If connections of the pool are not released by then, the test
+// fails and the database is left.
 func (p *Pgpool) WithEmpty(t testing.TB) *pgxpool.Pool {
-	pool, dbName := p.createRndDBPool(t)
+	pool, cleanupFn := p.newDBWithCleanup(t)
 	t.Cleanup(func() {
-		acquiredConns := pool.Stat().AcquiredConns()
-		if acquiredConns > 0 {
-			t.Fatalf(
-				"unreleased connections exists: %v, can't drop database %v",
-				acquiredConns, dbName,
-			)
-		}
-		pool.Close()
-		err := dropDB(dbName)
-		if err != nil {
-			t.Errorf("Can't drop DB %v: %v", dbName, err)
+		if err := cleanupFn(); err != nil {
+			t.Error(err)
 		}
 	})
 	return pool
 }
 
+// NewEmpty is like WithEmpty, but the database is dropped by returned
+// cleanup function instead of at the end of the test. The cleanup function
+// does not use t, so it may be called from any goroutine. If connections
+// of the pool are not released, it returns an error and may be called
+// again later.
+func (p *Pgpool) NewEmpty(t testing.TB) (*pgxpool.Pool, func() error) {
+	return p.newDBWithCleanup(t)
+}
+
 // WithStdEmpty creates empty database from template database, that was
-// created from `schema` file.
+// created from `schema` file. Cleanup behaves the same as in WithEmpty.
 func (p *Pgpool) WithStdEmpty(t testing.TB) *sql.DB {
-	db, cleanupFn := p.newStdDBWithCleanup(t)
+	db, _ := p.withStdEmpty(t)
+	return db
+}
+
+func (p *Pgpool) withStdEmpty(t testing.TB) (*sql.DB, string) {
+	db, dbName, cleanupFn := p.newStdDB(t)
 	if cleanupFn != nil {
 		t.Cleanup(func() {
 			if err := cleanupFn(); err != nil {
@@ -274,145 +727,255 @@ func (p *Pgpool) WithStdEmpty(t testing.TB) *sql.DB {
 			}
 		})
 	}
-	return db
+	return db, dbName
+}
+
+// NewStdEmpty is like WithStdEmpty, but the database is dropped by
+// returned cleanup function. See NewEmpty.
+func (p *Pgpool) NewStdEmpty(t testing.TB) (*sql.DB, func() error) {
+	return p.newStdDBWithCleanup(t)
+}
+
+func (p *Pgpool) newDBWithCleanup(
+	t testing.TB) (pool *pgxpool.Pool, cleanupFn func() error) {
+
+	pool, dbName := p.createRndDBPool(t)
+	return pool, p.poolCleanup(t, pool, dbName, p.releaseDB)
+}
+
+// Returns the function closing pool of test t and releasing its database
+// dbName with release.
+func (p *Pgpool) poolCleanup(t testing.TB, pool *pgxpool.Pool,
+	dbName string, release func(dbName string) error) func() error {
+
+	p.startWatchdog(t, dbName)
+	trackCleanup(dbName, t.Name())
+
+	return func() error {
+		untrackCleanup(dbName)
+		acquiredConns := p.acquiredConns(pool)
+		var lingering bool
+		if acquiredConns > 0 {
+			var err error
+			lingering, err = p.rollbackLingeringTx(dbName,
+				int(acquiredConns))
+			if err != nil {
+				return err
+			}
+			if !lingering {
+				return fmt.Errorf("%w: %v, can't drop database %v%v",
+					ErrUnreleasedConnections, acquiredConns, dbName,
+					p.activityHint(dbName))
+			}
+		}
+		var leaksErr error
+		if p.DetectLeaks {
+			ctx, cancel := context.WithTimeout(context.Background(),
+				p.timeout())
+			leaks, err := sessionLeaks(ctx, pool)
+			cancel()
+			if err != nil {
+				leaksErr = fmt.Errorf("can't check leaks in %v: %w", dbName,
+					err)
+			} else {
+				leaksErr = leaksError(dbName, leaks)
+			}
+		}
+		for _, pl := range append(p.takeSiblings(pool), pool) {
+			if lingering {
+				// Close waits for acquired connections to be released,
+				// which the test may never do.
+				go pl.Close()
+			} else {
+				pl.Close()
+			}
+		}
+		err := release(dbName)
+		if err != nil {
+			return fmt.Errorf("Can't drop DB %v: %w%v", dbName, err,
+				p.activityHint(dbName))
+		}
+		return leaksErr
+	}
 }
 
 func (p *Pgpool) newStdDBWithCleanup(
 	t testing.TB) (db *sql.DB, cleanupFn func() error) {
 
+	db, _, cleanupFn = p.newStdDB(t)
+	return db, cleanupFn
+}
+
+func (p *Pgpool) newStdDB(
+	t testing.TB) (db *sql.DB, dbName string, cleanupFn func() error) {
+
 	dbName, err := p.createRndDB(t)
 	if err != nil {
 		t.Fatal(err)
-		return nil, nil
+		return nil, "", nil
 	}
 
-	connString, err := p.registerStdConfig(t, dbName)
+	db, err = p.openStdDB(t, dbName)
 	if err != nil {
-		_ = dropDB(dbName)
+		_ = p.dropDB(dbName)
 		t.Fatal(err)
-		return nil, nil
+		return nil, "", nil
 	}
 
-	db, err = sql.Open("pgx", connString)
-	if err != nil {
-		_ = dropDB(dbName)
-		t.Fatal(err)
-		return nil, nil
-	}
+	p.startWatchdog(t, dbName)
+	trackCleanup(dbName, t.Name())
 
 	cleanupFn = func() error {
+		untrackCleanup(dbName)
 		stats := db.Stats()
 		if stats.InUse > 0 {
-			return errors.Errorf(
-				"unreleased connections exists: %v, can't drop database %v",
-				stats.InUse, dbName)
+			lingering, err := p.rollbackLingeringTx(dbName, stats.InUse)
+			if err != nil {
+				return err
+			}
+			if !lingering {
+				return fmt.Errorf("%w: %v, can't drop database %v%v",
+					ErrUnreleasedConnections, stats.InUse, dbName,
+					p.activityHint(dbName))
+			}
 		}
 		err := db.Close()
 		if err != nil {
-			return errors.Errorf("Can't close DB %v: %v", dbName, err)
+			return fmt.Errorf("Can't close DB %v: %w", dbName, err)
 		}
-		err = dropDB(dbName)
+		err = p.releaseDB(dbName)
 		if err != nil {
-			return errors.Errorf("Can't drop DB %v: %v", dbName, err)
+			return fmt.Errorf("Can't drop DB %v: %w%v", dbName, err,
+				p.activityHint(dbName))
 		}
 		return nil
 	}
-	return db, cleanupFn
-}
-
-func (p *Pgpool) createDB(name, tmplName string) error {
-	query := `CREATE DATABASE ` + quote(name)
-	if tmplName != "" {
-		query += ` WITH TEMPLATE ` + quote(tmplName)
-	}
-
-	return withNewConnection(
-		"",
-		func(ctx context.Context, conn *pgx.Conn) error {
-			_, err := conn.Exec(ctx, query)
-			return errors.WithStack(err)
-		},
-	)
+	return db, dbName, cleanupFn
 }
 
 // Creates template db, populates with SQLs from schema file and return name
 // of the new database. If database is exists, just return its name.
 func (p *Pgpool) createTemplateDB() (string, error) {
-	if p.SchemaFile == "" {
+	if !p.hasSchema() {
 		return "template1", nil
 	}
-	schemaSql, err := os.ReadFile(p.SchemaFile)
+	extVersions, err := p.extensionVersions(p.templateExtensions())
 	if err != nil {
-		return "", errors.WithStack(err)
+		return "", err
+	}
+	if p.TemplateFrom != nil {
+		ctx, cancel := context.WithTimeout(context.Background(),
+			p.timeout())
+		p.sourceSchema, err = p.TemplateFrom.dumpSchema(ctx)
+		cancel()
+		if err != nil {
+			return "", err
+		}
+	}
+	checksum, err := p.templateChecksum(extVersions)
+	if err != nil {
+		return "", err
 	}
-	checksum := md5.Sum(schemaSql)
 	schemaHex := hex.EncodeToString(checksum[:])
-	baseName := "dbtestpg"
-	if p.BaseName != "" {
-		baseName = p.BaseName
+	tmplDbName := fmt.Sprintf("%v_%v", p.baseName(), schemaHex)
+	if p.Yugabyte {
+		// Databases are created with schema replay, the template name is
+		// used as their prefix only.
+		return tmplDbName, nil
 	}
-	tmplDbName := fmt.Sprintf("%v_%v", baseName, schemaHex)
 
 	// ID of the advisory lock. Lock would be taken on master database (not
 	// on database we are going to create) and prevent from parallel creation
 	// of the same database from separate processes.
-	lockID := int64(binary.BigEndian.Uint64(checksum[:8]))
+	lockID := templateLockID(checksum[:])
 
-	err = withNewConnection(
-		"",
-		func(ctx context.Context, conn *pgx.Conn) error {
-			var dbExists bool
-			err := conn.QueryRow(ctx,
-				`SELECT EXISTS(SELECT 1 FROM pg_database WHERE datname = $1)`,
-				tmplDbName).Scan(&dbExists)
-			if err != nil {
-				return errors.WithStack(err)
-			}
-			if dbExists {
-				return nil
-			}
+	connString, err := p.connString()
+	if err != nil {
+		return "", err
+	}
 
-			// If we need to create a database, take an advisory lock on
-			// master database to prevent parallel creation of databases
-			// from several test processes.
-			_, err = conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, lockID)
-			if err != nil {
-				return errors.WithStack(err)
-			}
+	// Pgpool values with the same schema and options share the template,
+	// so it is checked and created once per process.
+	err = createTemplateOnce(templateKey(connString, tmplDbName), func() error {
+		return p.withNewConnection(
+			"",
+			func(ctx context.Context, conn *pgx.Conn) error {
+				// Take an advisory lock on master database to prevent
+				// parallel creation of databases from several test
+				// processes, and drop of the template by pruneTemplates of
+				// another process while it is checked and touched.
+				_, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, lockID)
+				if err != nil {
+					return err
+				}
+				// The connection is reused, so release the lock explicitly.
+				defer func() {
+					_, _ = conn.Exec(ctx, `SELECT pg_advisory_unlock($1)`,
+						lockID)
+				}()
 
-			// Check again for database existence. Database may be created
-			// in parallel process while waiting for the lock.
-			err = conn.QueryRow(ctx,
-				`SELECT EXISTS(SELECT 1 FROM pg_database WHERE datname = $1)`,
-				tmplDbName).Scan(&dbExists)
-			if err != nil {
-				return errors.WithStack(err)
-			}
-			if dbExists {
-				return nil
-			}
+				exists, reason, err := checkTemplate(ctx, conn, tmplDbName,
+					schemaHex)
+				if err != nil {
+					return err
+				}
+				if exists && reason == "" {
+					if p.opts().keepTemplates > 0 {
+						// Mark the template as used, so KeepTemplates of
+						// other processes keep it. Only the owner of the
+						// template may do it, so failures are not fatal.
+						err = touchManifest(ctx, conn, tmplDbName)
+						if err != nil {
+							log.Printf("go-test-pg: can't mark template "+
+								"database %v as used: %v", tmplDbName, err)
+						}
+					}
+					return nil
+				}
+				if exists {
+					log.Printf("go-test-pg: rebuilding template database "+
+						"%v: %v", tmplDbName, reason)
+					_, err = conn.Exec(ctx,
+						`DROP DATABASE `+quote(tmplDbName))
+					if err != nil {
+						return fmt.Errorf("can't drop template "+
+							"database %v (%v), drop it manually: %w",
+							tmplDbName, reason, err)
+					}
+				}
 
-			_, err = conn.Exec(ctx, `CREATE DATABASE `+quote(tmplDbName))
-			if err != nil {
-				return errors.WithStack(err)
-			}
+				_, err = conn.Exec(ctx, `CREATE DATABASE `+quote(tmplDbName))
+				if err != nil {
+					return err
+				}
 
-			err = withNewConnection(
-				tmplDbName,
-				func(ctx context.Context, conn *pgx.Conn) error {
-					_, err = conn.Exec(ctx, string(schemaSql))
-					return errors.WithStack(err)
-				},
-			)
+				err = p.withNewConnection(
+					tmplDbName,
+					func(ctx context.Context, conn *pgx.Conn) error {
+						if err := p.loadSchema(ctx, conn); err != nil {
+							return err
+						}
+						return p.lintSchema(ctx, conn)
+					},
+				)
 
-			if err != nil {
-				_ = dropDB(tmplDbName)
-				return err
-			}
+				if err == nil {
+					err = writeManifest(ctx, conn, tmplDbName, schemaHex)
+				}
+				if err == nil && p.TimescaleDB {
+					// Disconnect the job scheduler, connections to the
+					// template are not allowed anymore.
+					err = terminateConnections(ctx, conn, tmplDbName)
+				}
+				if err != nil {
+					_, _ = conn.Exec(ctx, `DROP DATABASE `+quote(tmplDbName))
+					return err
+				}
 
-			return nil
-		},
-	)
+				return nil
+			},
+		)
+	})
 
 	if err != nil {
 		return "", err
@@ -421,6 +984,184 @@ func (p *Pgpool) createTemplateDB() (string, error) {
 	return tmplDbName, nil
 }
 
+// Populates database with SQLs from schema file and schema parts, and
+// applies options that change database content.
+func (p *Pgpool) loadSchema(ctx context.Context, conn *pgx.Conn) error {
+	err := createExtensions(ctx, conn, p.templateExtensions())
+	if err != nil {
+		return err
+	}
+	if p.PostGIS && len(p.PostGISSRIDs) != 0 {
+		if err = trimSpatialRefSys(ctx, conn, p.PostGISSRIDs); err != nil {
+			return err
+		}
+	}
+
+	// Extensions are created before search_path is changed, as schemas
+	// of SearchPath may be created by the schema file.
+	if err = p.useSearchPath(ctx, conn); err != nil {
+		return err
+	}
+	if p.MockNow {
+		if err := installTestclock(ctx, conn); err != nil {
+			return err
+		}
+	}
+
+	if p.TemplateFrom != nil {
+		err = p.TemplateFrom.load(ctx, conn, p.sourceSchema)
+		if err != nil {
+			return err
+		}
+	}
+
+	if p.SchemaFile != "" {
+		if err := p.execSchemaFile(ctx, conn, p.SchemaFile); err != nil {
+			return err
+		}
+	}
+
+	if len(p.SchemaParts) != 0 {
+		err := p.loadSchemaParts(conn.Config().Database)
+		if err != nil {
+			return err
+		}
+	}
+
+	if p.opts().unloggedTables {
+		if err = setTablesUnlogged(ctx, conn); err != nil {
+			return err
+		}
+	}
+	if p.opts().disableAutovacuum {
+		if err = disableAutovacuum(ctx, conn); err != nil {
+			return err
+		}
+	}
+	if p.CaptureDDL {
+		return installDDLAudit(ctx, conn)
+	}
+	return nil
+}
+
+// Executes SQLs from file. The file is streamed to the server in chunks of
+// whole statements, so large files with seed data are not loaded into
+// memory.
+func (p *Pgpool) execSchemaFile(ctx context.Context, conn *pgx.Conn,
+	fileName string) error {
+
+	f, err := openSchemaFile(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	progress := p.trackSchemaProgress(fileName)
+	defer progress.stop()
+
+	chunker := newSQLChunker(f)
+	var applied int64
+	for {
+		chunk, err := chunker.next(schemaChunkSize)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("can't read schema file %v: %w", fileName, err)
+		}
+		if _, err = conn.Exec(ctx, chunk); err != nil {
+			return fmt.Errorf("can't apply schema file %v: %w%v",
+				fileName, err, schemaHint(conn.Config(), fileName))
+		}
+		applied += int64(len(chunk))
+		progress.set(chunker.statements, applied)
+	}
+}
+
+// Returns checksum of the template database content. Options that change
+// the content of the template are mixed into the schema checksum, so
+// templates built with different options do not clash.
+func (p *Pgpool) templateChecksum(
+	extVersions []string) ([md5.Size]byte, error) {
+
+	var checksum [md5.Size]byte
+
+	h := md5.New()
+	if p.SchemaFile != "" {
+		if err := hashFile(h, p.SchemaFile); err != nil {
+			return checksum, err
+		}
+	}
+	for _, part := range p.SchemaParts {
+		h.Write([]byte("\x00part"))
+		if err := hashFile(h, part.File); err != nil {
+			return checksum, err
+		}
+		for _, dep := range part.DependsOn {
+			h.Write([]byte("\x00dep\x00" + dep))
+		}
+	}
+	if p.TemplateFrom != nil {
+		p.TemplateFrom.hash(h, p.sourceSchema)
+	}
+	for _, lint := range p.SchemaLints {
+		h.Write([]byte("\x00lint\x00" + lint.Name))
+	}
+	if p.opts().unloggedTables {
+		h.Write([]byte("\x00unlogged"))
+	}
+	if p.opts().disableAutovacuum {
+		h.Write([]byte("\x00noautovacuum"))
+	}
+	if p.MockNow {
+		h.Write([]byte("\x00mocknow"))
+	}
+	if p.CaptureDDL {
+		h.Write([]byte("\x00ddl"))
+	}
+	if len(p.SearchPath) != 0 {
+		h.Write([]byte("\x00search_path\x00" + p.searchPath()))
+	}
+	for i, ext := range p.templateExtensions() {
+		h.Write([]byte("\x00" + ext + "\x00" + extVersions[i]))
+	}
+	if p.PostGIS && len(p.PostGISSRIDs) != 0 {
+		h.Write([]byte(fmt.Sprintf("\x00srids%v", p.PostGISSRIDs)))
+	}
+	copy(checksum[:], h.Sum(nil))
+	return checksum, nil
+}
+
+func hashFile(w io.Writer, fileName string) error {
+	f, err := openSchemaFile(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// Returns the prefix of names of template and test databases: BaseName
+// followed by the shard identifier, if any.
+func (p *Pgpool) baseName() string {
+	baseName := p.opts().baseName
+	if baseName == "" {
+		baseName = "dbtestpg"
+	}
+	if shard := p.shard(); shard != "" {
+		baseName += "_" + shard
+	}
+	return baseName
+}
+
+// Returns true if template database is created from schema files.
+func (p *Pgpool) hasSchema() bool {
+	return p.SchemaFile != "" || len(p.SchemaParts) != 0 ||
+		p.TemplateFrom != nil
+}
+
 func quote(name string) string {
 	return pgx.Identifier{name}.Sanitize()
 }
@@ -3,12 +3,13 @@ package go_test_pg
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/jackc/pgx/v5"
-	"github.com/pkg/errors"
 )
 
 func TestPgpool_WithStdEmpty(t *testing.T) {
@@ -62,7 +63,7 @@ func TestPgpool_WithStdEmpty_InuseConnections(t *testing.T) {
 	expectedErr := fmt.Sprintf(
 		"unreleased connections exists: 1, can't drop database %v", dbName)
 	err = cleanupFn()
-	if err == nil || err.Error() != expectedErr {
+	if err == nil || !strings.HasPrefix(err.Error(), expectedErr) {
 		t.Error(err)
 	}
 
@@ -74,6 +75,41 @@ func TestPgpool_WithStdEmpty_InuseConnections(t *testing.T) {
 	}
 }
 
+// cleanup returns error on unreleased connections and may be retried
+// from another goroutine
+func TestPgpool_NewEmpty_InuseConnections(t *testing.T) {
+	x := Pgpool{}
+	pool, cleanupFn := x.NewEmpty(t)
+
+	conn, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Error(err)
+		if err := cleanupFn(); err != nil {
+			t.Log(err)
+		}
+		t.FailNow()
+	}
+	dbName := pool.Config().ConnConfig.Database
+
+	errs := make(chan error)
+	go func() { errs <- cleanupFn() }()
+	expectedErr := fmt.Sprintf(
+		"unreleased connections exists: 1, can't drop database %v", dbName)
+	if err = <-errs; err == nil ||
+		!strings.HasPrefix(err.Error(), expectedErr) {
+		t.Error(err)
+	}
+	if !errors.Is(err, ErrUnreleasedConnections) {
+		t.Errorf("want ErrUnreleasedConnections, got %v", err)
+	}
+
+	conn.Release()
+	go func() { errs <- cleanupFn() }()
+	if err = <-errs; err != nil {
+		t.Error(err)
+	}
+}
+
 func TestName(t *testing.T) {
 	var dbPool = Pgpool{
 		BaseName:   "go_test_pg",
@@ -89,5 +125,5 @@ func TestName(t *testing.T) {
 func queryDBName(db *sql.DB) (string, error) {
 	var dbName string
 	err := db.QueryRow(`SELECT current_database()`).Scan(&dbName)
-	return dbName, errors.WithStack(err)
+	return dbName, err
 }
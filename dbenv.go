@@ -0,0 +1,88 @@
+package go_test_pg
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// SetDBEnv sets libpq environment variables (PGHOST, PGPORT, PGUSER,
+// PGPASSWORD, PGDATABASE, PGSSLMODE and PGOPTIONS) and DATABASE_URL to
+// point to the test database of pool, so CLI binaries and migration tools
+// run by the test as subprocesses connect to it. Variables are restored
+// with t.Setenv when the test ends, so the test can't be parallel.
+func (p *Pgpool) SetDBEnv(t testing.TB, pool *pgxpool.Pool) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	cfg, settings, err := p.clientConfig(ctx,
+		pool.Config().ConnConfig.Database)
+	if err != nil {
+		t.Fatalf("can't build database environment: %v", err)
+	}
+	env, err := dbEnv(cfg, settings, os.Getenv("PGSSLMODE"))
+	if err != nil {
+		t.Fatalf("can't build database environment: %v", err)
+	}
+	for name, value := range env {
+		t.Setenv(name, value)
+	}
+}
+
+// Returns environment variables pointing to the database of cfg. Only the
+// primary host is used, as in formatDSN.
+func dbEnv(cfg *pgx.ConnConfig, settings map[string]string,
+	sslMode string) (map[string]string, error) {
+
+	if sslMode == "" {
+		sslMode = tlsMode(cfg)
+	}
+	port := strconv.Itoa(int(cfg.Port))
+	env := map[string]string{
+		"PGHOST":     cfg.Host,
+		"PGPORT":     port,
+		"PGUSER":     cfg.User,
+		"PGPASSWORD": cfg.Password,
+		"PGDATABASE": cfg.Database,
+		"PGSSLMODE":  sslMode,
+		"PGOPTIONS":  "",
+	}
+
+	query := url.Values{"sslmode": {sslMode}}
+	u := url.URL{
+		Scheme: "postgres",
+		User:   url.User(cfg.User),
+		Path:   "/" + cfg.Database,
+	}
+	if cfg.Password != "" {
+		u.User = url.UserPassword(cfg.User, cfg.Password)
+	}
+	if strings.HasPrefix(cfg.Host, "/") {
+		// Unix socket directory is not a valid URL host.
+		query.Set("host", cfg.Host)
+		query.Set("port", port)
+	} else {
+		u.Host = net.JoinHostPort(cfg.Host, port)
+	}
+
+	if len(settings) != 0 {
+		options, err := settingsOptions(settings)
+		if err != nil {
+			return nil, err
+		}
+		env["PGOPTIONS"] = options
+		query.Set("options", options)
+	}
+	u.RawQuery = query.Encode()
+	env["DATABASE_URL"] = u.String()
+	return env, nil
+}
@@ -0,0 +1,33 @@
+package go_test_pg
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DBInfo describes a test database, e.g. to pass it to external tools
+// like pg_dump or logical replication subscribers.
+type DBInfo struct {
+	// Name of the database.
+	Name string
+	// DSN is the keyword/value connection string of the database. See
+	// ConnString.
+	DSN string
+	// Template is the name of the template database the database was
+	// created from.
+	Template string
+}
+
+// WithEmptyInfo is like WithEmpty, but also returns the name, the
+// connection string and the template of the created database.
+func (p *Pgpool) WithEmptyInfo(t testing.TB) (*pgxpool.Pool, DBInfo) {
+	t.Helper()
+
+	pool := p.WithEmpty(t)
+	return pool, DBInfo{
+		Name:     pool.Config().ConnConfig.Database,
+		DSN:      p.ConnString(t, pool),
+		Template: p.getTmpl(t),
+	}
+}
@@ -0,0 +1,32 @@
+package go_test_pg
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestWithEmptyInfo(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+	}
+	pool, info := dbPool.WithEmptyInfo(t)
+
+	if info.Name != pool.Config().ConnConfig.Database {
+		t.Fatalf("unexpected database name %v", info.Name)
+	}
+	if !strings.HasPrefix(info.Name, info.Template+"_") {
+		t.Fatalf("database %v is not created from template %v", info.Name,
+			info.Template)
+	}
+
+	conn, err := pgx.Connect(context.Background(), info.DSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close(context.Background())
+	AssertTableExists(t, conn, "table1")
+}
@@ -0,0 +1,104 @@
+package go_test_pg
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Installs ddlaudit schema with event triggers recording DDL commands to
+// ddlaudit.log table. Dropped objects are recorded by sql_drop trigger, as
+// pg_event_trigger_ddl_commands() does not report them.
+const ddlAuditSQL = `
+CREATE SCHEMA ddlaudit;
+CREATE TABLE ddlaudit.log (
+	id bigserial PRIMARY KEY,
+	command_tag text NOT NULL,
+	object_type text NOT NULL,
+	object_identity text,
+	query text NOT NULL
+);
+CREATE FUNCTION ddlaudit.log_ddl() RETURNS event_trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+	INSERT INTO ddlaudit.log
+		(command_tag, object_type, object_identity, query)
+	SELECT c.command_tag, c.object_type, c.object_identity,
+		pg_catalog.current_query()
+	FROM pg_catalog.pg_event_trigger_ddl_commands() c;
+END
+$$;
+CREATE FUNCTION ddlaudit.log_drop() RETURNS event_trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+	INSERT INTO ddlaudit.log
+		(command_tag, object_type, object_identity, query)
+	SELECT tg_tag, d.object_type, d.object_identity,
+		pg_catalog.current_query()
+	FROM pg_catalog.pg_event_trigger_dropped_objects() d
+	WHERE d.original;
+END
+$$;
+CREATE EVENT TRIGGER ddlaudit_log_ddl ON ddl_command_end
+	EXECUTE FUNCTION ddlaudit.log_ddl();
+CREATE EVENT TRIGGER ddlaudit_log_drop ON sql_drop
+	EXECUTE FUNCTION ddlaudit.log_drop();
+`
+
+// DDLCommand is a DDL command recorded in the test database created with
+// CaptureDDL option.
+type DDLCommand struct {
+	// Tag is the command tag, e.g. CREATE TABLE.
+	Tag string
+	// ObjectType is the type of the object, e.g. table or index.
+	ObjectType string
+	// Object is the schema-qualified identity of the object, e.g.
+	// public.users_2024_01.
+	Object string
+	// Query is the text of the top-level statement.
+	Query string
+}
+
+// Installs ddlaudit schema into the template database, so DDL executed by
+// tests is recorded. Installed after the schema is loaded, so DDL of the
+// schema file is not recorded.
+func installDDLAudit(ctx context.Context, conn *pgx.Conn) error {
+	_, err := conn.Exec(ctx, ddlAuditSQL)
+	if err != nil {
+		return fmt.Errorf("can't install ddlaudit schema "+
+			"(event triggers require superuser): %w", err)
+	}
+	return nil
+}
+
+// DDLLog returns DDL commands executed in the test database created with
+// CaptureDDL option, in order of execution. A command creating or altering
+// several objects, e.g. CREATE TABLE with a primary key, is returned once
+// for every object. DDL of rolled back transactions is not recorded.
+func DDLLog(t testing.TB, q Querier) []DDLCommand {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	rows, err := q.Query(ctx, `
+SELECT command_tag, object_type, coalesce(object_identity, ''), query
+FROM ddlaudit.log
+ORDER BY id`)
+	if err != nil {
+		t.Fatalf("can't query DDL log (is CaptureDDL enabled?): %v", err)
+	}
+	commands, err := pgx.CollectRows(rows, func(
+		row pgx.CollectableRow) (DDLCommand, error) {
+
+		var c DDLCommand
+		err := row.Scan(&c.Tag, &c.ObjectType, &c.Object, &c.Query)
+		return c, err
+	})
+	if err != nil {
+		t.Fatalf("can't query DDL log: %v", err)
+	}
+	return commands
+}
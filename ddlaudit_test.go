@@ -0,0 +1,45 @@
+package go_test_pg
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDDLLog(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+		CaptureDDL: true,
+	}
+	pool := dbPool.WithEmpty(t)
+
+	if commands := DDLLog(t, pool); len(commands) != 0 {
+		t.Fatalf("want schema DDL not recorded, got %+v", commands)
+	}
+
+	_, err := pool.Exec(context.Background(),
+		`CREATE TABLE table2 (id int)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = pool.Exec(context.Background(), `DROP TABLE table2`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []DDLCommand{
+		{Tag: "CREATE TABLE", ObjectType: "table",
+			Object: "public.table2", Query: `CREATE TABLE table2 (id int)`},
+		{Tag: "DROP TABLE", ObjectType: "table",
+			Object: "public.table2", Query: `DROP TABLE table2`},
+	}
+	commands := DDLLog(t, pool)
+	if len(commands) != len(want) {
+		t.Fatalf("want %+v, got %+v", want, commands)
+	}
+	for i := range want {
+		if commands[i] != want[i] {
+			t.Fatalf("want %+v, got %+v", want[i], commands[i])
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package go_test_pg
+
+import (
+	"fmt"
+	"log"
+	"sync"
+)
+
+// Maximum number of databases dropped in background at the same time.
+const maxAsyncDrops = 4
+
+var (
+	dropsWG  sync.WaitGroup
+	dropsSem = make(chan struct{}, maxAsyncDrops)
+
+	dropErrsM sync.Mutex
+	dropErrs  []error
+)
+
+// Drops database dbName of a finished test. With AsyncDrop the drop is
+// done in background and errors are reported by FlushDrops.
+func (p *Pgpool) releaseDB(dbName string) error {
+	if p.strategy == ResetTruncate {
+		err := p.truncateDB(dbName)
+		if err == nil {
+			p.putFreeDB(&p.freeDBs, dbName)
+			return nil
+		}
+		log.Printf("go-test-pg: can't truncate database %v, dropping it: %v",
+			dbName, err)
+	}
+
+	if !p.opts().asyncDrop {
+		return p.dropTestDB(dbName)
+	}
+
+	dropsWG.Add(1)
+	go func() {
+		defer dropsWG.Done()
+
+		dropsSem <- struct{}{}
+		defer func() { <-dropsSem }()
+
+		if err := p.dropTestDB(dbName); err != nil {
+			log.Printf("go-test-pg: can't drop database %v: %v", dbName,
+				err)
+			dropErrsM.Lock()
+			dropErrs = append(dropErrs,
+				fmt.Errorf("can't drop database %v: %w", dbName, err))
+			dropErrsM.Unlock()
+		}
+	}()
+	return nil
+}
+
+// FlushDrops waits for databases of Pgpool values with AsyncDrop to be
+// dropped. Returns the first error of drops done since the previous call.
+// Call it from TestMain after m.Run, otherwise databases of last tests may
+// be left behind.
+func FlushDrops() error {
+	dropsWG.Wait()
+
+	dropErrsM.Lock()
+	defer dropErrsM.Unlock()
+
+	if len(dropErrs) == 0 {
+		return nil
+	}
+	err := dropErrs[0]
+	if len(dropErrs) > 1 {
+		err = fmt.Errorf("%v more drops failed: %w", len(dropErrs)-1, err)
+	}
+	dropErrs = nil
+	return err
+}
@@ -0,0 +1,36 @@
+package go_test_pg
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPgpool_AsyncDrop(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+		AsyncDrop:  true,
+	}
+
+	var dbName string
+	t.Run("test", func(t *testing.T) {
+		pool := dbPool.WithEmpty(t)
+		dbName = pool.Config().ConnConfig.Database
+	})
+
+	if err := FlushDrops(); err != nil {
+		t.Fatal(err)
+	}
+
+	admin := (&Pgpool{BaseName: "go_test_pg"}).WithEmpty(t)
+	var exists bool
+	err := admin.QueryRow(context.Background(),
+		`SELECT EXISTS(SELECT 1 FROM pg_database WHERE datname = $1)`,
+		dbName).Scan(&exists)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists {
+		t.Fatalf("database %v is not dropped", dbName)
+	}
+}
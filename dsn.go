@@ -0,0 +1,129 @@
+package go_test_pg
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ConnString returns keyword/value connection string of the test database
+// of pool created by p, e.g. to pass it to a subprocess. SessionSettings
+// are included in options parameter. If BeforePasswordConnect is set, the
+// password is fetched once and may expire.
+func (p *Pgpool) ConnString(t testing.TB, pool *pgxpool.Pool) string {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	dsn, err := p.dsn(ctx, pool.Config().ConnConfig.Database)
+	if err != nil {
+		t.Fatalf("can't build connection string: %v", err)
+	}
+	return dsn
+}
+
+// Returns keyword/value connection string of database dbName understood by
+// libpq, lib/pq and pgx. Password is fetched with BeforePasswordConnect
+// once, so the string may expire together with the password.
+func (p *Pgpool) dsn(ctx context.Context, dbName string) (string, error) {
+	cfg, settings, err := p.clientConfig(ctx, dbName)
+	if err != nil {
+		return "", err
+	}
+	return formatDSN(cfg, settings, os.Getenv("PGSSLMODE"))
+}
+
+// Returns connection config of database dbName with the password fetched
+// and session settings to pass to clients in other processes.
+func (p *Pgpool) clientConfig(ctx context.Context,
+	dbName string) (*pgx.ConnConfig, map[string]string, error) {
+
+	cfg, err := p.testConnConfig(dbName)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = p.beforeConnect(ctx, cfg); err != nil {
+		return nil, nil, err
+	}
+
+	var settings map[string]string
+	if !p.opts().pgBouncer {
+		// In PgBouncer mode session settings are database defaults.
+		settings = p.sessionSettings()
+	}
+	return cfg, settings, nil
+}
+
+// Formats cfg as keyword/value connection string. Only the primary host is
+// used, as not all drivers support multiple hosts. Session settings are
+// passed with options parameter.
+func formatDSN(cfg *pgx.ConnConfig, settings map[string]string,
+	sslMode string) (string, error) {
+
+	params := []string{
+		"host=" + quoteConnValue(cfg.Host),
+		"port=" + strconv.Itoa(int(cfg.Port)),
+		"user=" + quoteConnValue(cfg.User),
+	}
+	if cfg.Password != "" {
+		params = append(params, "password="+quoteConnValue(cfg.Password))
+	}
+	params = append(params, "dbname="+quoteConnValue(cfg.Database))
+
+	if sslMode == "" {
+		sslMode = tlsMode(cfg)
+	}
+	params = append(params, "sslmode="+sslMode)
+
+	if len(settings) != 0 {
+		options, err := settingsOptions(settings)
+		if err != nil {
+			return "", err
+		}
+		params = append(params, "options="+quoteConnValue(options))
+	}
+
+	return strings.Join(params, " "), nil
+}
+
+// Guesses sslmode from the parsed TLS configuration.
+func tlsMode(cfg *pgx.ConnConfig) string {
+	if cfg.TLSConfig == nil {
+		return "disable"
+	}
+	for _, fb := range cfg.Fallbacks {
+		if fb.Host == cfg.Host && fb.TLSConfig == nil {
+			return "prefer"
+		}
+	}
+	return "require"
+}
+
+// Returns settings as "-c name=value" command-line options of the server.
+// Spaces and backslashes in values are escaped with backslash.
+func settingsOptions(settings map[string]string) (string, error) {
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		if !gucNameRe.MatchString(name) {
+			return "", fmt.Errorf("invalid setting name: %q", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var options []string
+	for _, name := range names {
+		v := strings.ReplaceAll(settings[name], `\`, `\\`)
+		v = strings.ReplaceAll(v, ` `, `\ `)
+		options = append(options, "-c "+name+"="+v)
+	}
+	return strings.Join(options, " "), nil
+}
@@ -0,0 +1,104 @@
+package go_test_pg
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+func TestFormatDSN(t *testing.T) {
+	cfg, err := pgx.ParseConfig(
+		"host=/tmp port=5433 user=u password=it's dbname=x sslmode=disable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Database = "go_test_pg_1"
+
+	dsn, err := formatDSN(cfg, map[string]string{
+		"timezone":    "UTC",
+		"search_path": `a b, c\d`,
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `host='/tmp' port=5433 user='u' password='it\'s' ` +
+		`dbname='go_test_pg_1' sslmode=disable ` +
+		`options='-c search_path=a\\ b,\\ c\\\\d -c timezone=UTC'`
+	if dsn != want {
+		t.Fatalf("want %v, got %v", want, dsn)
+	}
+
+	parsed, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Password != "it's" || parsed.Database != "go_test_pg_1" {
+		t.Fatalf("unexpected parsed config: %v, %v", parsed.Password,
+			parsed.Database)
+	}
+
+	dsn, err = formatDSN(cfg, nil, "verify-full")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `host='/tmp' port=5433 user='u' password='it\'s' ` +
+		`dbname='go_test_pg_1' sslmode=verify-full`
+	if dsn != want {
+		t.Fatalf("want %v, got %v", want, dsn)
+	}
+
+	_, err = formatDSN(cfg, map[string]string{"a=1 -c b": "2"}, "")
+	if err == nil {
+		t.Fatal("expected error on invalid setting name")
+	}
+}
+
+func TestPgpool_StdConnector(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:        "go_test_pg",
+		SchemaFile:      "./testdata/schema1.sql",
+		SessionSettings: map[string]string{"app.tenant_id": "42"},
+		StdConnector: func(dsn string) (driver.Connector, error) {
+			return stdlib.GetDefaultDriver().(driver.DriverContext).
+				OpenConnector(dsn)
+		},
+	}
+	db := dbPool.WithStdEmpty(t)
+	var tenantID string
+	err := db.QueryRow(`SELECT current_setting('app.tenant_id')`).
+		Scan(&tenantID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tenantID != "42" {
+		t.Fatalf("unexpected app.tenant_id: %v", tenantID)
+	}
+}
+
+func TestPgpool_ConnString(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+	}
+	pool := dbPool.WithEmpty(t)
+	dsn := dbPool.ConnString(t, pool)
+
+	conn, err := pgx.Connect(context.Background(), dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close(context.Background())
+
+	var dbName string
+	err = conn.QueryRow(context.Background(), `SELECT current_database()`).
+		Scan(&dbName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := pool.Config().ConnConfig.Database; dbName != want {
+		t.Fatalf("want database %v, got %v", want, dbName)
+	}
+}
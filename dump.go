@@ -0,0 +1,89 @@
+package go_test_pg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// DumpOptions configures DumpDatabase.
+type DumpOptions struct {
+	// SchemaOnly dumps only object definitions.
+	SchemaOnly bool
+	// DataOnly dumps only data as INSERT statements.
+	DataOnly bool
+	// Tables limits the dump to the listed tables. Patterns are accepted
+	// as in pg_dump --table.
+	Tables []string
+	// PgDump is the path of pg_dump binary. Default is pg_dump from PATH.
+	// Its major version must not be older than the server version.
+	PgDump string
+}
+
+// DumpDatabase writes a plain SQL dump of database dbName to w using
+// pg_dump. Connection parameters of p are used. The dump may be used as a
+// schema file or attached to a bug report. Data is dumped as INSERT
+// statements, so it can be loaded with Exec.
+func (p *Pgpool) DumpDatabase(ctx context.Context, dbName string,
+	w io.Writer, opts DumpOptions) error {
+
+	cfg, err := p.testConnConfig(dbName)
+	if err != nil {
+		return err
+	}
+	if err = p.beforeConnect(ctx, cfg); err != nil {
+		return err
+	}
+
+	pgDump := opts.PgDump
+	if pgDump == "" {
+		pgDump = "pg_dump"
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, pgDump, pgDumpArgs(opts)...)
+	cmd.Env = append(os.Environ(), pgDumpEnv(cfg)...)
+	cmd.Stdout = w
+	cmd.Stderr = &stderr
+	if err = cmd.Run(); err != nil {
+		return fmt.Errorf("pg_dump failed: %v: %w",
+			strings.TrimSpace(stderr.String()), err)
+	}
+	return nil
+}
+
+func pgDumpArgs(opts DumpOptions) []string {
+	args := []string{"--format=plain", "--no-owner", "--no-privileges",
+		"--inserts"}
+	if opts.SchemaOnly {
+		args = append(args, "--schema-only")
+	}
+	if opts.DataOnly {
+		args = append(args, "--data-only")
+	}
+	for _, table := range opts.Tables {
+		args = append(args, "--table="+table)
+	}
+	return args
+}
+
+// Returns environment variables passing connection parameters of cfg to
+// libpq.
+func pgDumpEnv(cfg *pgx.ConnConfig) []string {
+	env := []string{
+		"PGHOST=" + cfg.Host,
+		fmt.Sprintf("PGPORT=%v", cfg.Port),
+		"PGDATABASE=" + cfg.Database,
+		"PGUSER=" + cfg.User,
+	}
+	if cfg.Password != "" {
+		env = append(env, "PGPASSWORD="+cfg.Password)
+	}
+	return env
+}
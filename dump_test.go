@@ -0,0 +1,63 @@
+package go_test_pg
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestPgDumpArgs(t *testing.T) {
+	got := pgDumpArgs(DumpOptions{DataOnly: true,
+		Tables: []string{"public.t1", "t2"}})
+	want := []string{"--format=plain", "--no-owner", "--no-privileges",
+		"--inserts", "--data-only", "--table=public.t1", "--table=t2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected args: %v", got)
+	}
+}
+
+func TestPgDumpEnv(t *testing.T) {
+	cfg, err := pgx.ParseConfig(
+		"host=/tmp port=5433 user=u password=secret dbname=db1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"PGHOST=/tmp", "PGPORT=5433", "PGDATABASE=db1",
+		"PGUSER=u", "PGPASSWORD=secret"}
+	if got := pgDumpEnv(cfg); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected env: %v", got)
+	}
+}
+
+func TestPgpool_DumpDatabase(t *testing.T) {
+	if _, err := exec.LookPath("pg_dump"); err != nil {
+		t.Skip("pg_dump is not found")
+	}
+
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+	}
+	pool := dbPool.WithSQLs(t, []string{
+		`INSERT INTO table1 (name) VALUES ('dumped')`,
+	})
+
+	var buf bytes.Buffer
+	err := dbPool.DumpDatabase(context.Background(),
+		pool.Config().ConnConfig.Database, &buf, DumpOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"CREATE TABLE public.table1",
+		"'dumped'"} {
+
+		if !strings.Contains(buf.String(), s) {
+			t.Fatalf("dump does not contain %q:\n%v", s, buf.String())
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package go_test_pg
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+)
+
+var (
+	// ErrSchemaFileMissing is returned when SchemaFile or a file of
+	// SchemaParts does not exist.
+	ErrSchemaFileMissing = errors.New("schema file is missing")
+	// ErrUnreleasedConnections is returned by cleanup functions when
+	// connections to the test database are still in use.
+	ErrUnreleasedConnections = errors.New("unreleased connections exists")
+	// ErrTemplateCreateFailed is returned when the template database can't
+	// be created. The cause is available with errors.Unwrap.
+	ErrTemplateCreateFailed = errors.New("can't create template database")
+	// ErrSessionLeaks is returned by cleanup functions with DetectLeaks
+	// when the test left prepared statements or cursors open.
+	ErrSessionLeaks = errors.New("leaked prepared statements or cursors")
+	// ErrCleanupNotCalled is returned by CheckCleanups when cleanup
+	// functions of test databases were never called.
+	ErrCleanupNotCalled = errors.New("cleanup function is not called")
+	// ErrInsufficientPrivileges is returned when the user of
+	// administrative connections can't create databases or roles, or
+	// AppUser can't log in.
+	ErrInsufficientPrivileges = errors.New("insufficient privileges")
+	// ErrSchemaLint is returned when SchemaLints find problems in the
+	// schema. The template database is not created.
+	ErrSchemaLint = errors.New("schema lint failed")
+)
+
+// templateError matches ErrTemplateCreateFailed with errors.Is and keeps
+// the cause of the failure.
+type templateError struct {
+	err error
+}
+
+func (e *templateError) Error() string {
+	return ErrTemplateCreateFailed.Error() + ": " + e.err.Error()
+}
+
+func (e *templateError) Unwrap() error {
+	return e.err
+}
+
+func (e *templateError) Is(target error) bool {
+	return target == ErrTemplateCreateFailed
+}
+
+// schemaFileError matches ErrSchemaFileMissing with errors.Is and keeps
+// the error of opening the file.
+type schemaFileError struct {
+	err error
+}
+
+func (e *schemaFileError) Error() string {
+	return ErrSchemaFileMissing.Error() + ": " + e.err.Error()
+}
+
+func (e *schemaFileError) Unwrap() error {
+	return e.err
+}
+
+func (e *schemaFileError) Is(target error) bool {
+	return target == ErrSchemaFileMissing
+}
+
+// Opens schema file. Missing file is reported as ErrSchemaFileMissing.
+func openSchemaFile(fileName string) (*os.File, error) {
+	f, err := os.Open(fileName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, &schemaFileError{err: err}
+	}
+	return f, err
+}
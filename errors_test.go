@@ -0,0 +1,29 @@
+package go_test_pg
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+)
+
+func TestPgpool_prepareTmpl_SchemaFileMissing(t *testing.T) {
+	p := Pgpool{SchemaFile: "./testdata/no_such_schema.sql"}
+	_, err := p.prepareTmpl()
+	if !errors.Is(err, ErrTemplateCreateFailed) {
+		t.Fatalf("want ErrTemplateCreateFailed, got %v", err)
+	}
+	if !errors.Is(err, ErrSchemaFileMissing) {
+		t.Fatalf("want ErrSchemaFileMissing, got %v", err)
+	}
+	var pathErr *fs.PathError
+	if !errors.As(err, &pathErr) ||
+		pathErr.Path != "./testdata/no_such_schema.sql" {
+
+		t.Fatalf("want *fs.PathError of the schema file, got %v", err)
+	}
+	want := "can't create template database: schema file is missing: " +
+		"open ./testdata/no_such_schema.sql: no such file or directory"
+	if err.Error() != want {
+		t.Fatalf("want %q, got %q", want, err.Error())
+	}
+}
@@ -0,0 +1,66 @@
+package go_test_pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Returns extensions created in the template database before the schema
+// is loaded.
+func (p *Pgpool) templateExtensions() []string {
+	var exts []string
+	if p.TimescaleDB {
+		exts = append(exts, "timescaledb")
+	}
+	if p.PostGIS {
+		exts = append(exts, "postgis")
+	}
+	if p.PgVector {
+		exts = append(exts, "vector")
+	}
+	return exts
+}
+
+// Returns versions of extensions exts installed on the server.
+func (p *Pgpool) extensionVersions(exts []string) ([]string, error) {
+	if len(exts) == 0 {
+		return nil, nil
+	}
+
+	versions := make([]string, len(exts))
+	err := p.withNewConnection(
+		"",
+		func(ctx context.Context, conn *pgx.Conn) error {
+			for i, ext := range exts {
+				err := conn.QueryRow(ctx, `
+SELECT default_version FROM pg_available_extensions WHERE name = $1`,
+					ext).Scan(&versions[i])
+				if errors.Is(err, pgx.ErrNoRows) {
+					return fmt.Errorf("%v extension is not available on "+
+						"the server", ext)
+				}
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	)
+	return versions, err
+}
+
+func createExtensions(ctx context.Context, conn *pgx.Conn,
+	exts []string) error {
+
+	for _, ext := range exts {
+		_, err := conn.Exec(ctx,
+			`CREATE EXTENSION IF NOT EXISTS `+quote(ext))
+		if err != nil {
+			return fmt.Errorf("can't create %v extension: %w", ext, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package go_test_pg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// application_name of postgres_fdw connections made to test databases.
+const fdwApplicationName = "go-test-pg fdw"
+
+// CreateForeignServer creates postgres_fdw extension, foreign server named
+// server and user mapping for the current user in the database of local,
+// pointing to the database of remote. Connection parameters of remote pool
+// are used, so the server must be able to reach itself at the same address
+// as the test does. Foreign tables may be created with IMPORT FOREIGN
+// SCHEMA afterwards. Creating the extension requires superuser.
+//
+// When the test finishes, postgres_fdw connections to remote database are
+// terminated, so it can be dropped.
+func CreateForeignServer(t testing.TB, local, remote *pgxpool.Pool,
+	server string) {
+
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	cfg := remote.Config().ConnConfig
+	for _, s := range fdwSQLs(server, cfg.Host, cfg.Port, cfg.Database,
+		cfg.User, cfg.Password) {
+
+		if _, err := local.Exec(ctx, s); err != nil {
+			t.Fatalf("can't create foreign server %v: %v", server, err)
+		}
+	}
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(),
+			defaultTimeout)
+		defer cancel()
+		_, err := remote.Exec(ctx, `
+SELECT pg_terminate_backend(pid)
+FROM pg_stat_activity
+WHERE datname = current_database()
+	AND application_name = $1`, fdwApplicationName)
+		if err != nil {
+			t.Errorf("can't terminate connections of foreign server %v: %v",
+				server, err)
+		}
+	})
+}
+
+// Returns SQLs creating foreign server and user mapping.
+func fdwSQLs(server, host string, port uint16, dbName, user,
+	password string) []string {
+
+	serverOpts := []string{
+		"host " + quoteLiteral(host),
+		"port " + quoteLiteral(fmt.Sprint(port)),
+		"dbname " + quoteLiteral(dbName),
+		"application_name " + quoteLiteral(fdwApplicationName),
+	}
+	userOpts := []string{"user " + quoteLiteral(user)}
+	if password != "" {
+		userOpts = append(userOpts, "password "+quoteLiteral(password))
+	}
+
+	return []string{
+		`CREATE EXTENSION IF NOT EXISTS postgres_fdw`,
+		`CREATE SERVER ` + quote(server) +
+			` FOREIGN DATA WRAPPER postgres_fdw OPTIONS (` +
+			strings.Join(serverOpts, ", ") + `)`,
+		`CREATE USER MAPPING FOR CURRENT_USER SERVER ` + quote(server) +
+			` OPTIONS (` + strings.Join(userOpts, ", ") + `)`,
+	}
+}
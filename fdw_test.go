@@ -0,0 +1,53 @@
+package go_test_pg
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestFdwSQLs(t *testing.T) {
+	got := fdwSQLs("wh", "/var/run/postgresql", 5432, "wh_1", "it's",
+		"")
+	want := []string{
+		`CREATE EXTENSION IF NOT EXISTS postgres_fdw`,
+		`CREATE SERVER "wh" FOREIGN DATA WRAPPER postgres_fdw OPTIONS (` +
+			`host '/var/run/postgresql', port '5432', dbname 'wh_1', ` +
+			`application_name 'go-test-pg fdw')`,
+		`CREATE USER MAPPING FOR CURRENT_USER SERVER "wh" OPTIONS (` +
+			`user 'it''s')`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected SQLs:\n%v", got)
+	}
+}
+
+func TestCreateForeignServer(t *testing.T) {
+	var appPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+	}
+	var warehousePool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema2.sql",
+	}
+	pools := WithDatabases(t,
+		DBSpec{Name: "app", Pool: &appPool},
+		DBSpec{Name: "warehouse", Pool: &warehousePool},
+	)
+	CreateForeignServer(t, pools["app"], pools["warehouse"], "warehouse")
+
+	ctx := context.Background()
+	_, err := pools["warehouse"].Exec(ctx,
+		`INSERT INTO events DEFAULT VALUES`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = pools["app"].Exec(ctx, `
+IMPORT FOREIGN SCHEMA public LIMIT TO (events)
+FROM SERVER warehouse INTO public`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertRowCount(t, pools["app"], "events", 1)
+}
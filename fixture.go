@@ -0,0 +1,26 @@
+package go_test_pg
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Checks result of fixture f executed with error err.
+func (f Fixture) check(err error) error {
+	if f.ExpectError == "" {
+		return err
+	}
+	re, reErr := regexp.Compile(f.ExpectError)
+	if reErr != nil {
+		return fmt.Errorf("invalid ExpectError: %w", reErr)
+	}
+	if err == nil {
+		return fmt.Errorf("want error matching %q, query succeeded",
+			f.ExpectError)
+	}
+	if !re.MatchString(err.Error()) {
+		return fmt.Errorf("want error matching %q, got: %w", f.ExpectError,
+			err)
+	}
+	return nil
+}
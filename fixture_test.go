@@ -0,0 +1,46 @@
+package go_test_pg
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFixture_check(t *testing.T) {
+	errDup := errors.New(`ERROR: duplicate key value violates unique ` +
+		`constraint "table1_pkey" (SQLSTATE 23505)`)
+
+	if err := (Fixture{}).check(errDup); err != errDup {
+		t.Fatalf("want error returned as is, got %v", err)
+	}
+	if err := (Fixture{ExpectError: "SQLSTATE 23505"}).check(errDup); err != nil {
+		t.Fatal(err)
+	}
+	err := (Fixture{ExpectError: "check constraint"}).check(errDup)
+	if !errors.Is(err, errDup) {
+		t.Fatalf("want mismatched error wrapped, got %v", err)
+	}
+	err = (Fixture{ExpectError: "SQLSTATE 23505"}).check(nil)
+	if err == nil || !strings.Contains(err.Error(), "query succeeded") {
+		t.Fatalf("want error for succeeded query, got %v", err)
+	}
+	if err = (Fixture{ExpectError: "("}).check(errDup); err == nil {
+		t.Fatal("want error for invalid regular expression")
+	}
+}
+
+func TestPgpool_WithFixtures_ExpectError(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+	}
+	pool := dbPool.WithFixtures(t, []Fixture{
+		{Query: `INSERT INTO table1 (id) VALUES (1)`},
+		{
+			Query:       `INSERT INTO table1 (id) VALUES ($1)`,
+			Params:      []interface{}{1},
+			ExpectError: `unique constraint "table1_pkey"`,
+		},
+	})
+	AssertRowCount(t, pool, "table1", 1)
+}
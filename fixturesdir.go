@@ -0,0 +1,162 @@
+package go_test_pg
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Fixture files are sent to the server in batches of at least this size.
+const fixtureBatchSize = 64 << 10
+
+// Number of fixture files read ahead while previous ones are executed.
+const fixtureReadAhead = 4
+
+type fixtureFile struct {
+	name string
+	sql  string
+	err  error
+}
+
+// WithFixturesDir creates database from template database, and initializes
+// it with *.sql files from directory dir in lexical order. See
+// LoadFixturesDir.
+func (p *Pgpool) WithFixturesDir(t testing.TB, dir string) *pgxpool.Pool {
+	pool := p.WithEmpty(t)
+	start := time.Now()
+	LoadFixturesDir(t, pool, dir)
+	p.emit(EventFixtures, pool.Config().ConnConfig.Database, t.Name(), start,
+		nil)
+	return pool
+}
+
+// WithStdFixturesDir creates database from template database, and
+// initializes it with *.sql files from directory dir in lexical order. See
+// LoadFixturesDir.
+func (p *Pgpool) WithStdFixturesDir(t testing.TB, dir string) *sql.DB {
+	t.Helper()
+
+	db, dbName := p.withStdEmpty(t)
+	start := time.Now()
+	err := loadFixturesDir(dir, func(ctx context.Context, s string) error {
+		_, err := db.ExecContext(ctx, s)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("can't load fixtures from %v: %v%v", dir, err,
+			p.dbHint(dbName))
+	}
+	p.emit(EventFixtures, dbName, t.Name(), start, nil)
+	return db
+}
+
+// LoadFixturesDir executes *.sql files from directory dir in lexical
+// order. Next files are read while previous ones are executed. Small files
+// are concatenated and sent in one query, which runs in one implicit
+// transaction, so files must not contain COMMIT. If a batch fails, its
+// files are executed one by one to find the failing file.
+func LoadFixturesDir(t testing.TB, q Querier, dir string) {
+	t.Helper()
+
+	err := loadFixturesDir(dir, func(ctx context.Context, s string) error {
+		_, err := q.Exec(ctx, s)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("can't load fixtures from %v: %v%v", dir, err,
+			querierHint(q))
+	}
+}
+
+func loadFixturesDir(dir string,
+	exec func(ctx context.Context, sql string) error) error {
+
+	names, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	files := make(chan fixtureFile, fixtureReadAhead)
+	go readFixtureFiles(ctx, names, files)
+
+	var batch []fixtureFile
+	var batchSize int
+	flush := func() error {
+		defer func() { batch, batchSize = nil, 0 }()
+		return execFixtureBatch(ctx, batch, exec)
+	}
+
+	for f := range files {
+		if f.err != nil {
+			return f.err
+		}
+		batch = append(batch, f)
+		batchSize += len(f.sql)
+		if batchSize >= fixtureBatchSize {
+			if err = flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if len(batch) != 0 {
+		return flush()
+	}
+	return nil
+}
+
+// Reads files in order and sends them to out. Stops on the first error or
+// when ctx is done. Closes out.
+func readFixtureFiles(ctx context.Context, names []string,
+	out chan<- fixtureFile) {
+
+	defer close(out)
+	for _, name := range names {
+		buf, err := os.ReadFile(name)
+		f := fixtureFile{name: name, sql: string(buf),
+			err: err}
+		select {
+		case out <- f:
+		case <-ctx.Done():
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Executes files in one query. If it fails, executes files one by one to
+// report the failing file.
+func execFixtureBatch(ctx context.Context, batch []fixtureFile,
+	exec func(ctx context.Context, sql string) error) error {
+
+	if len(batch) > 1 {
+		sqls := make([]string, len(batch))
+		for i, f := range batch {
+			sqls[i] = f.sql
+		}
+		// Statements may lack the trailing semicolon.
+		if exec(ctx, strings.Join(sqls, ";\n")) == nil {
+			return nil
+		}
+	}
+
+	for _, f := range batch {
+		if err := exec(ctx, f.sql); err != nil {
+			return fmt.Errorf("can't load fixture file %v: %w", f.name, err)
+		}
+	}
+	return nil
+}
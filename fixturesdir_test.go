@@ -0,0 +1,66 @@
+package go_test_pg
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLoadFixturesDir_batches(t *testing.T) {
+	var queries []string
+	err := loadFixturesDir("./testdata/fixtures",
+		func(ctx context.Context, sql string) error {
+			queries = append(queries, sql)
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queries) != 1 {
+		t.Fatalf("want all files in one batch, got %q", queries)
+	}
+	a := strings.Index(queries[0], "('a')")
+	b := strings.Index(queries[0], "('b')")
+	if a < 0 || b < a {
+		t.Fatalf("unexpected batch: %q", queries[0])
+	}
+}
+
+func TestLoadFixturesDir_error(t *testing.T) {
+	var queries int
+	err := loadFixturesDir("./testdata/fixtures",
+		func(ctx context.Context, sql string) error {
+			queries++
+			if strings.Contains(sql, "('b')") {
+				return errors.New("boom")
+			}
+			return nil
+		})
+	if err == nil || !strings.Contains(err.Error(), "02_b.sql") {
+		t.Fatalf("want error of 02_b.sql, got %v", err)
+	}
+	// batch and two files executed one by one
+	if queries != 3 {
+		t.Fatalf("want 3 queries, got %v", queries)
+	}
+}
+
+func TestPgpool_WithFixturesDir(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+	}
+	pool := dbPool.WithFixturesDir(t, "./testdata/fixtures")
+	AssertRowCount(t, pool, "table1", 2)
+
+	db := dbPool.WithStdFixturesDir(t, "./testdata/fixtures")
+	var n int
+	err := db.QueryRow(`SELECT count(*) FROM table1`).Scan(&n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("want 2 rows, got %v", n)
+	}
+}
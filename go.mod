@@ -3,12 +3,11 @@ module github.com/olomix/go-test-pg/v2
 go 1.19
 
 require (
+	github.com/jackc/pgpassfile v1.0.0
 	github.com/jackc/pgx/v5 v5.3.1
-	github.com/pkg/errors v0.9.1
 )
 
 require (
-	github.com/jackc/pgpassfile v1.0.0 // indirect
 	github.com/jackc/pgservicefile v0.0.0-20221227161230-091c0ba34f0a // indirect
 	github.com/jackc/puddle/v2 v2.2.0 // indirect
 	golang.org/x/crypto v0.6.0 // indirect
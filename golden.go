@@ -0,0 +1,191 @@
+package go_test_pg
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Directory of golden files relative to the package of the test.
+const goldenDir = "testdata/golden"
+
+// UpdateGoldenEnv is the environment variable that makes AssertQueryGolden
+// rewrite golden files when set to a non-empty value.
+const UpdateGoldenEnv = "GO_TEST_PG_UPDATE"
+
+// AssertQueryGolden runs query and compares its result with the golden
+// file testdata/golden/<name>.golden. The result is rendered as text with
+// a header of column names and a line per row, so the query should have
+// ORDER BY. Golden files are rewritten with the actual result if the test
+// binary has a boolean -update flag set or UpdateGoldenEnv is set.
+func AssertQueryGolden(t testing.TB, q Querier, name, query string,
+	args ...any) {
+
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	actual, err := renderQuery(ctx, q, query, args...)
+	if err != nil {
+		t.Fatalf("can't run query for golden file %v: %v%v", name, err,
+			querierHint(q))
+	}
+
+	fileName := filepath.Join(goldenDir, name+".golden")
+	if updateGolden() {
+		err = os.MkdirAll(goldenDir, 0o755)
+		if err == nil {
+			err = os.WriteFile(fileName, []byte(actual), 0o644)
+		}
+		if err != nil {
+			t.Fatalf("can't update golden file: %v", err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(fileName)
+	if os.IsNotExist(err) {
+		t.Fatalf("golden file %v does not exist, run tests with -update "+
+			"flag or %v=1 to create it", fileName, UpdateGoldenEnv)
+	}
+	if err != nil {
+		t.Fatalf("can't read golden file: %v", err)
+	}
+
+	if string(expected) != actual {
+		t.Fatalf("query result differs from %v:\n%v", fileName,
+			lineDiff(splitLines(string(expected)), splitLines(actual)))
+	}
+}
+
+func updateGolden() bool {
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		return true
+	}
+	f := flag.Lookup("update")
+	if f == nil {
+		return false
+	}
+	getter, ok := f.Value.(flag.Getter)
+	if !ok {
+		return false
+	}
+	update, _ := getter.Get().(bool)
+	return update
+}
+
+// Renders result of query as text: a line with column names and a line
+// per row with values separated by " | ".
+func renderQuery(ctx context.Context, q Querier, query string,
+	args ...any) (string, error) {
+
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var b strings.Builder
+	for i, f := range rows.FieldDescriptions() {
+		if i > 0 {
+			b.WriteString(" | ")
+		}
+		b.WriteString(f.Name)
+	}
+	b.WriteString("\n")
+
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return "", err
+		}
+		for i, v := range values {
+			if i > 0 {
+				b.WriteString(" | ")
+			}
+			b.WriteString(goldenValue(v))
+		}
+		b.WriteString("\n")
+	}
+	if err = rows.Err(); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// Formats value independently of time zone of the test process.
+func goldenValue(v any) string {
+	switch v := v.(type) {
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case pgtype.Numeric:
+		buf, err := v.MarshalJSON()
+		if err != nil {
+			return formatValue(v)
+		}
+		return string(buf)
+	case map[string]any, []any:
+		buf, err := json.Marshal(v)
+		if err != nil {
+			return formatValue(v)
+		}
+		return string(buf)
+	default:
+		return formatValue(v)
+	}
+}
+
+func splitLines(s string) []string {
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// Returns line-based diff of expected and actual lines. Missing lines are
+// prefixed with "-", unexpected lines with "+".
+func lineDiff(expected, actual []string) string {
+	// lcs[i][j] is the length of the longest common subsequence of
+	// expected[i:] and actual[j:].
+	lcs := make([][]int, len(expected)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(actual)+1)
+	}
+	for i := len(expected) - 1; i >= 0; i-- {
+		for j := len(actual) - 1; j >= 0; j-- {
+			switch {
+			case expected[i] == actual[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var b strings.Builder
+	i, j := 0, 0
+	for i < len(expected) || j < len(actual) {
+		switch {
+		case i < len(expected) && j < len(actual) &&
+			expected[i] == actual[j]:
+			b.WriteString("  " + expected[i] + "\n")
+			i++
+			j++
+		case j == len(actual) ||
+			(i < len(expected) && lcs[i+1][j] >= lcs[i][j+1]):
+			b.WriteString("- " + expected[i] + "\n")
+			i++
+		default:
+			b.WriteString("+ " + actual[j] + "\n")
+			j++
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,45 @@
+package go_test_pg
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLineDiff(t *testing.T) {
+	got := lineDiff([]string{"a", "b", "c"}, []string{"a", "x", "c", "d"})
+	want := "  a\n- b\n+ x\n  c\n+ d\n"
+	if got != want {
+		t.Fatalf("unexpected diff:\n%v", got)
+	}
+}
+
+func TestGoldenValue(t *testing.T) {
+	tm := time.Date(2020, 1, 2, 3, 4, 5, 0, time.FixedZone("X", 3600))
+	testCases := []struct {
+		v    any
+		want string
+	}{
+		{nil, "NULL"},
+		{"it's", `"it's"`},
+		{int32(42), "42"},
+		{tm, "2020-01-02T02:04:05Z"},
+		{map[string]any{"b": 1.0, "a": []any{"x"}}, `{"a":["x"],"b":1}`},
+	}
+	for _, tc := range testCases {
+		if got := goldenValue(tc.v); got != tc.want {
+			t.Errorf("want %v, got %v", tc.want, got)
+		}
+	}
+}
+
+func TestAssertQueryGolden(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+	}
+	pool := dbPool.WithSQLs(t, []string{
+		`INSERT INTO table1 (name) VALUES ('a'), (NULL)`,
+	})
+	AssertQueryGolden(t, pool, "table1",
+		`SELECT id, name FROM table1 ORDER BY id`)
+}
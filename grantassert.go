@@ -0,0 +1,55 @@
+package go_test_pg
+
+import (
+	"context"
+	"testing"
+)
+
+// AssertRoleCanSelect fails the test if role has no SELECT privilege on
+// table, directly or through membership in other roles.
+func AssertRoleCanSelect(t testing.TB, q Querier, role, table string) {
+	t.Helper()
+	AssertRoleCan(t, q, role, table, "SELECT")
+}
+
+// AssertRoleCan fails the test if role has no privilege on table, e.g.
+// AssertRoleCan(t, pool, "writer", "public.users", "INSERT"). Privileges
+// granted to roles role is a member of are taken into account, as in
+// has_table_privilege.
+func AssertRoleCan(t testing.TB, q Querier, role, table, privilege string) {
+	t.Helper()
+	if !hasTablePrivilege(t, q, role, table, privilege) {
+		t.Fatalf("role %v has no %v privilege on %v%v", role, privilege,
+			table, querierHint(q))
+	}
+}
+
+// AssertRoleCannot fails the test if role has privilege on table, e.g.
+// AssertRoleCannot(t, pool, "readonly", "public.users", "DELETE").
+func AssertRoleCannot(t testing.TB, q Querier, role, table,
+	privilege string) {
+
+	t.Helper()
+	if hasTablePrivilege(t, q, role, table, privilege) {
+		t.Fatalf("role %v has %v privilege on %v%v", role, privilege,
+			table, querierHint(q))
+	}
+}
+
+func hasTablePrivilege(t testing.TB, q Querier, role, table,
+	privilege string) bool {
+
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var has bool
+	err := q.QueryRow(ctx, `SELECT has_table_privilege($1, $2, $3)`, role,
+		table, privilege).Scan(&has)
+	if err != nil {
+		t.Fatalf("can't check %v privilege of %v on %v: %v%v", privilege,
+			role, table, err, querierHint(q))
+	}
+	return has
+}
@@ -0,0 +1,27 @@
+package go_test_pg
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGrantAssertions(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+		Roles: []RoleSpec{
+			{Name: "go_test_pg_readonly", Options: []string{"NOLOGIN"}},
+		},
+	}
+	pool := dbPool.WithEmpty(t)
+
+	_, err := pool.Exec(context.Background(),
+		`GRANT SELECT ON table1 TO go_test_pg_readonly`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	AssertRoleCanSelect(t, pool, "go_test_pg_readonly", "public.table1")
+	AssertRoleCannot(t, pool, "go_test_pg_readonly", "table1", "INSERT")
+	AssertRoleCannot(t, pool, "go_test_pg_readonly", "table1", "DELETE")
+}
@@ -0,0 +1,26 @@
+package go_test_pg
+
+import (
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Registers the first GSSProvider of all pools with pgx.
+var registerGSSOnce sync.Once
+
+// Sets GSSAPI options of p on cfg and registers GSSProvider.
+func (p *Pgpool) applyGSS(cfg *pgx.ConnConfig) {
+	if p.KerberosSrvName != "" {
+		cfg.KerberosSrvName = p.KerberosSrvName
+	}
+	if p.KerberosSpn != "" {
+		cfg.KerberosSpn = p.KerberosSpn
+	}
+	if p.GSSProvider != nil {
+		registerGSSOnce.Do(func() {
+			pgconn.RegisterGSSProvider(p.GSSProvider)
+		})
+	}
+}
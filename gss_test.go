@@ -0,0 +1,45 @@
+package go_test_pg
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestApplyGSS(t *testing.T) {
+	p := &Pgpool{KerberosSrvName: "pg", KerberosSpn: "pg/db.example.com"}
+	cfg, err := p.connConfig("db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.KerberosSrvName != "pg" || cfg.KerberosSpn != "pg/db.example.com" {
+		t.Fatalf("unexpected Kerberos options: %q, %q", cfg.KerberosSrvName,
+			cfg.KerberosSpn)
+	}
+}
+
+func TestBeforeGSSConnect(t *testing.T) {
+	errExpired := errors.New("ticket expired")
+	var calls int
+	p := &Pgpool{
+		AppUser: "app",
+		BeforeGSSConnect: func(ctx context.Context) error {
+			calls++
+			if calls > 1 {
+				return errExpired
+			}
+			return nil
+		},
+	}
+	cfg, err := p.testConnConfig("db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.beforeConnect(context.Background(), cfg); err != nil {
+		t.Fatal(err)
+	}
+	err = p.beforeConnect(context.Background(), cfg)
+	if !errors.Is(err, errExpired) {
+		t.Fatalf("want %v, got %v", errExpired, err)
+	}
+}
@@ -0,0 +1,21 @@
+package go_test_pg
+
+import (
+	"strconv"
+)
+
+// Returns settings of test databases limiting resources a test may hold.
+func (p *Pgpool) guardSettings() map[string]string {
+	settings := make(map[string]string)
+	if p.IdleInTransactionTimeout > 0 {
+		settings["idle_in_transaction_session_timeout"] = strconv.FormatInt(
+			p.IdleInTransactionTimeout.Milliseconds(), 10)
+	}
+	return settings
+}
+
+// Returns the statement setting connection limit of database dbName.
+func connectionLimitSQL(dbName string, limit int) string {
+	return `ALTER DATABASE ` + quote(dbName) + ` CONNECTION LIMIT ` +
+		strconv.Itoa(limit)
+}
@@ -0,0 +1,65 @@
+package go_test_pg
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGuardSettings(t *testing.T) {
+	p := &Pgpool{
+		IdleInTransactionTimeout: 5 * time.Second,
+		DatabaseSettings:         map[string]string{"work_mem": "64MB"},
+	}
+	want := map[string]string{
+		"idle_in_transaction_session_timeout": "5000",
+		"work_mem":                            "64MB",
+	}
+	if got := p.databaseSettings(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+
+	p.DatabaseSettings["idle_in_transaction_session_timeout"] = "1min"
+	got := p.databaseSettings()["idle_in_transaction_session_timeout"]
+	if got != "1min" {
+		t.Fatalf("DatabaseSettings must take precedence, got %v", got)
+	}
+
+	wantSQL := `ALTER DATABASE "db" CONNECTION LIMIT 10`
+	if got := connectionLimitSQL("db", 10); got != wantSQL {
+		t.Fatalf("want %v, got %v", wantSQL, got)
+	}
+}
+
+func TestConnectionLimit(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:                 "go_test_pg",
+		SchemaFile:               "./testdata/schema1.sql",
+		ConnectionLimit:          10,
+		IdleInTransactionTimeout: time.Minute,
+	}
+	pool := dbPool.WithEmpty(t)
+
+	ctx := context.Background()
+	var limit int
+	err := pool.QueryRow(ctx, `
+SELECT datconnlimit FROM pg_database WHERE datname = current_database()`).
+		Scan(&limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limit != 10 {
+		t.Fatalf("want connection limit 10, got %v", limit)
+	}
+
+	var timeout string
+	err = pool.QueryRow(ctx, `SHOW idle_in_transaction_session_timeout`).
+		Scan(&timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timeout != "1min" {
+		t.Fatalf("want timeout 1min, got %v", timeout)
+	}
+}
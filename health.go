@@ -0,0 +1,40 @@
+package go_test_pg
+
+import (
+	"context"
+	"fmt"
+)
+
+// Err returns the error of template database creation, or nil if the
+// template is created or was not created yet. The same error fails all
+// tests using the pool.
+func (p *Pgpool) Err() error {
+	p.m.RLock()
+	defer p.m.RUnlock()
+
+	return p.err
+}
+
+// Healthy checks that the server is reachable and creates the template
+// database if it does not exist yet. Call it from TestMain or SetupSuite to
+// report a broken environment once instead of failing every test with the
+// same error. It returns nil if Skip is set.
+func (p *Pgpool) Healthy(ctx context.Context) error {
+	if p.Skip {
+		return nil
+	}
+	if err := p.Err(); err != nil {
+		return err
+	}
+
+	pool, err := p.getAdminPool()
+	if err != nil {
+		return fmt.Errorf("can't connect to server: %w", err)
+	}
+	if err = pool.Ping(ctx); err != nil {
+		return fmt.Errorf("can't connect to server: %w", err)
+	}
+
+	_, err = p.prepareTmpl()
+	return err
+}
@@ -0,0 +1,37 @@
+package go_test_pg
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPgpool_Err(t *testing.T) {
+	p := &Pgpool{}
+	if err := p.Err(); err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+
+	tmplErr := &templateError{err: errors.New("boom")}
+	p.err = tmplErr
+	if err := p.Err(); err != tmplErr {
+		t.Fatalf("want %v, got %v", tmplErr, err)
+	}
+	if err := p.Healthy(context.Background()); err != tmplErr {
+		t.Fatalf("want %v, got %v", tmplErr, err)
+	}
+
+	if err := (&Pgpool{Skip: true}).Healthy(context.Background()); err != nil {
+		t.Fatalf("want no error for skipped pool, got %v", err)
+	}
+}
+
+func TestPgpool_Healthy(t *testing.T) {
+	p := &Pgpool{BaseName: "go_test_pg", SchemaFile: "./testdata/schema1.sql"}
+	if err := p.Healthy(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if p.Err() != nil || p.tmpl == "" {
+		t.Fatal("template is not created")
+	}
+}
@@ -0,0 +1,79 @@
+package go_test_pg
+
+import "time"
+
+// EventKind is the kind of a lifecycle event passed to Pgpool.OnEvent.
+type EventKind int
+
+const (
+	// EventTemplateReady is sent when the template database is created or
+	// an existing one is found valid.
+	EventTemplateReady EventKind = iota
+	// EventCreate is sent when a test database is created.
+	EventCreate
+	// EventFixtures is sent when fixtures are loaded to a test database.
+	EventFixtures
+	// EventDrop is sent when a test database is dropped.
+	EventDrop
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventTemplateReady:
+		return "template_ready"
+	case EventCreate:
+		return "create"
+	case EventFixtures:
+		return "fixtures"
+	case EventDrop:
+		return "drop"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a lifecycle event of a Pgpool.
+type Event struct {
+	Kind EventKind
+	// Database is the name of the template or test database.
+	Database string
+	// Test is the name of the test the database was created for. It is
+	// empty for EventTemplateReady.
+	Test string
+	// Duration of the operation.
+	Duration time.Duration
+	// Err is set if the operation failed.
+	Err error
+}
+
+func (p *Pgpool) emit(kind EventKind, dbName, test string, start time.Time,
+	err error) {
+
+	ev := Event{
+		Kind:     kind,
+		Database: dbName,
+		Test:     test,
+		Duration: time.Since(start),
+		Err:      err,
+	}
+	if p.OnEvent != nil {
+		p.OnEvent(ev)
+	}
+	p.report(ev)
+}
+
+// Returns the name of the test database dbName was last used by.
+func (p *Pgpool) dbTest(dbName string) string {
+	test, _ := p.dbTests.Load(dbName)
+	s, _ := test.(string)
+	return s
+}
+
+// Drops test database dbName and sends EventDrop.
+func (p *Pgpool) dropTestDB(dbName string) error {
+	start := time.Now()
+	err := p.dropDB(dbName)
+	p.emit(EventDrop, dbName, p.dbTest(dbName), start, err)
+	p.dbTests.Delete(dbName)
+	return err
+}
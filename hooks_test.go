@@ -0,0 +1,61 @@
+package go_test_pg
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPgpool_emit(t *testing.T) {
+	var events []Event
+	p := Pgpool{OnEvent: func(e Event) { events = append(events, e) }}
+
+	p.emit(EventCreate, "db_1", "TestX", time.Now().Add(-time.Second),
+		nil)
+	errDrop := errors.New("drop failed")
+	p.emit(EventDrop, "db_1", "TestX", time.Now(), errDrop)
+
+	if len(events) != 2 {
+		t.Fatalf("want 2 events, got %v", len(events))
+	}
+	e := events[0]
+	if e.Kind != EventCreate || e.Database != "db_1" || e.Test != "TestX" ||
+		e.Duration < time.Second || e.Err != nil {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+	if events[1].Kind.String() != "drop" || events[1].Err != errDrop {
+		t.Fatalf("unexpected event: %+v", events[1])
+	}
+
+	// No handler.
+	(&Pgpool{}).emit(EventDrop, "db_1", "", time.Now(), nil)
+}
+
+func TestPgpool_OnEvent(t *testing.T) {
+	var m sync.Mutex
+	kinds := map[EventKind]int{}
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+		OnEvent: func(e Event) {
+			m.Lock()
+			kinds[e.Kind]++
+			m.Unlock()
+		},
+	}
+
+	t.Run("sub", func(t *testing.T) {
+		dbPool.WithSQLs(t, []string{`INSERT INTO table1 (name) VALUES ('a')`})
+	})
+
+	m.Lock()
+	defer m.Unlock()
+	for _, k := range []EventKind{EventTemplateReady, EventCreate,
+		EventFixtures, EventDrop} {
+
+		if kinds[k] != 1 {
+			t.Errorf("want one %v event, got %v", k, kinds[k])
+		}
+	}
+}
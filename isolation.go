@@ -0,0 +1,63 @@
+package go_test_pg
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// IsolationLevels are the transaction isolation levels used by
+// RunIsolationLevels.
+var IsolationLevels = []pgx.TxIsoLevel{
+	pgx.ReadCommitted,
+	pgx.RepeatableRead,
+	pgx.Serializable,
+}
+
+// RunIsolationLevels runs fn as a subtest for every level of
+// IsolationLevels. Every subtest gets a new database with
+// default_transaction_isolation set to the level, so transactions started
+// without explicit isolation level use it.
+func (p *Pgpool) RunIsolationLevels(t *testing.T,
+	fn func(t *testing.T, pool *pgxpool.Pool, level pgx.TxIsoLevel)) {
+
+	t.Helper()
+
+	for _, level := range IsolationLevels {
+		level := level
+		name := strings.ReplaceAll(string(level), " ", "_")
+		t.Run(name, func(t *testing.T) {
+			pool := p.WithEmpty(t)
+			setIsolationLevel(t, pool, level)
+			fn(t, pool, level)
+		})
+	}
+}
+
+// Sets default_transaction_isolation of the database of pool and closes
+// existing connections of pool, so new ones pick it up.
+func setIsolationLevel(t testing.TB, pool *pgxpool.Pool,
+	level pgx.TxIsoLevel) {
+
+	t.Helper()
+
+	sqls, err := alterDatabaseSQLs(pool.Config().ConnConfig.Database,
+		map[string]string{"default_transaction_isolation": string(level)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	for _, s := range sqls {
+		if _, err = pool.Exec(ctx, s); err != nil {
+			t.Fatalf("can't set isolation level %v: %v", level, err)
+		}
+	}
+
+	pool.Reset()
+}
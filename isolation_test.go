@@ -0,0 +1,36 @@
+package go_test_pg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestPgpool_RunIsolationLevels(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+	}
+
+	var levels []pgx.TxIsoLevel
+	dbPool.RunIsolationLevels(t,
+		func(t *testing.T, pool *pgxpool.Pool, level pgx.TxIsoLevel) {
+			levels = append(levels, level)
+
+			var got string
+			err := pool.QueryRow(context.Background(),
+				`SHOW transaction_isolation`).Scan(&got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(level) {
+				t.Fatalf("want isolation %v, got %v", level, got)
+			}
+		})
+
+	if len(levels) != len(IsolationLevels) {
+		t.Fatalf("want %v runs, got %v", len(IsolationLevels), levels)
+	}
+}
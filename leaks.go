@@ -0,0 +1,63 @@
+package go_test_pg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Prefix of names of statements prepared by pgx statement cache.
+const pgxStmtCachePrefix = "stmtcache_"
+
+// Returns prepared statements and holdable cursors left open on
+// connections of pool. Statements prepared by pgx statement cache are not
+// leaks. All connections of pool must be released.
+func sessionLeaks(ctx context.Context, pool *pgxpool.Pool) ([]string,
+	error) {
+
+	conns := pool.AcquireAllIdle(ctx)
+	defer func() {
+		for _, conn := range conns {
+			conn.Release()
+		}
+	}()
+
+	var leaks []string
+	for _, conn := range conns {
+		l, err := connLeaks(ctx, conn.Conn())
+		if err != nil {
+			return nil, err
+		}
+		leaks = append(leaks, l...)
+	}
+	return leaks, nil
+}
+
+func connLeaks(ctx context.Context, conn *pgx.Conn) ([]string, error) {
+	rows, err := conn.Query(ctx, `
+SELECT 'prepared statement ' || name || ': ' || statement
+FROM pg_prepared_statements
+WHERE left(name, length($1)) <> $1
+UNION ALL
+SELECT 'cursor ' || name || ': ' || statement
+FROM pg_cursors
+WHERE name <> ''
+ORDER BY 1`, pgxStmtCachePrefix)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// Returns error describing leaked prepared statements and cursors, or nil
+// if there are none.
+func leaksError(dbName string, leaks []string) error {
+	if len(leaks) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w in database %v:\n\t%v", ErrSessionLeaks, dbName,
+		strings.Join(leaks, "\n\t"))
+}
@@ -0,0 +1,57 @@
+package go_test_pg
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestLeaksError(t *testing.T) {
+	if err := leaksError("db_1", nil); err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	err := leaksError("db_1", []string{
+		"cursor c1: DECLARE c1 CURSOR WITH HOLD FOR SELECT 1",
+		"prepared statement s1: PREPARE s1 AS SELECT 1",
+	})
+	if !errors.Is(err, ErrSessionLeaks) {
+		t.Fatalf("want ErrSessionLeaks, got %v", err)
+	}
+	want := "leaked prepared statements or cursors in database db_1:\n" +
+		"\tcursor c1: DECLARE c1 CURSOR WITH HOLD FOR SELECT 1\n" +
+		"\tprepared statement s1: PREPARE s1 AS SELECT 1"
+	if err.Error() != want {
+		t.Fatalf("want %q, got %q", want, err.Error())
+	}
+}
+
+func TestPgpool_DetectLeaks(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:    "go_test_pg",
+		SchemaFile:  "./testdata/schema1.sql",
+		DetectLeaks: true,
+		MaxConns:    1,
+	}
+	pool, cleanupFn := dbPool.NewEmpty(t)
+
+	ctx := context.Background()
+	// Statements prepared by pgx statement cache are not leaks.
+	if _, err := pool.Exec(ctx, `SELECT id FROM table1 WHERE id = $1`,
+		1); err != nil {
+		t.Fatal(err)
+	}
+	_, err := pool.Exec(ctx, `PREPARE s1 AS SELECT 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = pool.Exec(ctx,
+		`BEGIN; DECLARE c1 CURSOR WITH HOLD FOR SELECT 1; COMMIT`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = cleanupFn()
+	if !errors.Is(err, ErrSessionLeaks) {
+		t.Fatalf("want ErrSessionLeaks, got %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package go_test_pg
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Terminates sessions to test database dbName left idle in transaction if
+// RollbackLingeringTx is set and all inUse connections of the returned
+// handle are such sessions. Termination rolls their transactions back.
+// Returns false if connections are still in use for other reasons.
+func (p *Pgpool) rollbackLingeringTx(dbName string, inUse int) (bool,
+	error) {
+
+	if !p.RollbackLingeringTx {
+		return false, nil
+	}
+
+	var terminated int
+	err := p.withMasterConnection(
+		func(ctx context.Context, conn *pgx.Conn) error {
+			lingering, err := lingeringTxPids(ctx, conn, dbName)
+			if err != nil || len(lingering) < inUse {
+				return err
+			}
+			_, err = conn.Exec(ctx,
+				`SELECT pg_terminate_backend(pid) FROM unnest($1::int[]) pid`,
+				lingering)
+			terminated = len(lingering)
+			return err
+		})
+	if err != nil {
+		return false, fmt.Errorf("can't roll back transactions in %v: %w",
+			dbName, err)
+	}
+	if terminated == 0 {
+		return false, nil
+	}
+
+	log.Printf("go-test-pg: test %v left %v transactions open in database "+
+		"%v, rolled them back", p.dbTest(dbName), terminated, dbName)
+	return true, nil
+}
+
+// Returns pids of sessions to database dbName idle in transaction.
+func lingeringTxPids(ctx context.Context, conn *pgx.Conn,
+	dbName string) ([]int32, error) {
+
+	rows, err := conn.Query(ctx, `
+SELECT pid
+FROM pg_stat_activity
+WHERE datname = $1
+	AND state IN ('idle in transaction', 'idle in transaction (aborted)')`,
+		dbName)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[int32])
+}
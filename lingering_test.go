@@ -0,0 +1,68 @@
+package go_test_pg
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPgpool_RollbackLingeringTx(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:            "go_test_pg",
+		SchemaFile:          "./testdata/schema1.sql",
+		RollbackLingeringTx: true,
+	}
+	pool, cleanupFn := dbPool.NewEmpty(t)
+
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tx.Exec(ctx, `INSERT INTO table1 (id) VALUES (1)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = cleanupFn(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPgpool_RollbackLingeringTx_Std(t *testing.T) {
+	var dbPool = Pgpool{RollbackLingeringTx: true}
+	db, cleanupFn := dbPool.NewStdEmpty(t)
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = tx.Exec(`SELECT 1`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = cleanupFn(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// connections acquired outside of transactions still fail cleanup
+func TestPgpool_RollbackLingeringTx_Acquired(t *testing.T) {
+	var dbPool = Pgpool{RollbackLingeringTx: true}
+	pool, cleanupFn := dbPool.NewEmpty(t)
+
+	conn, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = cleanupFn()
+	if !errors.Is(err, ErrUnreleasedConnections) {
+		t.Errorf("want ErrUnreleasedConnections, got %v", err)
+	}
+
+	conn.Release()
+	if err = cleanupFn(); err != nil {
+		t.Fatal(err)
+	}
+}
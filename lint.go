@@ -0,0 +1,163 @@
+package go_test_pg
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SchemaLint is a check of the schema run with SchemaLints option.
+type SchemaLint struct {
+	// Name identifies the check in reported problems. It is mixed into the
+	// template checksum, so the template is rebuilt and checked again when
+	// the set of checks changes.
+	Name string
+	// Check returns problems found in the database conn is connected to.
+	// Every problem should tell how to fix it.
+	Check func(ctx context.Context, conn *pgx.Conn) ([]string, error)
+}
+
+// Condition on pg_namespace n selecting schemas of the user, without
+// schemas installed by MockNow and CaptureDDL.
+const lintSchemas = `n.nspname NOT IN ('pg_catalog', 'information_schema',
+		'testclock', 'ddlaudit')
+	AND n.nspname NOT LIKE 'pg\_%'`
+
+// Condition on pg_class c excluding objects created by extensions.
+const lintNotExtension = `NOT EXISTS(
+		SELECT 1 FROM pg_depend d
+		WHERE d.classid = 'pg_class'::regclass
+			AND d.objid = c.oid
+			AND d.deptype = 'e')`
+
+// LintForeignKeyIndexes reports foreign keys without an index on the
+// referencing columns. Without an index, deletes from the referenced table
+// scan the referencing one.
+var LintForeignKeyIndexes = SchemaLint{
+	Name: "foreign-key-indexes",
+	Check: func(ctx context.Context, conn *pgx.Conn) ([]string, error) {
+		return lintQuery(ctx, conn, `
+SELECT format('foreign key %I of table %s has no index, add: '
+		'CREATE INDEX ON %s (%s)', con.conname, c.oid::regclass,
+		c.oid::regclass,
+		(SELECT string_agg(quote_ident(a.attname), ', ' ORDER BY k.i)
+		FROM unnest(con.conkey) WITH ORDINALITY k(n, i)
+			JOIN pg_attribute a ON a.attrelid = c.oid AND a.attnum = k.n))
+FROM pg_constraint con
+	JOIN pg_class c ON c.oid = con.conrelid
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE con.contype = 'f'
+	AND `+lintSchemas+`
+	AND `+lintNotExtension+`
+	AND NOT EXISTS(
+		SELECT 1 FROM pg_index i
+		WHERE i.indrelid = con.conrelid
+			AND (i.indkey::int2[])[0:cardinality(con.conkey) - 1]
+				@> con.conkey)
+ORDER BY 1`)
+	},
+}
+
+// LintTimestampTZ reports columns of type timestamp without time zone,
+// which are interpreted in the time zone of the session.
+var LintTimestampTZ = SchemaLint{
+	Name: "timestamp-tz",
+	Check: func(ctx context.Context, conn *pgx.Conn) ([]string, error) {
+		return lintQuery(ctx, conn, `
+SELECT format('column %s.%I is timestamp without time zone, '
+		'use timestamptz', c.oid::regclass, a.attname)
+FROM pg_attribute a
+	JOIN pg_class c ON c.oid = a.attrelid
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE a.atttypid = 'timestamp'::regtype
+	AND a.attnum > 0
+	AND NOT a.attisdropped
+	AND c.relkind IN ('r', 'p')
+	AND `+lintSchemas+`
+	AND `+lintNotExtension+`
+ORDER BY 1`)
+	},
+}
+
+// LintNames reports tables, views and their columns with names not
+// matching re, e.g. regexp.MustCompile(`^[a-z][a-z0-9_]*$`) for snake
+// case.
+func LintNames(re *regexp.Regexp) SchemaLint {
+	return SchemaLint{
+		Name: "names " + re.String(),
+		Check: func(ctx context.Context,
+			conn *pgx.Conn) ([]string, error) {
+
+			rows, err := conn.Query(ctx, `
+SELECT c.oid::regclass::text, c.relname, coalesce(a.attname, '')
+FROM pg_class c
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+	LEFT JOIN pg_attribute a ON a.attrelid = c.oid
+		AND a.attnum > 0 AND NOT a.attisdropped
+WHERE c.relkind IN ('r', 'p', 'v', 'm')
+	AND `+lintSchemas+`
+	AND `+lintNotExtension+`
+ORDER BY 1, a.attnum`)
+			if err != nil {
+				return nil, err
+			}
+
+			var problems []string
+			reported := make(map[string]bool)
+			var table, relName, column string
+			_, err = pgx.ForEachRow(rows, []any{&table, &relName, &column},
+				func() error {
+					if !re.MatchString(relName) && !reported[table] {
+						reported[table] = true
+						problems = append(problems, fmt.Sprintf(
+							"name of %v does not match %v", table, re))
+					}
+					if column != "" && !re.MatchString(column) {
+						problems = append(problems, fmt.Sprintf(
+							"name of column %v.%v does not match %v",
+							table, quote(column), re))
+					}
+					return nil
+				})
+			return problems, err
+		},
+	}
+}
+
+// Returns problems returned by query as rows with a single text column.
+func lintQuery(ctx context.Context, conn *pgx.Conn,
+	query string) ([]string, error) {
+
+	rows, err := conn.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// Runs SchemaLints over the template database conn is connected to.
+// Problems are reported with ErrSchemaLint.
+func (p *Pgpool) lintSchema(ctx context.Context, conn *pgx.Conn) error {
+	var problems []string
+	for _, lint := range p.SchemaLints {
+		found, err := lint.Check(ctx, conn)
+		if err != nil {
+			return fmt.Errorf("can't run schema lint %v: %w", lint.Name, err)
+		}
+		for _, problem := range found {
+			problems = append(problems, lint.Name+": "+problem)
+		}
+	}
+	return lintError(problems)
+}
+
+func lintError(problems []string) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w:\n\t%v", ErrSchemaLint,
+		strings.Join(problems, "\n\t"))
+}
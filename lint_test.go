@@ -0,0 +1,58 @@
+package go_test_pg
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestLintError(t *testing.T) {
+	if err := lintError(nil); err != nil {
+		t.Fatal(err)
+	}
+	err := lintError([]string{"a: one", "b: two"})
+	if !errors.Is(err, ErrSchemaLint) {
+		t.Fatalf("want ErrSchemaLint, got %v", err)
+	}
+	want := "schema lint failed:\n\ta: one\n\tb: two"
+	if err.Error() != want {
+		t.Fatalf("want %q, got %q", want, err.Error())
+	}
+}
+
+func TestPgpool_SchemaLints(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema_lint.sql",
+		SchemaLints: []SchemaLint{
+			LintForeignKeyIndexes,
+			LintTimestampTZ,
+			LintNames(regexp.MustCompile(`^[a-z][a-z0-9_]*$`)),
+		},
+	}
+	_, err := dbPool.prepareTmpl()
+	if !errors.Is(err, ErrSchemaLint) {
+		t.Fatalf("want ErrSchemaLint, got %v", err)
+	}
+	for _, problem := range []string{
+		"foreign-key-indexes: foreign key orders_user_id_fkey of table " +
+			"orders has no index, add: CREATE INDEX ON orders (user_id)",
+		`timestamp-tz: column users."createdAt" is timestamp without ` +
+			`time zone, use timestamptz`,
+		`name of column users."createdAt" does not match`,
+	} {
+		if !strings.Contains(err.Error(), problem) {
+			t.Errorf("problem %q is not reported: %v", problem, err)
+		}
+	}
+
+	var clean = Pgpool{
+		BaseName:    "go_test_pg",
+		SchemaFile:  "./testdata/schema1.sql",
+		SchemaLints: []SchemaLint{LintForeignKeyIndexes, LintTimestampTZ},
+	}
+	if _, err = clean.prepareTmpl(); err != nil {
+		t.Fatal(err)
+	}
+}
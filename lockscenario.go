@@ -0,0 +1,233 @@
+package go_test_pg
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Error code of deadlock_detected error.
+const pgErrDeadlockDetected = "40P01"
+
+// LockScenario runs statements on several sessions (connections) to the test
+// database in a fixed order. It is used to provoke lock waits and deadlocks
+// deterministically. Every session runs in its own transaction started by
+// NewLockScenario.
+type LockScenario struct {
+	pool *pgxpool.Pool
+	// Connection polling pg_stat_activity. It is not taken from pool, so
+	// all connections of pool may be used by sessions.
+	monitor  *pgx.Conn
+	monitorM sync.Mutex
+	ctx      context.Context
+	cancel   context.CancelFunc
+	sessions []*lockSession
+	wg       sync.WaitGroup
+}
+
+type lockSession struct {
+	conn  *pgxpool.Conn
+	pid   uint32
+	steps chan *LockStep
+}
+
+// LockStep is a statement started with LockScenario.Exec.
+type LockStep struct {
+	sql  string
+	args []any
+	done chan struct{}
+	err  error
+
+	// Blocked is true if the statement was waiting for a lock when Exec
+	// returned.
+	Blocked bool
+}
+
+// NewLockScenario acquires n connections from pool and begins a
+// transaction on every one of them. One more connection outside of pool is
+// opened to detect lock waits, so n may be up to MaxConns of pool. On test
+// cleanup all transactions are rolled back and connections are released.
+func NewLockScenario(t testing.TB, pool *pgxpool.Pool, n int) *LockScenario {
+	t.Helper()
+
+	if maxConns := pool.Config().MaxConns; int32(n) > maxConns {
+		t.Fatalf("lock scenario needs %v connections, pool allows %v",
+			n, maxConns)
+	}
+
+	s := &LockScenario{pool: pool}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+
+	ctx, cancel := context.WithTimeout(s.ctx, defaultTimeout)
+	defer cancel()
+
+	poolCfg := pool.Config()
+	cfg := poolCfg.ConnConfig
+	if poolCfg.BeforeConnect != nil {
+		if err := poolCfg.BeforeConnect(ctx, cfg); err != nil {
+			s.close()
+			t.Fatalf("can't configure monitor connection: %v", err)
+		}
+	}
+	var err error
+	s.monitor, err = pgx.ConnectConfig(ctx, cfg)
+	if err != nil {
+		s.close()
+		t.Fatalf("can't open monitor connection: %v", err)
+	}
+
+	for i := 0; i < n; i++ {
+		var conn *pgxpool.Conn
+		conn, err = pool.Acquire(ctx)
+		if err != nil {
+			s.close()
+			t.Fatalf("can't acquire connection: %v", err)
+		}
+
+		sess := &lockSession{
+			conn:  conn,
+			pid:   conn.Conn().PgConn().PID(),
+			steps: make(chan *LockStep, 1),
+		}
+		s.sessions = append(s.sessions, sess)
+		s.wg.Add(1)
+		go s.run(sess)
+
+		err = s.Exec(t, i, `BEGIN`).Wait(t, defaultTimeout)
+		if err != nil {
+			s.close()
+			t.Fatalf("can't begin transaction: %v", err)
+		}
+	}
+
+	t.Cleanup(s.close)
+	return s
+}
+
+func (s *LockScenario) run(sess *lockSession) {
+	defer s.wg.Done()
+	for step := range sess.steps {
+		_, step.err = sess.conn.Exec(s.ctx, step.sql, step.args...)
+		close(step.done)
+	}
+}
+
+// Exec starts sql on session with index session and returns when the
+// statement completes or when it starts waiting for a lock. In the latter
+// case returned step has Blocked set, and its result may be waited with
+// LockStep.Wait. Do not start new statements on a session until its
+// blocked statement completes.
+func (s *LockScenario) Exec(t testing.TB, session int, sql string,
+	args ...any) *LockStep {
+
+	t.Helper()
+
+	if session < 0 || session >= len(s.sessions) {
+		t.Fatalf("no session %v in lock scenario", session)
+	}
+	sess := s.sessions[session]
+
+	step := &LockStep{sql: sql, args: args, done: make(chan struct{})}
+	sess.steps <- step
+
+	timeout := time.NewTimer(defaultTimeout)
+	defer timeout.Stop()
+
+	for {
+		select {
+		case <-step.done:
+			return step
+		case <-timeout.C:
+			t.Fatalf("statement on session %v neither completed nor "+
+				"blocked within %v: %v", session, defaultTimeout, sql)
+		case <-time.After(activityPollInterval):
+		}
+
+		waitsLock, err := s.waitsLock(sess.pid)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			t.Fatalf("can't check session %v state: %v", session, err)
+		}
+		if waitsLock {
+			step.Blocked = true
+			return step
+		}
+	}
+}
+
+// Returns true if backend pid waits for a lock.
+func (s *LockScenario) waitsLock(pid uint32) (bool, error) {
+	s.monitorM.Lock()
+	defer s.monitorM.Unlock()
+
+	ctx, cancel := context.WithTimeout(s.ctx, defaultTimeout)
+	defer cancel()
+
+	var waitsLock bool
+	err := s.monitor.QueryRow(ctx, `
+SELECT coalesce(wait_event_type = 'Lock', false)
+FROM pg_stat_activity
+WHERE pid = $1`, pid).Scan(&waitsLock)
+	return waitsLock, err
+}
+
+// Wait waits for the statement to complete and returns its error.
+func (st *LockStep) Wait(t testing.TB, timeout time.Duration) error {
+	t.Helper()
+
+	select {
+	case <-st.done:
+		return st.err
+	case <-time.After(timeout):
+		t.Fatalf("statement did not complete within %v: %v",
+			timeout, st.sql)
+		return nil
+	}
+}
+
+// Rolls back all transactions and releases connections.
+func (s *LockScenario) close() {
+	// Rollback is queued after pending statements of every session. Blocked
+	// statements complete as soon as other sessions roll back.
+	for _, sess := range s.sessions {
+		go func(sess *lockSession) {
+			sess.steps <- &LockStep{sql: `ROLLBACK`,
+				done: make(chan struct{})}
+			close(sess.steps)
+		}(sess)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(defaultTimeout):
+		s.cancel()
+		<-done
+	}
+	s.cancel()
+
+	for _, sess := range s.sessions {
+		sess.conn.Release()
+	}
+	s.sessions = nil
+
+	if s.monitor != nil {
+		closeConn(s.monitor)
+		s.monitor = nil
+	}
+}
+
+// IsDeadlock returns true if err is a deadlock_detected error.
+func IsDeadlock(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgErrDeadlockDetected
+}
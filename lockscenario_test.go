@@ -0,0 +1,63 @@
+package go_test_pg
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockScenario_Deadlock(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+	}
+	pool := dbPool.WithSQLs(t, []string{
+		`INSERT INTO table1 (id) VALUES (1), (2)`,
+	})
+
+	s := NewLockScenario(t, pool, 2)
+	lockRow := `SELECT id FROM table1 WHERE id = $1 FOR UPDATE`
+
+	if s.Exec(t, 0, lockRow, 1).Blocked {
+		t.Fatal("session 0 must not be blocked")
+	}
+	if s.Exec(t, 1, lockRow, 2).Blocked {
+		t.Fatal("session 1 must not be blocked")
+	}
+
+	step0 := s.Exec(t, 0, lockRow, 2)
+	if !step0.Blocked {
+		t.Fatal("session 0 must be blocked by session 1")
+	}
+
+	// One of the sessions is chosen as a deadlock victim.
+	step1 := s.Exec(t, 1, lockRow, 1)
+	err0 := step0.Wait(t, 10*time.Second)
+	err1 := step1.Wait(t, 10*time.Second)
+	if IsDeadlock(err0) == IsDeadlock(err1) {
+		t.Fatalf("want exactly one deadlock error, got %v and %v",
+			err0, err1)
+	}
+}
+
+func TestLockScenario_AllConnections(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+	}
+	pool := dbPool.WithSQLs(t, []string{
+		`INSERT INTO table1 (id) VALUES (1)`,
+	})
+
+	n := int(pool.Config().MaxConns)
+	s := NewLockScenario(t, pool, n)
+	lockRow := `SELECT id FROM table1 WHERE id = $1 FOR UPDATE`
+
+	if s.Exec(t, 0, lockRow, 1).Blocked {
+		t.Fatal("session 0 must not be blocked")
+	}
+	for i := 1; i < n; i++ {
+		if !s.Exec(t, i, lockRow, 1).Blocked {
+			t.Fatalf("session %v must be blocked by session 0", i)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package go_test_pg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Version of the way template databases are built. Increase it when the
+// content of templates changes for the same schema file, so templates
+// built by older versions are rebuilt.
+const templateFormat = 1
+
+// Module path of this package, used to find its version in build info.
+const modulePath = "github.com/olomix/go-test-pg/v2"
+
+// templateManifest describes how a template database was built. It is
+// stored as a comment on the template database, so it is not copied to
+// test databases.
+type templateManifest struct {
+	Format    int       `json:"format"`
+	Checksum  string    `json:"checksum"`
+	CreatedAt time.Time `json:"created_at"`
+	// UsedAt is updated when the template is reused with KeepTemplates.
+	UsedAt  *time.Time `json:"used_at,omitempty"`
+	Library string     `json:"library"`
+	Server  string     `json:"server"`
+}
+
+// Returns the reason why template database with comment and datallowconn
+// allowConn can't be reused for schema with checksum, or empty string if it
+// can. Templates are closed for connections after creation, so a template
+// allowing connections may have been modified manually.
+func validateManifest(comment *string, allowConn bool,
+	checksum string) string {
+
+	if comment == nil {
+		return "no manifest"
+	}
+	var m templateManifest
+	if err := json.Unmarshal([]byte(*comment), &m); err != nil {
+		return fmt.Sprintf("invalid manifest: %v", err)
+	}
+	if m.Format != templateFormat {
+		return fmt.Sprintf("built by library %v with template format %v, "+
+			"want format %v", m.Library, m.Format, templateFormat)
+	}
+	if m.Checksum != checksum {
+		return fmt.Sprintf("built for schema checksum %v", m.Checksum)
+	}
+	if allowConn {
+		return "connections are allowed, it may have been modified"
+	}
+	return ""
+}
+
+// Checks template database dbName. Returns false if the database does not
+// exist. Returns non-empty reason if it exists but can't be reused.
+func checkTemplate(ctx context.Context, conn *pgx.Conn, dbName,
+	checksum string) (exists bool, reason string, err error) {
+
+	var comment *string
+	var allowConn bool
+	err = conn.QueryRow(ctx, `
+SELECT shobj_description(oid, 'pg_database'), datallowconn
+FROM pg_database
+WHERE datname = $1`, dbName).Scan(&comment, &allowConn)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, "", nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+	return true, validateManifest(comment, allowConn, checksum), nil
+}
+
+// Stores manifest of template database dbName and closes it for
+// connections.
+func writeManifest(ctx context.Context, conn *pgx.Conn, dbName,
+	checksum string) error {
+
+	m := templateManifest{
+		Format:    templateFormat,
+		Checksum:  checksum,
+		CreatedAt: time.Now().UTC(),
+		Library:   libraryVersion(),
+	}
+	err := conn.QueryRow(ctx, `SHOW server_version`).Scan(&m.Server)
+	if err != nil {
+		return err
+	}
+
+	buf, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.Exec(ctx, `COMMENT ON DATABASE `+quote(dbName)+` IS `+
+		quoteLiteral(string(buf)))
+	if err != nil {
+		return err
+	}
+	_, err = conn.Exec(ctx,
+		`ALTER DATABASE `+quote(dbName)+` ALLOW_CONNECTIONS false`)
+	return err
+}
+
+// Returns version of this module from build info of the binary.
+func libraryVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	if info.Main.Path == modulePath {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			return dep.Version
+		}
+	}
+	return "unknown"
+}
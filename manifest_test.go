@@ -0,0 +1,76 @@
+package go_test_pg
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestValidateManifest(t *testing.T) {
+	manifest := func(m templateManifest) *string {
+		buf, err := json.Marshal(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := string(buf)
+		return &s
+	}
+	valid := manifest(templateManifest{Format: templateFormat,
+		Checksum: "abc"})
+	invalid := "{"
+
+	testCases := []struct {
+		name      string
+		comment   *string
+		allowConn bool
+		valid     bool
+	}{
+		{"valid", valid, false, true},
+		{"no manifest", nil, false, false},
+		{"invalid json", &invalid, false, false},
+		{"old format", manifest(templateManifest{Format: 0,
+			Checksum: "abc"}), false, false},
+		{"other checksum", manifest(templateManifest{
+			Format: templateFormat, Checksum: "def"}), false, false},
+		{"connections allowed", valid, true, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reason := validateManifest(tc.comment, tc.allowConn, "abc")
+			if (reason == "") != tc.valid {
+				t.Fatalf("want valid %v, got reason %q", tc.valid, reason)
+			}
+		})
+	}
+}
+
+func TestTemplateManifest(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+	}
+	pool := dbPool.WithEmpty(t)
+
+	var comment *string
+	var allowConn bool
+	err := pool.QueryRow(context.Background(), `
+SELECT shobj_description(oid, 'pg_database'), datallowconn
+FROM pg_database
+WHERE datname = $1`, dbPool.tmpl).Scan(&comment, &allowConn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if comment == nil {
+		t.Fatal("template has no manifest")
+	}
+	var m templateManifest
+	if err = json.Unmarshal([]byte(*comment), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Format != templateFormat || m.Server == "" {
+		t.Fatalf("unexpected manifest: %+v", m)
+	}
+	if allowConn {
+		t.Fatal("template allows connections")
+	}
+}
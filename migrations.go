@@ -0,0 +1,85 @@
+package go_test_pg
+
+import (
+	"context"
+	"testing"
+)
+
+// Migrations applies and rolls back migrations of the database for
+// TestMigrationsReversible. Up and Down get the connection string of the
+// test database, so any migration tool may be used, e.g. golang-migrate,
+// goose or a subprocess.
+type Migrations struct {
+	// Up applies all migrations.
+	Up func(ctx context.Context, dsn string) error
+	// Down rolls back all migrations.
+	Down func(ctx context.Context, dsn string) error
+	// IgnoreTables are names of tables left out of the comparison, e.g.
+	// version tables of migration tools, which are not dropped by Down.
+	// Default is DefaultMigrationTables.
+	IgnoreTables []string
+}
+
+// DefaultMigrationTables are version tables of popular migration tools.
+var DefaultMigrationTables = []string{
+	"schema_migrations",
+	"goose_db_version",
+	"gorp_migrations",
+	"flyway_schema_history",
+	"atlas_schema_revisions",
+}
+
+// TestMigrationsReversible applies migrations to an empty test database,
+// rolls them back and applies them again. The test fails if the database
+// after rollback differs from the empty one or the database after the
+// second run differs from the first run, which catches broken down
+// migrations. Databases are compared as with DiffSchema.
+func (p *Pgpool) TestMigrationsReversible(t testing.TB, m Migrations) {
+	t.Helper()
+
+	pool := p.WithEmpty(t)
+	dsn := p.ConnString(t, pool)
+	ignore := m.IgnoreTables
+	if ignore == nil {
+		ignore = DefaultMigrationTables
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout())
+	defer cancel()
+
+	catalog := func(step string) []schemaObject {
+		t.Helper()
+		c, err := readSchema(ctx, pool, ignore)
+		if err != nil {
+			t.Fatalf("can't read catalog %v: %v", step, err)
+		}
+		return c
+	}
+	migrate := func(step string, f func(context.Context, string) error) {
+		t.Helper()
+		// Connections of the pool may have cached plans of dropped
+		// objects.
+		pool.Reset()
+		if err := f(ctx, dsn); err != nil {
+			t.Fatalf("%v failed: %v", step, err)
+		}
+		pool.Reset()
+	}
+
+	empty := catalog("before migrations")
+	migrate("up", m.Up)
+	up := catalog("after up")
+	migrate("down", m.Down)
+	down := catalog("after down")
+	migrate("second up", m.Up)
+	again := catalog("after second up")
+
+	if diff := diffSchemaObjects(empty, down); len(diff) != 0 {
+		t.Errorf("down migrations left changes (- before up, "+
+			"+ after down):\n%v", diff)
+	}
+	if diff := diffSchemaObjects(up, again); len(diff) != 0 {
+		t.Errorf("schema after up, down and up differs from up "+
+			"(- up, + up after down):\n%v", diff)
+	}
+}
@@ -0,0 +1,59 @@
+package go_test_pg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type errorfTB struct {
+	testing.TB
+	errors []string
+}
+
+func (r *errorfTB) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func execMigration(sql string) func(ctx context.Context, dsn string) error {
+	return func(ctx context.Context, dsn string) error {
+		conn, err := pgx.Connect(ctx, dsn)
+		if err != nil {
+			return err
+		}
+		defer conn.Close(ctx)
+		_, err = conn.Exec(ctx, sql)
+		return err
+	}
+}
+
+func TestPgpool_TestMigrationsReversible(t *testing.T) {
+	var dbPool = Pgpool{BaseName: "go_test_pg"}
+	up := execMigration(`
+CREATE TABLE schema_migrations (version int);
+CREATE TABLE users (id SERIAL PRIMARY KEY, name text NOT NULL);
+CREATE INDEX users_name_idx ON users (name);`)
+
+	dbPool.TestMigrationsReversible(t, Migrations{
+		Up: up,
+		Down: execMigration(`
+DROP TABLE users;
+CREATE TABLE IF NOT EXISTS schema_migrations (version int);`),
+	})
+
+	tb := &errorfTB{TB: t}
+	dbPool.TestMigrationsReversible(tb, Migrations{
+		Up: execMigration(`
+CREATE TABLE IF NOT EXISTS schema_migrations (version int);
+CREATE TABLE IF NOT EXISTS users (id SERIAL PRIMARY KEY, name text);
+CREATE INDEX IF NOT EXISTS users_name_idx ON users (name);`),
+		Down: execMigration(`DROP INDEX users_name_idx`),
+	})
+	if len(tb.errors) != 1 ||
+		!strings.Contains(tb.errors[0], "+ table users") {
+		t.Fatalf("want leftover users table reported, got %v", tb.errors)
+	}
+}
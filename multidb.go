@@ -0,0 +1,42 @@
+package go_test_pg
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DBSpec describes one of databases created by WithDatabases.
+type DBSpec struct {
+	// Name is the key of the database pool in the result of WithDatabases.
+	Name string
+	// Pool is the configuration the database is created with. Usually it
+	// is a package-level variable, so the template database is created
+	// once.
+	Pool *Pgpool
+}
+
+// WithDatabases creates a database for every spec, like Pgpool.WithEmpty
+// does, and returns their pools by spec names. Specs may use different
+// schema files. All databases are dropped when the test finishes.
+func WithDatabases(t testing.TB, specs ...DBSpec) map[string]*pgxpool.Pool {
+	t.Helper()
+
+	for i, s := range specs {
+		if s.Name == "" {
+			t.Fatalf("database spec at idx %v has no name", i)
+		}
+		if s.Pool == nil {
+			t.Fatalf("database spec %v has no pool", s.Name)
+		}
+	}
+
+	pools := make(map[string]*pgxpool.Pool, len(specs))
+	for _, s := range specs {
+		if _, ok := pools[s.Name]; ok {
+			t.Fatalf("duplicate database spec %v", s.Name)
+		}
+		pools[s.Name] = s.Pool.WithEmpty(t)
+	}
+	return pools
+}
@@ -0,0 +1,26 @@
+package go_test_pg
+
+import (
+	"testing"
+)
+
+func TestWithDatabases(t *testing.T) {
+	var appPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+	}
+	var warehousePool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema2.sql",
+	}
+
+	pools := WithDatabases(t,
+		DBSpec{Name: "app", Pool: &appPool},
+		DBSpec{Name: "warehouse", Pool: &warehousePool},
+	)
+
+	AssertTableExists(t, pools["app"], "table1")
+	AssertTableNotExists(t, pools["app"], "events")
+	AssertTableExists(t, pools["warehouse"], "events")
+	AssertTableNotExists(t, pools["warehouse"], "table1")
+}
@@ -0,0 +1,180 @@
+package go_test_pg
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// WaitForNotification subscribes conn to channel and waits for the next
+// notification on it. Notifications on other channels are skipped. Test
+// fails if no notification arrives within timeout.
+//
+// Notifications sent before conn starts listening are lost. If the code
+// under test sends notification before WaitForNotification is called, use
+// NotifyRecorder.
+func WaitForNotification(t testing.TB, conn *pgx.Conn, channel string,
+	timeout time.Duration) *pgconn.Notification {
+
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	_, err := conn.Exec(ctx, `LISTEN `+quote(channel))
+	if err != nil {
+		t.Fatalf("can't listen channel %v: %v", channel, err)
+	}
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			t.Fatalf("no notification on channel %v: %v", channel, err)
+		}
+		if n.Channel == channel {
+			return n
+		}
+	}
+}
+
+// NotifyRecorder listens channels on a dedicated connection to the test
+// database and accumulates received notifications.
+type NotifyRecorder struct {
+	conn   *pgx.Conn
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	m             sync.Mutex
+	notifications []*pgconn.Notification
+	err           error
+	// Closed and replaced on every new notification.
+	changed chan struct{}
+}
+
+// NewNotifyRecorder opens a new connection to the database of pool,
+// subscribes it to channels and starts recording notifications. The
+// connection is closed on test cleanup.
+func NewNotifyRecorder(t testing.TB, pool *pgxpool.Pool,
+	channels ...string) *NotifyRecorder {
+
+	t.Helper()
+
+	cfg := pool.Config()
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	if cfg.BeforeConnect != nil {
+		if err := cfg.BeforeConnect(ctx, cfg.ConnConfig); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+
+	conn, err := pgx.ConnectConfig(ctx, cfg.ConnConfig)
+	if err != nil {
+		t.Fatalf("can't connect to database: %v", err)
+	}
+
+	for _, ch := range channels {
+		_, err = conn.Exec(ctx, `LISTEN `+quote(ch))
+		if err != nil {
+			_ = conn.Close(ctx)
+			t.Fatalf("can't listen channel %v: %v", ch, err)
+		}
+	}
+
+	r := &NotifyRecorder{
+		conn:    conn,
+		done:    make(chan struct{}),
+		changed: make(chan struct{}),
+	}
+	var loopCtx context.Context
+	loopCtx, r.cancel = context.WithCancel(context.Background())
+	go r.loop(loopCtx)
+
+	t.Cleanup(func() {
+		r.cancel()
+		<-r.done
+		ctx, cancel := context.WithTimeout(context.Background(),
+			defaultTimeout)
+		defer cancel()
+		if err := r.conn.Close(ctx); err != nil {
+			t.Errorf("can't close notify recorder connection: %v", err)
+		}
+	})
+
+	return r
+}
+
+func (r *NotifyRecorder) loop(ctx context.Context) {
+	defer close(r.done)
+	for {
+		n, err := r.conn.WaitForNotification(ctx)
+
+		r.m.Lock()
+		if err != nil {
+			if ctx.Err() == nil {
+				r.err = err
+			}
+		} else {
+			r.notifications = append(r.notifications, n)
+		}
+		close(r.changed)
+		r.changed = make(chan struct{})
+		r.m.Unlock()
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Notifications returns all notifications received so far.
+func (r *NotifyRecorder) Notifications() []*pgconn.Notification {
+	r.m.Lock()
+	defer r.m.Unlock()
+	return append([]*pgconn.Notification(nil), r.notifications...)
+}
+
+// Wait waits until at least n notifications are received on channel and
+// returns all of them. If channel is empty, notifications on all channels
+// are counted. Test fails if they do not arrive within timeout.
+func (r *NotifyRecorder) Wait(t testing.TB, channel string, n int,
+	timeout time.Duration) []*pgconn.Notification {
+
+	t.Helper()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		r.m.Lock()
+		var received []*pgconn.Notification
+		for _, ntf := range r.notifications {
+			if channel == "" || ntf.Channel == channel {
+				received = append(received, ntf)
+			}
+		}
+		err := r.err
+		changed := r.changed
+		r.m.Unlock()
+
+		if len(received) >= n {
+			return received
+		}
+		if err != nil {
+			t.Fatalf("notify recorder failed: %v", err)
+		}
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			t.Fatalf("want %v notifications on channel %q, got %v",
+				n, channel, len(received))
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package go_test_pg
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNotifyRecorder(t *testing.T) {
+	var dbPool = Pgpool{}
+	pool := dbPool.WithEmpty(t)
+
+	r := NewNotifyRecorder(t, pool, "events")
+
+	ctx := context.Background()
+	for _, payload := range []string{"one", "two"} {
+		_, err := pool.Exec(ctx, `SELECT pg_notify('events', $1)`, payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ns := r.Wait(t, "events", 2, 5*time.Second)
+	if ns[0].Payload != "one" || ns[1].Payload != "two" {
+		t.Fatalf("unexpected notifications: %v, %v",
+			ns[0].Payload, ns[1].Payload)
+	}
+}
+
+func TestWaitForNotification(t *testing.T) {
+	var dbPool = Pgpool{}
+	pool := dbPool.WithEmpty(t)
+
+	ctx := context.Background()
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, `LISTEN events`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = pool.Exec(ctx, `NOTIFY events, 'hello'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := WaitForNotification(t, conn.Conn(), "events", 5*time.Second)
+	if n.Payload != "hello" {
+		t.Fatalf("unexpected payload: %v", n.Payload)
+	}
+}
@@ -0,0 +1,110 @@
+package go_test_pg
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// PartitionInterval is the range of values of a single partition created
+// by CreatePartitions.
+type PartitionInterval int
+
+const (
+	// PartitionDaily creates partitions named parent_YYYYMMDD.
+	PartitionDaily PartitionInterval = iota
+	// PartitionMonthly creates partitions named parent_YYYYMM.
+	PartitionMonthly
+	// PartitionYearly creates partitions named parent_YYYY.
+	PartitionYearly
+)
+
+// CreatePartitions creates partitions of table parent partitioned by range
+// of a date or timestamp column, so they cover times from through to
+// inclusive. Boundaries of partitions are aligned to interval in UTC.
+// Existing partitions with the same names are left intact.
+func CreatePartitions(t testing.TB, q Querier, parent string, from,
+	to time.Time, interval PartitionInterval) {
+
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var strategy *string
+	err := q.QueryRow(ctx, `
+SELECT (
+	SELECT partstrat::text
+	FROM pg_partitioned_table
+	WHERE partrelid = to_regclass($1))`, parent).Scan(&strategy)
+	if err != nil {
+		t.Fatalf("can't check table %v: %v", parent, err)
+	}
+	if strategy == nil {
+		t.Fatalf("table %v is not partitioned", parent)
+	}
+	if *strategy != "r" {
+		t.Fatalf("table %v is not partitioned by range", parent)
+	}
+
+	for _, s := range partitionSQLs(parent, from, to, interval) {
+		if _, err = q.Exec(ctx, s); err != nil {
+			t.Fatalf("can't create partition of %v: %v", parent, err)
+		}
+	}
+}
+
+// Returns SQLs creating partitions of parent covering from through to.
+func partitionSQLs(parent string, from, to time.Time,
+	interval PartitionInterval) []string {
+
+	var sqls []string
+	to = to.UTC()
+	for start := truncPartition(from.UTC(), interval); !start.After(to); {
+		end := nextPartition(start, interval)
+		sqls = append(sqls, fmt.Sprintf(
+			`CREATE TABLE IF NOT EXISTS %v PARTITION OF %v `+
+				`FOR VALUES FROM ('%v') TO ('%v')`,
+			quoteQualified(parent+"_"+partitionSuffix(start, interval)),
+			quoteQualified(parent),
+			start.Format("2006-01-02 15:04:05Z07"),
+			end.Format("2006-01-02 15:04:05Z07")))
+		start = end
+	}
+	return sqls
+}
+
+func truncPartition(tm time.Time, interval PartitionInterval) time.Time {
+	switch interval {
+	case PartitionMonthly:
+		return time.Date(tm.Year(), tm.Month(), 1, 0, 0, 0, 0, time.UTC)
+	case PartitionYearly:
+		return time.Date(tm.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(tm.Year(), tm.Month(), tm.Day(), 0, 0, 0, 0,
+			time.UTC)
+	}
+}
+
+func nextPartition(tm time.Time, interval PartitionInterval) time.Time {
+	switch interval {
+	case PartitionMonthly:
+		return tm.AddDate(0, 1, 0)
+	case PartitionYearly:
+		return tm.AddDate(1, 0, 0)
+	default:
+		return tm.AddDate(0, 0, 1)
+	}
+}
+
+func partitionSuffix(tm time.Time, interval PartitionInterval) string {
+	switch interval {
+	case PartitionMonthly:
+		return tm.Format("200601")
+	case PartitionYearly:
+		return tm.Format("2006")
+	default:
+		return tm.Format("20060102")
+	}
+}
@@ -0,0 +1,55 @@
+package go_test_pg
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPartitionSQLs(t *testing.T) {
+	from := time.Date(2020, 11, 15, 10, 0, 0, 0, time.UTC)
+	to := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	got := partitionSQLs("s.events", from, to, PartitionMonthly)
+	want := []string{
+		`CREATE TABLE IF NOT EXISTS "s"."events_202011" PARTITION OF ` +
+			`"s"."events" FOR VALUES ` +
+			`FROM ('2020-11-01 00:00:00Z') TO ('2020-12-01 00:00:00Z')`,
+		`CREATE TABLE IF NOT EXISTS "s"."events_202012" PARTITION OF ` +
+			`"s"."events" FOR VALUES ` +
+			`FROM ('2020-12-01 00:00:00Z') TO ('2021-01-01 00:00:00Z')`,
+		`CREATE TABLE IF NOT EXISTS "s"."events_202101" PARTITION OF ` +
+			`"s"."events" FOR VALUES ` +
+			`FROM ('2021-01-01 00:00:00Z') TO ('2021-02-01 00:00:00Z')`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected SQLs:\n%v", got)
+	}
+
+	got = partitionSQLs("events", from, from, PartitionDaily)
+	if len(got) != 1 {
+		t.Fatalf("want 1 partition, got %v", got)
+	}
+}
+
+func TestCreatePartitions(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema_partitioned.sql",
+	}
+	pool := dbPool.WithEmpty(t)
+
+	from := time.Date(2020, 2, 27, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, 3, 2, 0, 0, 0, 0, time.UTC)
+	CreatePartitions(t, pool, "measurements", from, to, PartitionDaily)
+	// existing partitions are skipped
+	CreatePartitions(t, pool, "measurements", from, to, PartitionDaily)
+
+	AssertTableExists(t, pool, "measurements_20200229")
+	_, err := pool.Exec(context.Background(),
+		`INSERT INTO measurements (taken_at) VALUES ($1), ($2)`, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertRowCount(t, pool, "measurements_20200302", 1)
+}
@@ -0,0 +1,54 @@
+package go_test_pg
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgpassfile"
+	"github.com/jackc/pgx/v5"
+)
+
+// Returns the password of the user of cfg from the password file, or empty
+// string if it is not found. pgx looks up the password of the user of the
+// connection string only, this is used when the user is replaced.
+func passfilePassword(cfg *pgx.ConnConfig) string {
+	fileName := passfilePath()
+	if fileName == "" {
+		return ""
+	}
+	passfile, err := pgpassfile.ReadPassfile(fileName)
+	if err != nil {
+		return ""
+	}
+
+	// libpq matches unix socket connections with localhost entries.
+	host := cfg.Host
+	if strings.HasPrefix(host, "/") {
+		host = "localhost"
+	}
+	return passfile.FindPassword(host, strconv.Itoa(int(cfg.Port)),
+		cfg.Database, cfg.User)
+}
+
+// Returns the path to the password file the same way libpq does.
+func passfilePath() string {
+	if fileName := os.Getenv("PGPASSFILE"); fileName != "" {
+		return fileName
+	}
+	if runtime.GOOS == "windows" {
+		appData := os.Getenv("APPDATA")
+		if appData == "" {
+			return ""
+		}
+		return filepath.Join(appData, "postgresql", "pgpass.conf")
+	}
+	u, err := user.Current()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(u.HomeDir, ".pgpass")
+}
@@ -0,0 +1,61 @@
+package go_test_pg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestPassfilePassword(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "pgpass")
+	err := os.WriteFile(fileName, []byte(
+		"localhost:5432:*:app:app_secret\n"+
+			"db:*:*:*:admin_secret\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PGPASSFILE", fileName)
+
+	testCases := []struct {
+		host string
+		user string
+		want string
+	}{
+		{"/var/run/postgresql", "app", "app_secret"},
+		{"localhost", "app", "app_secret"},
+		{"localhost", "other", ""},
+		{"db", "app", "admin_secret"},
+	}
+	for _, tc := range testCases {
+		cfg, err := pgx.ParseConfig("password=x")
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.Host, cfg.Port, cfg.User = tc.host, 5432, tc.user
+		cfg.Database = "go_test_pg_1"
+		if got := passfilePassword(cfg); got != tc.want {
+			t.Errorf("%v@%v: want %q, got %q", tc.user, tc.host, tc.want,
+				got)
+		}
+	}
+}
+
+func TestTestConnConfigPassfile(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "pgpass")
+	err := os.WriteFile(fileName, []byte("*:*:*:app:app_secret\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PGPASSFILE", fileName)
+
+	p := &Pgpool{AppUser: "app", Hosts: []string{"db"}}
+	cfg, err := p.testConnConfig("go_test_pg_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.User != "app" || cfg.Password != "app_secret" {
+		t.Fatalf("unexpected credentials %v:%v", cfg.User, cfg.Password)
+	}
+}
@@ -0,0 +1,68 @@
+package go_test_pg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// WithPgConn creates database from template database and returns a raw
+// low-level connection to it, for tests of protocol-level code like
+// pipeline mode or CopyBoth. The connection is closed and the database is
+// dropped when the test finishes.
+func (p *Pgpool) WithPgConn(t testing.TB) *pgconn.PgConn {
+	t.Helper()
+
+	dbName, err := p.createRndDB(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := p.connectPgConn(dbName)
+	if err != nil {
+		_ = p.dropDB(dbName)
+		t.Fatalf("can't connect to %v: %v", dbName, err)
+	}
+
+	p.startWatchdog(t, dbName)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(),
+			defaultTimeout)
+		// Close is safe to call on a connection closed by the test.
+		err := conn.Close(ctx)
+		cancel()
+		if err != nil {
+			t.Errorf("Can't close connection to %v: %v", dbName, err)
+		}
+		if err = p.releaseDB(dbName); err != nil {
+			t.Errorf("Can't drop DB %v: %v%v", dbName, err,
+				p.activityHint(dbName))
+		}
+	})
+	return conn
+}
+
+// Connects to database dbName. Session settings are sent as run-time
+// parameters in the startup message.
+func (p *Pgpool) connectPgConn(dbName string) (*pgconn.PgConn, error) {
+	cfg, err := p.testConnConfig(dbName)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	if err = p.beforeConnect(ctx, cfg); err != nil {
+		return nil, err
+	}
+	if !p.opts().pgBouncer {
+		for name, value := range p.sessionSettings() {
+			cfg.RuntimeParams[name] = value
+		}
+	}
+
+	conn, err := pgconn.ConnectConfig(ctx, &cfg.Config)
+	return conn, err
+}
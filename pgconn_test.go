@@ -0,0 +1,28 @@
+package go_test_pg
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPgpool_WithPgConn(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:        "go_test_pg",
+		SchemaFile:      "./testdata/schema1.sql",
+		SessionSettings: map[string]string{"app.tenant_id": "42"},
+	}
+	conn := dbPool.WithPgConn(t)
+
+	results, err := conn.Exec(context.Background(),
+		`INSERT INTO table1 (name) VALUES ('a');
+		SELECT current_setting('app.tenant_id')`).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("want 2 results, got %v", len(results))
+	}
+	if got := string(results[1].Rows[0][0]); got != "42" {
+		t.Fatalf("unexpected app.tenant_id: %v", got)
+	}
+}
@@ -0,0 +1,151 @@
+package go_test_pg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// PlanWarnEnv is the environment variable that makes AssertPlanGolden log
+// changed plans instead of failing the test when set to a non-empty value.
+const PlanWarnEnv = "GO_TEST_PG_PLAN_WARN"
+
+// AssertPlanGolden compares the shape of the plan of query with the golden
+// file testdata/golden/<name>.plan. The shape is a tree of plan nodes with
+// join types, scanned relations and used indexes; costs, row estimates and
+// other numbers are left out, so the file changes only when the planner
+// picks a different plan, e.g. after an index was dropped or a query was
+// rewritten. Plans of small tables depend on their statistics, so load
+// representative fixtures and run ANALYZE first. The query is only
+// planned, not executed.
+//
+// Golden files are rewritten as with AssertQueryGolden. If PlanWarnEnv is
+// set, a changed plan is logged and the test does not fail.
+func AssertPlanGolden(t testing.TB, q Querier, name, query string,
+	args ...any) {
+
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var plan string
+	err := q.QueryRow(ctx, `EXPLAIN (FORMAT JSON, VERBOSE) `+query,
+		args...).Scan(&plan)
+	if err != nil {
+		t.Fatalf("can't explain query: %v%v", err, querierHint(q))
+	}
+	root, err := parsePlan(plan)
+	if err != nil {
+		t.Fatalf("can't parse query plan: %v", err)
+	}
+	actual := planShape(root)
+
+	fileName := filepath.Join(goldenDir, name+".plan")
+	if updateGolden() {
+		err = os.MkdirAll(goldenDir, 0o755)
+		if err == nil {
+			err = os.WriteFile(fileName, []byte(actual), 0o644)
+		}
+		if err != nil {
+			t.Fatalf("can't update golden file: %v", err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(fileName)
+	if os.IsNotExist(err) {
+		t.Fatalf("golden file %v does not exist, run tests with -update "+
+			"flag or %v=1 to create it", fileName, UpdateGoldenEnv)
+	}
+	if err != nil {
+		t.Fatalf("can't read golden file: %v", err)
+	}
+
+	if string(expected) != actual {
+		diff := lineDiff(splitLines(string(expected)), splitLines(actual))
+		if os.Getenv(PlanWarnEnv) != "" {
+			t.Logf("go-test-pg: plan of query differs from %v:\n%v",
+				fileName, diff)
+			return
+		}
+		t.Fatalf("plan of query differs from %v:\n%v", fileName, diff)
+	}
+}
+
+// Node of a plan in JSON format.
+type planNode struct {
+	NodeType     string     `json:"Node Type"`
+	Strategy     string     `json:"Strategy"`
+	JoinType     string     `json:"Join Type"`
+	RelationName string     `json:"Relation Name"`
+	FunctionName string     `json:"Function Name"`
+	CTEName      string     `json:"CTE Name"`
+	Schema       string     `json:"Schema"`
+	IndexName    string     `json:"Index Name"`
+	SubplanName  string     `json:"Subplan Name"`
+	Plans        []planNode `json:"Plans"`
+}
+
+// Returns the schema-qualified name of the scanned relation.
+func (n planNode) relation() string {
+	if n.Schema != "" {
+		return n.Schema + "." + n.RelationName
+	}
+	return n.RelationName
+}
+
+// Returns the root node of a plan in JSON format.
+func parsePlan(plan string) (planNode, error) {
+	var plans []struct {
+		Plan planNode `json:"Plan"`
+	}
+	if err := json.Unmarshal([]byte(plan), &plans); err != nil {
+		return planNode{}, err
+	}
+	if len(plans) == 0 {
+		return planNode{}, errors.New("empty plan")
+	}
+	return plans[0].Plan, nil
+}
+
+// Renders plan as an indented tree with a line per node.
+func planShape(root planNode) string {
+	var b strings.Builder
+	var walk func(n planNode, depth int)
+	walk = func(n planNode, depth int) {
+		b.WriteString(strings.Repeat("  ", depth))
+		if n.SubplanName != "" {
+			fmt.Fprintf(&b, "[%v] ", n.SubplanName)
+		}
+		if n.Strategy != "" && n.Strategy != "Plain" {
+			b.WriteString(n.Strategy + " ")
+		}
+		b.WriteString(n.NodeType)
+		if n.JoinType != "" {
+			fmt.Fprintf(&b, " (%v)", n.JoinType)
+		}
+		if n.IndexName != "" {
+			b.WriteString(" using " + n.IndexName)
+		}
+		switch {
+		case n.RelationName != "":
+			b.WriteString(" on " + n.relation())
+		case n.FunctionName != "":
+			b.WriteString(" on " + n.FunctionName)
+		case n.CTEName != "":
+			b.WriteString(" on " + n.CTEName)
+		}
+		b.WriteString("\n")
+		for _, child := range n.Plans {
+			walk(child, depth+1)
+		}
+	}
+	walk(root, 0)
+	return b.String()
+}
@@ -0,0 +1,50 @@
+package go_test_pg
+
+import (
+	"testing"
+)
+
+func TestPlanShape(t *testing.T) {
+	root, err := parsePlan(`[{"Plan": {"Node Type": "Aggregate",
+		"Strategy": "Hashed", "Total Cost": 12.5, "Plans": [
+		{"Node Type": "Hash Join", "Join Type": "Inner", "Plans": [
+			{"Node Type": "Seq Scan", "Relation Name": "users",
+				"Schema": "public", "Plan Rows": 10},
+			{"Node Type": "Hash", "Plans": [
+				{"Node Type": "Index Scan", "Index Name": "orders_pkey",
+					"Relation Name": "orders", "Schema": "public"}
+			]}
+		]},
+		{"Node Type": "Function Scan", "Function Name": "generate_series",
+			"Subplan Name": "SubPlan 1"}
+	]}}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `Hashed Aggregate
+  Hash Join (Inner)
+    Seq Scan on public.users
+    Hash
+      Index Scan using orders_pkey on public.orders
+  [SubPlan 1] Function Scan on generate_series
+`
+	if got := planShape(root); got != want {
+		t.Fatalf("unexpected plan shape:\n%v", lineDiff(splitLines(want),
+			splitLines(got)))
+	}
+
+	if _, err = parsePlan(`[]`); err == nil {
+		t.Fatal("want error for empty plan")
+	}
+}
+
+func TestAssertPlanGolden(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+	}
+	pool := dbPool.WithEmpty(t)
+	AssertPlanGolden(t, pool, "table1_by_id",
+		`SELECT name FROM table1 WHERE id = $1`, 1)
+}
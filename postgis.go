@@ -0,0 +1,53 @@
+package go_test_pg
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Deletes spatial reference systems other than srids from spatial_ref_sys
+// table and compacts it.
+func trimSpatialRefSys(ctx context.Context, conn *pgx.Conn,
+	srids []int) error {
+
+	_, err := conn.Exec(ctx,
+		`DELETE FROM spatial_ref_sys WHERE srid <> ALL($1)`, srids)
+	if err != nil {
+		return fmt.Errorf("can't trim spatial_ref_sys: %w", err)
+	}
+	_, err = conn.Exec(ctx, `VACUUM FULL spatial_ref_sys`)
+	if err != nil {
+		return fmt.Errorf("can't vacuum spatial_ref_sys: %w", err)
+	}
+	return nil
+}
+
+// InsertGeometries inserts rows with geometries into column of table. Each
+// geometry is WKT, e.g. "POINT(30 10)", or GeoJSON, e.g.
+// `{"type":"Point","coordinates":[30,10]}`, in spatial reference system
+// srid. Other columns get default values.
+func InsertGeometries(t testing.TB, q Querier, table, column string,
+	srid int, geoms ...string) {
+
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	_, err := q.Exec(ctx, `INSERT INTO `+quoteQualified(table)+` (`+
+		quote(column)+`)
+SELECT CASE
+	WHEN left(ltrim(g), 1) = '{'
+		THEN ST_SetSRID(ST_GeomFromGeoJSON(g), $2)
+	ELSE ST_GeomFromText(g, $2)
+END
+FROM unnest($1::text[]) WITH ORDINALITY AS u(g, n)
+ORDER BY n`, geoms, srid)
+	if err != nil {
+		t.Fatalf("can't insert geometries into %v: %v%v", table, err,
+			querierHint(q))
+	}
+}
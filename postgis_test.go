@@ -0,0 +1,63 @@
+package go_test_pg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestPgpool_templateChecksum_PostGISSRIDs(t *testing.T) {
+	p := Pgpool{SchemaFile: "./testdata/schema_postgis.sql", PostGIS: true}
+	all, err := p.templateChecksum([]string{"3.4.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.PostGISSRIDs = []int{4326}
+	trimmed, err := p.templateChecksum([]string{"3.4.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if all == trimmed {
+		t.Fatal("PostGISSRIDs does not change template checksum")
+	}
+}
+
+func TestInsertGeometries(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:     "go_test_pg",
+		SchemaFile:   "./testdata/schema_postgis.sql",
+		PostGIS:      true,
+		PostGISSRIDs: []int{4326},
+	}
+	if _, err := dbPool.extensionVersions([]string{"postgis"}); err != nil {
+		t.Skip(err)
+	}
+
+	pool := dbPool.WithEmpty(t)
+	InsertGeometries(t, pool, "places", "geom", 4326,
+		"POINT(30 10)", `{"type":"Point","coordinates":[40,20]}`)
+
+	rows, err := pool.Query(context.Background(),
+		`SELECT ST_AsText(geom) FROM places ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != "POINT(30 10)" || got[1] != "POINT(40 20)" {
+		t.Fatalf("unexpected geometries: %v", got)
+	}
+
+	var srids int
+	err = pool.QueryRow(context.Background(),
+		`SELECT count(*) FROM spatial_ref_sys`).Scan(&srids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if srids != 1 {
+		t.Fatalf("want 1 row in spatial_ref_sys, got %v", srids)
+	}
+}
@@ -0,0 +1,33 @@
+package go_test_pg
+
+import (
+	"fmt"
+	"sync"
+)
+
+// PrepareTemplates creates template databases of all pools concurrently.
+// Call it from TestMain to avoid creating templates one by one on first
+// use. Pools with Skip set are ignored. Returns the first error, the same
+// error fails all tests using the pool.
+func PrepareTemplates(pools ...*Pgpool) error {
+	errs := make([]error, len(pools))
+	var wg sync.WaitGroup
+	for i, p := range pools {
+		if p.Skip {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, p *Pgpool) {
+			defer wg.Done()
+			_, errs[i] = p.prepareTmpl()
+		}(i, p)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("%v: %w", pools[i].SchemaFile, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package go_test_pg
+
+import "testing"
+
+func TestPrepareTemplates(t *testing.T) {
+	if err := PrepareTemplates(&Pgpool{Skip: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	pools := []*Pgpool{
+		{BaseName: "go_test_pg", SchemaFile: "./testdata/schema1.sql"},
+		{BaseName: "go_test_pg", SchemaFile: "./testdata/schema2.sql"},
+	}
+	if err := PrepareTemplates(pools...); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range pools {
+		if p.tmpl == "" {
+			t.Fatalf("template for %v is not created", p.SchemaFile)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package go_test_pg
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Role attributes of the user of administrative connections.
+type adminPrivileges struct {
+	user       string
+	super      bool
+	createDB   bool
+	createRole bool
+}
+
+// Checks that the user of administrative connections may create databases
+// and, if Roles or RolesFile are set, roles, so missing privileges are
+// reported before template creation fails. Databases of custom Backend
+// are not created by the user, so CREATEDB is not required then.
+func (p *Pgpool) checkPrivileges() error {
+	var priv adminPrivileges
+	err := p.withNewConnection(
+		"",
+		func(ctx context.Context, conn *pgx.Conn) error {
+			return conn.QueryRow(ctx, `
+SELECT rolname, rolsuper, rolcreatedb, rolcreaterole
+FROM pg_roles
+WHERE rolname = current_user`).Scan(&priv.user, &priv.super,
+				&priv.createDB, &priv.createRole)
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("can't check privileges: %w", err)
+	}
+
+	needRoles := p.RolesFile != "" || len(p.Roles) != 0
+	return privilegesError(priv, p.Backend == nil, needRoles)
+}
+
+// Returns ErrInsufficientPrivileges with statements granting missing
+// privileges, or nil if priv is enough.
+func privilegesError(priv adminPrivileges, needCreateDB,
+	needCreateRole bool) error {
+
+	if priv.super {
+		return nil
+	}
+	var missing string
+	switch {
+	case needCreateDB && !priv.createDB && needCreateRole &&
+		!priv.createRole:
+		missing = "CREATEDB CREATEROLE"
+	case needCreateDB && !priv.createDB:
+		missing = "CREATEDB"
+	case needCreateRole && !priv.createRole:
+		missing = "CREATEROLE"
+	default:
+		return nil
+	}
+	return fmt.Errorf("%w: user %v has no %v, grant it as superuser "+
+		"with: ALTER ROLE %v %v", ErrInsufficientPrivileges, priv.user,
+		missing, quote(priv.user), missing)
+}
+
+// Checks that AppUser exists and may log in. It is checked after Roles
+// are created, so AppUser may be one of them.
+func (p *Pgpool) checkAppUser() error {
+	appUser := p.opts().appUser
+	if appUser == "" {
+		return nil
+	}
+	return p.withNewConnection(
+		"",
+		func(ctx context.Context, conn *pgx.Conn) error {
+			var canLogin bool
+			err := conn.QueryRow(ctx, `
+SELECT EXISTS(SELECT 1 FROM pg_roles WHERE rolname = $1 AND rolcanlogin)`,
+				appUser).Scan(&canLogin)
+			if err != nil {
+				return err
+			}
+			if !canLogin {
+				return fmt.Errorf("%w: app user %v does not exist or "+
+					"has no LOGIN", ErrInsufficientPrivileges, appUser)
+			}
+			return nil
+		},
+	)
+}
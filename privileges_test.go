@@ -0,0 +1,106 @@
+package go_test_pg
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPrivilegesError(t *testing.T) {
+	testCases := []struct {
+		name       string
+		priv       adminPrivileges
+		createDB   bool
+		createRole bool
+		want       string
+	}{
+		{
+			name:       "superuser",
+			priv:       adminPrivileges{user: "postgres", super: true},
+			createDB:   true,
+			createRole: true,
+		},
+		{
+			name:     "createdb",
+			priv:     adminPrivileges{user: "ci", createDB: true},
+			createDB: true,
+		},
+		{
+			name:     "no createdb",
+			priv:     adminPrivileges{user: "ci"},
+			createDB: true,
+			want: "insufficient privileges: user ci has no CREATEDB, " +
+				`grant it as superuser with: ALTER ROLE "ci" CREATEDB`,
+		},
+		{
+			name: "custom backend",
+			priv: adminPrivileges{user: "ci"},
+		},
+		{
+			name:       "no createrole",
+			priv:       adminPrivileges{user: "ci", createDB: true},
+			createDB:   true,
+			createRole: true,
+			want: "insufficient privileges: user ci has no CREATEROLE, " +
+				`grant it as superuser with: ALTER ROLE "ci" CREATEROLE`,
+		},
+		{
+			name:       "no createdb and createrole",
+			priv:       adminPrivileges{user: "ci"},
+			createDB:   true,
+			createRole: true,
+			want: "insufficient privileges: user ci has no " +
+				"CREATEDB CREATEROLE, grant it as superuser with: " +
+				`ALTER ROLE "ci" CREATEDB CREATEROLE`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := privilegesError(tc.priv, tc.createDB, tc.createRole)
+			if tc.want == "" {
+				if err != nil {
+					t.Fatalf("want no error, got %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInsufficientPrivileges) {
+				t.Fatalf("want ErrInsufficientPrivileges, got %v", err)
+			}
+			if err.Error() != tc.want {
+				t.Fatalf("want %q, got %q", tc.want, err.Error())
+			}
+		})
+	}
+}
+
+func TestPgpool_testConnConfig_AppUser(t *testing.T) {
+	p := &Pgpool{AppUser: "app", AppPassword: "secret"}
+	cfg, err := p.testConnConfig("db_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.User != "app" || cfg.Password != "secret" {
+		t.Fatalf("want app credentials, got %v/%v", cfg.User, cfg.Password)
+	}
+	if !p.isAppUser(cfg) {
+		t.Fatal("want app user config")
+	}
+
+	adminCfg, err := p.connConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.isAppUser(adminCfg) {
+		t.Fatal("admin config is app user config")
+	}
+}
+
+func TestPgpool_AppUser_Missing(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName: "go_test_pg",
+		AppUser:  "go_test_pg_no_such_user",
+	}
+	_, err := dbPool.prepareTmpl()
+	if !errors.Is(err, ErrInsufficientPrivileges) {
+		t.Fatalf("want ErrInsufficientPrivileges, got %v", err)
+	}
+}
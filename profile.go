@@ -0,0 +1,297 @@
+package go_test_pg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ProfileEnv is the environment variable with the name of the profile used
+// when Pgpool.Profile is empty, e.g. GO_TEST_PG_PROFILE=ci.
+const ProfileEnv = "GO_TEST_PG_PROFILE"
+
+// ProfileFileEnv is the environment variable with the path to the profile
+// file. If it is not set, go-test-pg.toml is looked up in the current
+// directory and its parents up to the root of the Go module.
+const ProfileFileEnv = "GO_TEST_PG_CONFIG"
+
+// Name of the profile file.
+const profileFileName = "go-test-pg.toml"
+
+// Returns the path to the profile file, or empty string if there is none.
+func profileFile() (string, error) {
+	if fileName := os.Getenv(ProfileFileEnv); fileName != "" {
+		return fileName, nil
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		fileName := filepath.Join(dir, profileFileName)
+		if _, err = os.Stat(fileName); err == nil {
+			return fileName, nil
+		}
+		if _, err = os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return "", nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// Options of Pgpool that may be set by a profile. Fields of Pgpool are
+// not modified, as they may be read concurrently.
+type poolOptions struct {
+	dsn                 string
+	baseName            string
+	hosts               []string
+	port                uint16
+	socketDir           string
+	service             string
+	tablespace          string
+	appUser             string
+	appPassword         string
+	minConns            int32
+	maxConns            int32
+	maxAdminConcurrency int
+	keepTemplates       int
+	unloggedTables      bool
+	disableAutovacuum   bool
+	asyncDrop           bool
+	pgBouncer           bool
+}
+
+// Returns options of p with the profile applied. The profile is resolved
+// on first call, before the first connection is made. If it can't be
+// resolved, options of p are returned and connString reports the error.
+func (p *Pgpool) opts() *poolOptions {
+	p.optsOnce.Do(func() {
+		p.options = &poolOptions{
+			baseName:            p.BaseName,
+			hosts:               p.Hosts,
+			port:                p.Port,
+			socketDir:           p.SocketDir,
+			service:             p.Service,
+			tablespace:          p.Tablespace,
+			appUser:             p.AppUser,
+			appPassword:         p.AppPassword,
+			minConns:            p.MinConns,
+			maxConns:            p.MaxConns,
+			maxAdminConcurrency: p.MaxAdminConcurrency,
+			keepTemplates:       p.KeepTemplates,
+			unloggedTables:      p.UnloggedTables,
+			disableAutovacuum:   p.DisableAutovacuum,
+			asyncDrop:           p.AsyncDrop,
+			pgBouncer:           p.PgBouncer,
+		}
+		o := *p.options
+		if err := o.applyProfile(p.Profile); err != nil {
+			p.optsErr = err
+			return
+		}
+		p.options = &o
+	})
+	return p.options
+}
+
+// Fills options left empty with the profile named by name, ProfileEnv or
+// default key of the profile file.
+func (o *poolOptions) applyProfile(name string) error {
+	if name == "" {
+		name = os.Getenv(ProfileEnv)
+	}
+
+	fileName, err := profileFile()
+	if err != nil {
+		return fmt.Errorf("can't find profile file: %w", err)
+	}
+	if fileName == "" {
+		if name != "" {
+			return fmt.Errorf("profile %v is set, but %v is not found",
+				name, profileFileName)
+		}
+		return nil
+	}
+
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return fmt.Errorf("can't read profile file: %w", err)
+	}
+	tables, err := parseTOML(string(data))
+	if err != nil {
+		return fmt.Errorf("can't parse %v: %w", fileName, err)
+	}
+	if err = o.setProfile(tables, name); err != nil {
+		return fmt.Errorf("%v: %w", fileName, err)
+	}
+	return nil
+}
+
+// Fills options left empty with profile name of the parsed profile file.
+// If name is empty, the profile in default key is used, if any.
+func (o *poolOptions) setProfile(tables map[string]map[string]any,
+	name string) error {
+
+	for key, v := range tables[""] {
+		if key != "default" {
+			return fmt.Errorf("unknown key %v", key)
+		}
+		if name != "" {
+			continue
+		}
+		var ok bool
+		if name, ok = v.(string); !ok {
+			return errors.New("default must be a string")
+		}
+	}
+	if name == "" {
+		return nil
+	}
+
+	profile, ok := tables["profiles."+name]
+	if !ok {
+		return fmt.Errorf("profile %v is not defined", name)
+	}
+	keys := make([]string, 0, len(profile))
+	for key := range profile {
+		keys = append(keys, key)
+	}
+	// Report errors in a stable order.
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := o.setProfileKey(key, profile[key]); err != nil {
+			return fmt.Errorf("profile %v: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Sets option named by key of the profile to v if the option is empty.
+func (o *poolOptions) setProfileKey(key string, v any) error {
+	str := func(field *string) error {
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%v must be a string", key)
+		}
+		if *field == "" {
+			*field = s
+		}
+		return nil
+	}
+	flag := func(field *bool) error {
+		b, ok := v.(bool)
+		if !ok {
+			return fmt.Errorf("%v must be a boolean", key)
+		}
+		*field = *field || b
+		return nil
+	}
+	integer := func(max int64) (int64, error) {
+		n, ok := v.(int64)
+		if !ok || n < 0 || n > max {
+			return 0, fmt.Errorf("%v must be an integer from 0 to %v", key,
+				max)
+		}
+		return n, nil
+	}
+
+	switch key {
+	case "dsn":
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%v must be a string", key)
+		}
+		if strings.Contains(s, "://") {
+			return fmt.Errorf("%v must be in keyword/value format, e.g. "+
+				"\"user=postgres password=secret\"", key)
+		}
+		o.dsn = s
+	case "base_name":
+		return str(&o.baseName)
+	case "socket_dir":
+		return str(&o.socketDir)
+	case "service":
+		return str(&o.service)
+	case "tablespace":
+		return str(&o.tablespace)
+	case "app_user":
+		return str(&o.appUser)
+	case "app_password":
+		return str(&o.appPassword)
+	case "hosts":
+		values, ok := v.([]any)
+		if !ok {
+			return fmt.Errorf("%v must be an array of strings", key)
+		}
+		hosts := make([]string, 0, len(values))
+		for _, h := range values {
+			s, ok := h.(string)
+			if !ok {
+				return fmt.Errorf("%v must be an array of strings", key)
+			}
+			hosts = append(hosts, s)
+		}
+		if len(o.hosts) == 0 {
+			o.hosts = hosts
+		}
+	case "port":
+		n, err := integer(1<<16 - 1)
+		if err != nil {
+			return err
+		}
+		if o.port == 0 {
+			o.port = uint16(n)
+		}
+	case "min_conns":
+		n, err := integer(1<<31 - 1)
+		if err != nil {
+			return err
+		}
+		if o.minConns == 0 {
+			o.minConns = int32(n)
+		}
+	case "max_conns":
+		n, err := integer(1<<31 - 1)
+		if err != nil {
+			return err
+		}
+		if o.maxConns == 0 {
+			o.maxConns = int32(n)
+		}
+	case "max_admin_concurrency":
+		n, err := integer(1<<31 - 1)
+		if err != nil {
+			return err
+		}
+		if o.maxAdminConcurrency == 0 {
+			o.maxAdminConcurrency = int(n)
+		}
+	case "keep_templates":
+		n, err := integer(1<<31 - 1)
+		if err != nil {
+			return err
+		}
+		if o.keepTemplates == 0 {
+			o.keepTemplates = int(n)
+		}
+	case "unlogged_tables":
+		return flag(&o.unloggedTables)
+	case "disable_autovacuum":
+		return flag(&o.disableAutovacuum)
+	case "async_drop":
+		return flag(&o.asyncDrop)
+	case "pgbouncer":
+		return flag(&o.pgBouncer)
+	default:
+		return fmt.Errorf("unknown key %v", key)
+	}
+	return nil
+}
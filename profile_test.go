@@ -0,0 +1,94 @@
+package go_test_pg
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSetProfile(t *testing.T) {
+	tables, err := parseTOML(`
+default = "local"
+
+[profiles.local]
+socket_dir = "/tmp"
+
+[profiles.ci]
+dsn = "user=postgres sslmode=disable"
+base_name = "ci"
+hosts = ["db:5433"]
+min_conns = 2
+max_conns = 8
+async_drop = true
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o := &poolOptions{}
+	if err = o.setProfile(tables, ""); err != nil {
+		t.Fatal(err)
+	}
+	if o.socketDir != "/tmp" {
+		t.Fatalf("default profile is not applied: %+v", o)
+	}
+
+	o = &poolOptions{baseName: "app"}
+	if err = o.setProfile(tables, "ci"); err != nil {
+		t.Fatal(err)
+	}
+	if o.baseName != "app" || o.dsn != "user=postgres sslmode=disable" ||
+		!reflect.DeepEqual(o.hosts, []string{"db:5433"}) ||
+		o.minConns != 2 || o.maxConns != 8 || !o.asyncDrop || o.socketDir != "" {
+
+		t.Fatalf("unexpected options: %+v", o)
+	}
+	hosts, err := o.hostsConnString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "host='db' port='5433'"
+	if hosts != want {
+		t.Fatalf("want %v, got %v", want, hosts)
+	}
+
+	for _, profile := range []string{
+		`[profiles.x]` + "\n" + `unknown = 1`,
+		`[profiles.x]` + "\n" + `port = 70000`,
+		`[profiles.x]` + "\n" + `dsn = "postgres://localhost"`,
+		`[profiles.y]`,
+	} {
+		tables, err = parseTOML(profile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = (&poolOptions{}).setProfile(tables, "x"); err == nil {
+			t.Errorf("want error for %q", profile)
+		}
+	}
+}
+
+func TestApplyProfile(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "profiles.toml")
+	err := os.WriteFile(fileName,
+		[]byte("[profiles.ci]\nbase_name = \"ci\"\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(ProfileFileEnv, fileName)
+	t.Setenv(ProfileEnv, "ci")
+
+	p := &Pgpool{}
+	if p.opts().baseName != "ci" || p.optsErr != nil {
+		t.Fatalf("profile is not applied: %+v, %v", p.opts(), p.optsErr)
+	}
+	if p.BaseName != "" {
+		t.Fatalf("pool is modified: %v", p.BaseName)
+	}
+
+	p = &Pgpool{Profile: "local"}
+	if _, err = p.connString(); err == nil {
+		t.Fatal("want error for undefined profile")
+	}
+}
@@ -0,0 +1,115 @@
+package go_test_pg
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Interval of progress reports of schema files being applied.
+const schemaProgressInterval = 30 * time.Second
+
+// SchemaProgress is a progress report of a schema file being applied to
+// the template database, passed to Pgpool.OnSchemaProgress.
+type SchemaProgress struct {
+	// File is the name of the schema file.
+	File string
+	// Statements is the number of statements applied so far.
+	Statements int64
+	// Bytes is the size of SQL applied so far.
+	Bytes int64
+	// Elapsed is the time since the file started to be applied.
+	Elapsed time.Duration
+	// Done is set in the last report, sent when the file is applied or
+	// failed.
+	Done bool
+}
+
+// Tracks progress of a schema file and reports it periodically.
+type schemaProgress struct {
+	file       string
+	start      time.Time
+	statements atomic.Int64
+	bytes      atomic.Int64
+	report     func(SchemaProgress)
+	// Set if report is OnSchemaProgress rather than logging.
+	custom bool
+	// Set after the first periodic report.
+	reported bool
+
+	stopOnce sync.Once
+	done     chan struct{}
+	stopped  chan struct{}
+}
+
+// Starts tracking progress of schema file fileName. Progress is passed to
+// OnSchemaProgress or, if it is nil, logged every schemaProgressInterval.
+// Call stop when the file is applied.
+func (p *Pgpool) trackSchemaProgress(fileName string) *schemaProgress {
+	sp := &schemaProgress{
+		file:    fileName,
+		start:   time.Now(),
+		report:  p.OnSchemaProgress,
+		custom:  p.OnSchemaProgress != nil,
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	if !sp.custom {
+		sp.report = logSchemaProgress
+	}
+
+	go func() {
+		defer close(sp.stopped)
+		ticker := time.NewTicker(schemaProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				sp.reported = true
+				sp.report(sp.progress(false))
+			case <-sp.done:
+				return
+			}
+		}
+	}()
+	return sp
+}
+
+// Records that statements in total and bytes of SQL in total are applied.
+func (sp *schemaProgress) set(statements int, bytes int64) {
+	sp.statements.Store(int64(statements))
+	sp.bytes.Store(bytes)
+}
+
+// Stops periodic reports and sends the last one. The last report is not
+// logged if the file was applied before the first periodic report, so
+// quick schema loads are silent.
+func (sp *schemaProgress) stop() {
+	sp.stopOnce.Do(func() {
+		close(sp.done)
+		<-sp.stopped
+		if sp.custom || sp.reported {
+			sp.report(sp.progress(true))
+		}
+	})
+}
+
+func (sp *schemaProgress) progress(done bool) SchemaProgress {
+	return SchemaProgress{
+		File:       sp.file,
+		Statements: sp.statements.Load(),
+		Bytes:      sp.bytes.Load(),
+		Elapsed:    time.Since(sp.start),
+		Done:       done,
+	}
+}
+
+func logSchemaProgress(sp SchemaProgress) {
+	state := "applying"
+	if sp.Done {
+		state = "applied"
+	}
+	log.Printf("go-test-pg: %v %v: %v statements, %v bytes in %v", state,
+		sp.File, sp.Statements, sp.Bytes, sp.Elapsed.Round(time.Second))
+}
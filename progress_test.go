@@ -0,0 +1,27 @@
+package go_test_pg
+
+import (
+	"testing"
+)
+
+func TestPgpool_trackSchemaProgress(t *testing.T) {
+	var reports []SchemaProgress
+	p := &Pgpool{OnSchemaProgress: func(sp SchemaProgress) {
+		reports = append(reports, sp)
+	}}
+
+	progress := p.trackSchemaProgress("schema.sql")
+	progress.set(3, 42)
+	progress.stop()
+	progress.stop()
+
+	if len(reports) != 1 {
+		t.Fatalf("want 1 report, got %v", reports)
+	}
+	got := reports[0]
+	if got.File != "schema.sql" || got.Statements != 3 || got.Bytes != 42 ||
+		!got.Done {
+
+		t.Fatalf("unexpected report: %+v", got)
+	}
+}
@@ -0,0 +1,217 @@
+package go_test_pg
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Proxy is an in-process TCP proxy for injecting network failures between
+// a client and a server.
+type Proxy struct {
+	listener net.Listener
+	network  string
+	address  string
+
+	m         sync.Mutex
+	latency   time.Duration
+	blackhole bool
+	conns     map[net.Conn]struct{}
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// NewProxy starts a proxy listening on a random port of 127.0.0.1 and
+// forwarding connections to address on network ("tcp" or "unix"). The proxy
+// is closed on test cleanup.
+func NewProxy(t testing.TB, network, address string) *Proxy {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("can't start proxy: %v", err)
+	}
+
+	px := &Proxy{
+		listener: l,
+		network:  network,
+		address:  address,
+		conns:    make(map[net.Conn]struct{}),
+	}
+
+	px.wg.Add(1)
+	go px.acceptLoop()
+
+	t.Cleanup(px.Close)
+	return px
+}
+
+// Addr returns the address the proxy listens on.
+func (px *Proxy) Addr() *net.TCPAddr {
+	return px.listener.Addr().(*net.TCPAddr)
+}
+
+// SetLatency delays every chunk of data passing the proxy in both
+// directions by d.
+func (px *Proxy) SetLatency(d time.Duration) {
+	px.m.Lock()
+	px.latency = d
+	px.m.Unlock()
+}
+
+// SetBlackhole turns on or off the black hole mode. In the black hole mode
+// connections are accepted and kept open, but all data passing the proxy
+// is silently discarded, so clients hang until their timeouts.
+func (px *Proxy) SetBlackhole(on bool) {
+	px.m.Lock()
+	px.blackhole = on
+	px.m.Unlock()
+}
+
+// DropConnections closes all active connections passing the proxy. New
+// connections are accepted as usual.
+func (px *Proxy) DropConnections() {
+	px.m.Lock()
+	defer px.m.Unlock()
+	for c := range px.conns {
+		_ = c.Close()
+		delete(px.conns, c)
+	}
+}
+
+// Close stops the proxy and closes all connections.
+func (px *Proxy) Close() {
+	px.m.Lock()
+	px.closed = true
+	px.m.Unlock()
+
+	_ = px.listener.Close()
+	px.DropConnections()
+	px.wg.Wait()
+}
+
+func (px *Proxy) acceptLoop() {
+	defer px.wg.Done()
+	for {
+		client, err := px.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		server, err := net.Dial(px.network, px.address)
+		if err != nil {
+			_ = client.Close()
+			continue
+		}
+
+		px.m.Lock()
+		if px.closed {
+			px.m.Unlock()
+			_ = client.Close()
+			_ = server.Close()
+			return
+		}
+		px.conns[client] = struct{}{}
+		px.conns[server] = struct{}{}
+		px.wg.Add(2)
+		px.m.Unlock()
+
+		go px.pipe(client, server)
+		go px.pipe(server, client)
+	}
+}
+
+// Copies data from src to dst applying latency and black hole settings.
+// Closes both connections when done.
+func (px *Proxy) pipe(src, dst net.Conn) {
+	defer px.wg.Done()
+	defer func() {
+		px.m.Lock()
+		delete(px.conns, src)
+		delete(px.conns, dst)
+		px.m.Unlock()
+		_ = src.Close()
+		_ = dst.Close()
+	}()
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			px.m.Lock()
+			latency := px.latency
+			blackhole := px.blackhole
+			px.m.Unlock()
+
+			if !blackhole {
+				if latency > 0 {
+					time.Sleep(latency)
+				}
+				if _, werr := dst.Write(buf[:n]); werr != nil {
+					return
+				}
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// WithProxy creates empty database like WithEmpty and returns a pool that
+// connects to it through a fault-injecting proxy.
+func (p *Pgpool) WithProxy(t testing.TB) (*pgxpool.Pool, *Proxy) {
+	t.Helper()
+	return NewProxiedPool(t, p.WithEmpty(t))
+}
+
+// NewProxiedPool starts a proxy in front of the server of pool and returns
+// a new pool to the same database connecting through the proxy. The new
+// pool and the proxy are closed on test cleanup.
+func NewProxiedPool(t testing.TB,
+	pool *pgxpool.Pool) (*pgxpool.Pool, *Proxy) {
+
+	t.Helper()
+
+	cfg := pool.Config()
+	network, address := "tcp", net.JoinHostPort(cfg.ConnConfig.Host,
+		strconv.Itoa(int(cfg.ConnConfig.Port)))
+	if strings.HasPrefix(cfg.ConnConfig.Host, "/") {
+		network = "unix"
+		address = filepath.Join(cfg.ConnConfig.Host,
+			".s.PGSQL."+strconv.Itoa(int(cfg.ConnConfig.Port)))
+	}
+	px := NewProxy(t, network, address)
+
+	cfg.ConnConfig.Host = px.Addr().IP.String()
+	cfg.ConnConfig.Port = uint16(px.Addr().Port)
+	cfg.ConnConfig.Fallbacks = nil
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	proxied, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		t.Fatalf("can't create proxied pool: %v", err)
+	}
+
+	t.Cleanup(func() {
+		acquiredConns := proxied.Stat().AcquiredConns()
+		if acquiredConns > 0 {
+			t.Errorf("unreleased connections exists in proxied pool: %v",
+				acquiredConns)
+			return
+		}
+		px.Close()
+		proxied.Close()
+	})
+
+	return proxied, px
+}
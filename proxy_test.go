@@ -0,0 +1,92 @@
+package go_test_pg
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func startEchoServer(t *testing.T) net.Addr {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				_, _ = io.Copy(c, c)
+			}()
+		}
+	}()
+	return l.Addr()
+}
+
+func echo(t *testing.T, c net.Conn, msg string) error {
+	t.Helper()
+	if err := c.SetDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Write([]byte(msg)); err != nil {
+		return err
+	}
+	buf := make([]byte, len(msg))
+	if _, err := io.ReadFull(c, buf); err != nil {
+		return err
+	}
+	if string(buf) != msg {
+		t.Fatalf("want %q, got %q", msg, buf)
+	}
+	return nil
+}
+
+func TestProxy(t *testing.T) {
+	px := NewProxy(t, "tcp", startEchoServer(t).String())
+
+	c, err := net.Dial("tcp", px.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err = echo(t, c, "hello"); err != nil {
+		t.Fatal(err)
+	}
+
+	px.SetLatency(100 * time.Millisecond)
+	start := time.Now()
+	if err = echo(t, c, "slow"); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 200*time.Millisecond {
+		t.Fatalf("latency is not applied: %v", d)
+	}
+	px.SetLatency(0)
+
+	px.SetBlackhole(true)
+	err = echo(t, c, "lost")
+	if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+		t.Fatalf("want timeout error, got %v", err)
+	}
+	px.SetBlackhole(false)
+
+	px.DropConnections()
+	if err = echo(t, c, "dropped"); err == nil {
+		t.Fatal("want error on dropped connection")
+	}
+
+	c2, err := net.Dial("tcp", px.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c2.Close()
+	if err = echo(t, c2, "again"); err != nil {
+		t.Fatal(err)
+	}
+}
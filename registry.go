@@ -0,0 +1,93 @@
+package go_test_pg
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Templates created by this process, keyed by connection string and
+// template name.
+var (
+	templatesM sync.Mutex
+	templates  = make(map[string]*templateEntry)
+)
+
+type templateEntry struct {
+	once sync.Once
+	err  error
+}
+
+// Calls create once per process for key. All calls for the same key
+// return the result of the first one.
+func createTemplateOnce(key string, create func() error) error {
+	templatesM.Lock()
+	e, ok := templates[key]
+	if !ok {
+		e = &templateEntry{}
+		templates[key] = e
+	}
+	templatesM.Unlock()
+
+	e.once.Do(func() { e.err = create() })
+	return e.err
+}
+
+// Forgets the result of createTemplateOnce for key, so the next call
+// creates the template again.
+func forgetTemplate(key string) {
+	templatesM.Lock()
+	defer templatesM.Unlock()
+
+	delete(templates, key)
+}
+
+// Returns key of template tmplDbName on server of connString in the
+// registry of created templates.
+func templateKey(connString, tmplDbName string) string {
+	return connString + "\x00" + tmplDbName
+}
+
+// ResetTemplate drops the template database and databases kept for reuse,
+// and clears the stored template creation error, so the next test
+// creates the template again from the current schema file. It is useful
+// when the schema file is edited while tests are running, e.g. in
+// migration development. Other Pgpool values sharing the template must be
+// reset too. It must not be called while tests use the pool.
+func (p *Pgpool) ResetTemplate() error {
+	p.m.Lock()
+	tmpl := p.tmpl
+	p.tmpl = ""
+	p.err = nil
+	p.m.Unlock()
+
+	for _, dbName := range p.takeFreeDBs() {
+		if err := p.dropTestDB(dbName); err != nil {
+			return fmt.Errorf("can't drop database %v: %w", dbName, err)
+		}
+	}
+
+	if tmpl == "" || !p.hasSchema() || p.Yugabyte {
+		return nil
+	}
+
+	connString, err := p.connString()
+	if err != nil {
+		return err
+	}
+	forgetTemplate(templateKey(connString, tmpl))
+
+	return p.withNewConnection(
+		"",
+		func(ctx context.Context, conn *pgx.Conn) error {
+			_, err := conn.Exec(ctx, `DROP DATABASE IF EXISTS `+quote(tmpl))
+			if err != nil {
+				return fmt.Errorf("can't drop template database %v: %w",
+					tmpl, err)
+			}
+			return nil
+		},
+	)
+}
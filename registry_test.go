@@ -0,0 +1,82 @@
+package go_test_pg
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestCreateTemplateOnce(t *testing.T) {
+	var calls int
+	var m sync.Mutex
+	create := func() error {
+		m.Lock()
+		defer m.Unlock()
+		calls++
+		return errors.New("boom")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := createTemplateOnce("TestCreateTemplateOnce", create)
+			if err == nil || err.Error() != "boom" {
+				t.Errorf("want error of the first call, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Fatalf("want 1 call, got %v", calls)
+	}
+
+	err := createTemplateOnce("TestCreateTemplateOnce/other",
+		func() error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestForgetTemplate(t *testing.T) {
+	var calls int
+	create := func() error {
+		calls++
+		return nil
+	}
+	for i := 0; i < 2; i++ {
+		if err := createTemplateOnce("TestForgetTemplate", create); err != nil {
+			t.Fatal(err)
+		}
+		forgetTemplate("TestForgetTemplate")
+	}
+	if calls != 2 {
+		t.Fatalf("want 2 calls, got %v", calls)
+	}
+}
+
+func TestPgpool_ResetTemplate(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg_reset",
+		SchemaFile: "./testdata/schema1.sql",
+	}
+	tmpl, err := dbPool.prepareTmpl()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = dbPool.ResetTemplate(); err != nil {
+		t.Fatal(err)
+	}
+	if dbPool.tmpl != "" {
+		t.Fatal("template is not cleared")
+	}
+
+	pool := dbPool.WithEmpty(t)
+	AssertTableExists(t, pool, "table1")
+	if dbPool.tmpl != tmpl {
+		t.Fatalf("want template %v, got %v", tmpl, dbPool.tmpl)
+	}
+}
@@ -0,0 +1,180 @@
+package go_test_pg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Name of the publication created in primary databases of PrimaryReplica.
+const replicaPublication = "go_test_pg_replica"
+
+// PrimaryReplica is a pair of test databases with tables of Primary
+// replicated to Replica with logical replication.
+type PrimaryReplica struct {
+	// Primary is the pool of the database changes are made in.
+	Primary *pgxpool.Pool
+	// Replica is the pool of the database changes are replicated to.
+	Replica *pgxpool.Pool
+
+	slotName string
+}
+
+// WithPrimaryReplica creates a database with primary and a database with
+// replica, like Pgpool.WithEmpty does, and subscribes the replica to all
+// tables of the primary. Both usually have the same schema file; primary
+// and replica may be the same Pgpool, or replica may point to another
+// server that can connect to the primary one. Existing rows are not
+// copied, as both databases start with the same content of their
+// templates. Sequences are not replicated.
+//
+// The server of the primary must run with wal_level=logical, and the user
+// must be allowed to create subscriptions. Replication is stopped and
+// databases are dropped when the test finishes.
+func WithPrimaryReplica(t testing.TB, primary,
+	replica *Pgpool) *PrimaryReplica {
+
+	t.Helper()
+
+	r := &PrimaryReplica{
+		Primary:  primary.WithEmpty(t),
+		Replica:  replica.WithEmpty(t),
+		slotName: randomSlotName(),
+	}
+	primaryDB := r.Primary.Config().ConnConfig.Database
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	connString, err := primary.dsn(ctx, primaryDB)
+	if err != nil {
+		t.Fatalf("can't build connection string of %v: %v", primaryDB, err)
+	}
+
+	_, err = r.Primary.Exec(ctx,
+		`CREATE PUBLICATION `+quote(replicaPublication)+` FOR ALL TABLES`)
+	if err != nil {
+		t.Fatalf("can't create publication: %v%v", err, querierHint(r.Primary))
+	}
+	// The slot is created separately, as CREATE SUBSCRIPTION creating the
+	// slot hangs when both databases are on the same server.
+	_, err = r.Primary.Exec(ctx,
+		`SELECT pg_create_logical_replication_slot($1, 'pgoutput')`,
+		r.slotName)
+	if err != nil {
+		t.Fatalf("can't create replication slot %v "+
+			"(is wal_level set to logical?): %v", r.slotName, err)
+	}
+	t.Cleanup(func() { r.dropSlot(t) })
+
+	_, err = r.Replica.Exec(ctx, `CREATE SUBSCRIPTION `+quote(r.slotName)+
+		` CONNECTION `+quoteLiteral(connString)+
+		` PUBLICATION `+quote(replicaPublication)+
+		` WITH (create_slot = false, copy_data = false, slot_name = `+
+		quoteLiteral(r.slotName)+`)`)
+	if err != nil {
+		t.Fatalf("can't create subscription: %v%v", err,
+			querierHint(r.Replica))
+	}
+	t.Cleanup(func() { r.dropSubscription(t) })
+
+	return r
+}
+
+// WaitReplicated waits until changes committed in Primary so far are
+// applied to Replica.
+func (r *PrimaryReplica) WaitReplicated(t testing.TB) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var lsn string
+	err := r.Primary.QueryRow(ctx, `SELECT pg_current_wal_lsn()::text`).
+		Scan(&lsn)
+	if err != nil {
+		t.Fatalf("can't get WAL position: %v", err)
+	}
+
+	for {
+		var replicated bool
+		err = r.Primary.QueryRow(ctx, `
+SELECT coalesce(confirmed_flush_lsn >= $1::pg_lsn, false)
+FROM pg_replication_slots
+WHERE slot_name = $2`, lsn, r.slotName).Scan(&replicated)
+		if err != nil {
+			t.Fatalf("can't get position of replication slot %v: %v",
+				r.slotName, err)
+		}
+		if replicated {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			t.Fatalf("replica has not caught up with %v in %v", lsn,
+				defaultTimeout)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// Drops the subscription without dropping the slot, which is dropped
+// separately.
+func (r *PrimaryReplica) dropSubscription(t testing.TB) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	sub := quote(r.slotName)
+	_, err := r.Replica.Exec(ctx, `ALTER SUBSCRIPTION `+sub+` DISABLE`)
+	if err == nil {
+		_, err = r.Replica.Exec(ctx,
+			`ALTER SUBSCRIPTION `+sub+` SET (slot_name = NONE)`)
+	}
+	if err == nil {
+		_, err = r.Replica.Exec(ctx, `DROP SUBSCRIPTION `+sub)
+	}
+	if err != nil {
+		t.Errorf("can't drop subscription %v: %v", r.slotName, err)
+	}
+}
+
+// Drops the replication slot. The slot stays active for a while after the
+// subscription is disabled, so the drop is retried.
+func (r *PrimaryReplica) dropSlot(t testing.TB) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	for {
+		_, err := r.Primary.Exec(ctx, `
+SELECT pg_drop_replication_slot(slot_name)
+FROM pg_replication_slots
+WHERE slot_name = $1 AND NOT active`, r.slotName)
+		if err != nil {
+			t.Errorf("can't drop replication slot %v: %v", r.slotName, err)
+			return
+		}
+
+		var exists bool
+		err = r.Primary.QueryRow(ctx, `
+SELECT EXISTS(SELECT 1 FROM pg_replication_slots WHERE slot_name = $1)`,
+			r.slotName).Scan(&exists)
+		if err != nil {
+			t.Errorf("can't drop replication slot %v: %v", r.slotName, err)
+			return
+		}
+		if !exists {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			t.Errorf("can't drop replication slot %v: it is still active",
+				r.slotName)
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package go_test_pg
+
+import (
+	"context"
+	"testing"
+)
+
+func TestWithPrimaryReplica(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+	}
+	skipUnlessLogicalWAL(t, &dbPool)
+	r := WithPrimaryReplica(t, &dbPool, &dbPool)
+
+	_, err := r.Primary.Exec(context.Background(),
+		`INSERT INTO table1 (id, name) VALUES (1, 'replicated')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.WaitReplicated(t)
+	AssertExists(t, r.Replica, "table1", "id = $1 AND name = $2", 1,
+		"replicated")
+}
@@ -0,0 +1,139 @@
+package go_test_pg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// LogicalReplicationOptions configures NewLogicalReplication.
+type LogicalReplicationOptions struct {
+	// SlotName is the name of the replication slot. Slot names are unique
+	// across the cluster, so if empty, random name is generated.
+	SlotName string
+	// Plugin is the output plugin of the slot. Default is test_decoding.
+	// Changes can be read with LogicalReplication.Changes only from
+	// test_decoding slots, ChangesSince decodes pgoutput slots too.
+	Plugin string
+	// Publication is the name of publication to create. If empty,
+	// publication is not created.
+	Publication string
+	// PublicationTables are the tables of the publication. If empty,
+	// publication is created for all tables.
+	PublicationTables []string
+}
+
+// LogicalReplication is a logical replication slot (and an optional
+// publication) on the test database. Server must run with
+// wal_level=logical.
+type LogicalReplication struct {
+	// SlotName is the name of created replication slot.
+	SlotName string
+	// Publication is the name of created publication or empty.
+	Publication string
+
+	pool   *pgxpool.Pool
+	plugin string
+}
+
+// NewLogicalReplication creates a logical replication slot and an optional
+// publication on the database of pool. The slot is dropped on test cleanup
+// before the database is dropped.
+func NewLogicalReplication(t testing.TB, pool *pgxpool.Pool,
+	opts LogicalReplicationOptions) *LogicalReplication {
+
+	t.Helper()
+
+	r := &LogicalReplication{
+		SlotName:    opts.SlotName,
+		Publication: opts.Publication,
+		pool:        pool,
+		plugin:      opts.Plugin,
+	}
+	if r.SlotName == "" {
+		r.SlotName = randomSlotName()
+	}
+	if r.plugin == "" {
+		r.plugin = "test_decoding"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	if r.Publication != "" {
+		query := `CREATE PUBLICATION ` + quote(r.Publication)
+		if len(opts.PublicationTables) == 0 {
+			query += ` FOR ALL TABLES`
+		} else {
+			tables := make([]string, 0, len(opts.PublicationTables))
+			for _, tbl := range opts.PublicationTables {
+				tables = append(tables, quoteQualified(tbl))
+			}
+			query += ` FOR TABLE ` + strings.Join(tables, ", ")
+		}
+		if _, err := pool.Exec(ctx, query); err != nil {
+			t.Fatalf("can't create publication %v: %v", r.Publication, err)
+		}
+	}
+
+	_, err := pool.Exec(ctx,
+		`SELECT pg_create_logical_replication_slot($1, $2)`,
+		r.SlotName, r.plugin)
+	if err != nil {
+		t.Fatalf("can't create replication slot %v "+
+			"(is wal_level set to logical?): %v",
+			r.SlotName, err)
+	}
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(),
+			defaultTimeout)
+		defer cancel()
+		_, err := pool.Exec(ctx, `SELECT pg_drop_replication_slot($1)`,
+			r.SlotName)
+		if err != nil {
+			t.Errorf("can't drop replication slot %v: %v", r.SlotName, err)
+		}
+	})
+
+	return r
+}
+
+// Changes consumes changes accumulated in the slot since the previous call
+// and returns them as test_decoding text lines, including BEGIN and COMMIT
+// records.
+func (r *LogicalReplication) Changes(t testing.TB) []string {
+	t.Helper()
+
+	if r.plugin != "test_decoding" {
+		t.Fatalf("can't read changes from slot with %v plugin", r.plugin)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx,
+		`SELECT data FROM pg_logical_slot_get_changes($1, NULL, NULL)`,
+		r.SlotName)
+	if err != nil {
+		t.Fatalf("can't get changes from slot %v: %v", r.SlotName, err)
+	}
+	changes, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		t.Fatalf("can't get changes from slot %v: %v", r.SlotName, err)
+	}
+	return changes
+}
+
+func randomSlotName() string {
+	return fmt.Sprintf("go_test_pg_%016x", randomUint64())
+}
+
+// Quotes possibly schema-qualified name like "public.users".
+func quoteQualified(name string) string {
+	return pgx.Identifier(strings.Split(name, ".")).Sanitize()
+}
@@ -0,0 +1,61 @@
+package go_test_pg
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Skips the test if wal_level of the server is not logical, as logical
+// replication slots can't be created then.
+func skipUnlessLogicalWAL(t testing.TB, p *Pgpool) {
+	t.Helper()
+
+	var walLevel string
+	err := p.withNewConnection("",
+		func(ctx context.Context, conn *pgx.Conn) error {
+			return conn.QueryRow(ctx, `SHOW wal_level`).Scan(&walLevel)
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if walLevel != "logical" {
+		t.Skipf("wal_level is %v, logical is required", walLevel)
+	}
+}
+
+func TestLogicalReplication(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+	}
+	skipUnlessLogicalWAL(t, &dbPool)
+	pool := dbPool.WithEmpty(t)
+
+	r := NewLogicalReplication(t, pool, LogicalReplicationOptions{
+		Publication: "outbox",
+	})
+
+	_, err := pool.Exec(context.Background(),
+		`INSERT INTO table1 (id) VALUES (1)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := r.Changes(t)
+	var found bool
+	for _, c := range changes {
+		if strings.HasPrefix(c, "table public.table1: INSERT:") {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("insert not found in changes: %v", changes)
+	}
+
+	if changes = r.Changes(t); len(changes) != 0 {
+		t.Fatalf("changes must be consumed, got %v", changes)
+	}
+}
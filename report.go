@@ -0,0 +1,104 @@
+package go_test_pg
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ReportEnv is the environment variable with the path of the setup report.
+// If set, every lifecycle event (see EventKind) is appended to the file as
+// a JSON object on a separate line, with the owning test, the duration and
+// the size of created databases, so CI can track the cost of database
+// setup over time. Test binaries of different packages may append to the
+// same file.
+const ReportEnv = "GO_TEST_PG_REPORT"
+
+// Line of the setup report.
+type reportEntry struct {
+	Time       time.Time `json:"time"`
+	Binary     string    `json:"binary"`
+	Kind       string    `json:"kind"`
+	Database   string    `json:"database"`
+	Test       string    `json:"test,omitempty"`
+	DurationMS float64   `json:"duration_ms"`
+	SizeBytes  int64     `json:"size_bytes,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+var reportM sync.Mutex
+
+// Appends ev to the setup report if ReportEnv is set.
+func (p *Pgpool) report(ev Event) {
+	path := os.Getenv(ReportEnv)
+	if path == "" {
+		return
+	}
+
+	entry := reportEntry{
+		Time:       time.Now().UTC(),
+		Binary:     filepath.Base(os.Args[0]),
+		Kind:       ev.Kind.String(),
+		Database:   ev.Database,
+		Test:       ev.Test,
+		DurationMS: float64(ev.Duration) / float64(time.Millisecond),
+	}
+	if ev.Err != nil {
+		entry.Error = ev.Err.Error()
+	} else if ev.Kind == EventTemplateReady || ev.Kind == EventCreate {
+		entry.SizeBytes = p.databaseSize(ev.Database)
+	}
+
+	if err := appendReport(path, entry); err != nil {
+		log.Printf("go-test-pg: can't write setup report: %v", err)
+	}
+}
+
+// Returns size of database dbName or 0 if it can't be queried.
+func (p *Pgpool) databaseSize(dbName string) int64 {
+	if p.Backend != nil {
+		return 0
+	}
+	pool, err := p.getAdminPool()
+	if err != nil {
+		return 0
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout())
+	defer cancel()
+
+	var size int64
+	err = pool.QueryRow(ctx, `SELECT pg_database_size($1)`, dbName).
+		Scan(&size)
+	if err != nil {
+		return 0
+	}
+	return size
+}
+
+func appendReport(path string, entry reportEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	reportM.Lock()
+	defer reportM.Unlock()
+
+	// Single write of a line in append mode is not interleaved with writes
+	// of other test binaries.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(line); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,54 @@
+package go_test_pg
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPgpool_report(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.jsonl")
+	t.Setenv(ReportEnv, path)
+
+	p := Pgpool{}
+	p.emit(EventCreate, "db_1", "TestX", time.Now().Add(-time.Second),
+		errors.New("create failed"))
+	p.emit(EventDrop, "db_2", "TestY", time.Now(), nil)
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var entries []reportEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e reportEntry
+		if err = json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, e)
+	}
+	if err = scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("want 2 entries, got %+v", entries)
+	}
+	e := entries[0]
+	if e.Kind != "create" || e.Database != "db_1" || e.Test != "TestX" ||
+		e.DurationMS < 1000 || e.Error != "create failed" ||
+		e.Binary == "" {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+	e = entries[1]
+	if e.Kind != "drop" || e.Database != "db_2" || e.Error != "" {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+}
@@ -0,0 +1,83 @@
+package go_test_pg
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var shellSafeRe = regexp.MustCompile(`^[A-Za-z0-9_./:=@%+-]+$`)
+
+// Returns the test database of cfg and a psql command connecting to it,
+// to be appended to failure messages. Password is never included.
+func reproHint(cfg *pgx.ConnConfig) string {
+	return fmt.Sprintf("\ndatabase %v on %v, reproduce with:\n\t%v",
+		cfg.Database, serverAddr(cfg),
+		"psql "+strings.Join(psqlArgs(cfg), " "))
+}
+
+// Returns reproHint of the database q is connected to, or empty string if
+// the configuration of q is not available.
+func querierHint(q Querier) string {
+	var cfg *pgx.ConnConfig
+	switch q := q.(type) {
+	case *pgxpool.Pool:
+		cfg = q.Config().ConnConfig
+	case *pgxpool.Conn:
+		cfg = q.Conn().Config()
+	case *pgx.Conn:
+		cfg = q.Config()
+	case pgx.Tx:
+		cfg = q.Conn().Config()
+	default:
+		return ""
+	}
+	return reproHint(cfg)
+}
+
+// Returns reproHint of database dbName.
+func (p *Pgpool) dbHint(dbName string) string {
+	cfg, err := p.testConnConfig(dbName)
+	if err != nil {
+		return ""
+	}
+	return reproHint(cfg)
+}
+
+// Returns commands that create database of cfg and apply schema file to
+// it. Template databases are dropped on failure, so the database is
+// created again.
+func schemaHint(cfg *pgx.ConnConfig, fileName string) string {
+	return fmt.Sprintf("\ndatabase %v on %v, reproduce with:\n\t%v && %v",
+		cfg.Database, serverAddr(cfg),
+		"createdb "+strings.Join(psqlArgs(cfg), " "),
+		"psql "+strings.Join(psqlArgs(cfg), " ")+
+			" -v ON_ERROR_STOP=1 -f "+shellQuote(fileName))
+}
+
+func serverAddr(cfg *pgx.ConnConfig) string {
+	if strings.HasPrefix(cfg.Host, "/") {
+		return fmt.Sprintf("%v (port %v)", cfg.Host, cfg.Port)
+	}
+	return fmt.Sprintf("%v:%v", cfg.Host, cfg.Port)
+}
+
+func psqlArgs(cfg *pgx.ConnConfig) []string {
+	return []string{
+		"-h", shellQuote(cfg.Host),
+		"-p", strconv.Itoa(int(cfg.Port)),
+		"-U", shellQuote(cfg.User),
+		shellQuote(cfg.Database),
+	}
+}
+
+func shellQuote(s string) string {
+	if shellSafeRe.MatchString(s) {
+		return s
+	}
+	return `'` + strings.ReplaceAll(s, `'`, `'\''`) + `'`
+}
@@ -0,0 +1,43 @@
+package go_test_pg
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestReproHint(t *testing.T) {
+	cfg, err := pgx.ParseConfig(
+		"host=db.local port=5433 user=app password=secret dbname=go_test_pg_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "\ndatabase go_test_pg_1 on db.local:5433, reproduce with:\n" +
+		"\tpsql -h db.local -p 5433 -U app go_test_pg_1"
+	if got := reproHint(cfg); got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+
+	want = "\ndatabase go_test_pg_1 on db.local:5433, reproduce with:\n" +
+		"\tcreatedb -h db.local -p 5433 -U app go_test_pg_1 && " +
+		"psql -h db.local -p 5433 -U app go_test_pg_1 -v ON_ERROR_STOP=1 " +
+		"-f 'my schema.sql'"
+	if got := schemaHint(cfg, "my schema.sql"); got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	testCases := map[string]string{
+		"/var/run/postgresql": "/var/run/postgresql",
+		"it's":                `'it'\''s'`,
+		"a b":                 `'a b'`,
+		"":                    `''`,
+	}
+	for in, want := range testCases {
+		if got := shellQuote(in); got != want {
+			t.Errorf("shellQuote(%q): want %q, got %q", in, want, got)
+		}
+	}
+}
@@ -0,0 +1,389 @@
+package go_test_pg
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ResetStrategy defines how a database is prepared for the next test.
+type ResetStrategy int
+
+const (
+	// ResetClone clones a new database from the template for every test
+	// and drops it when the test finishes.
+	ResetClone ResetStrategy = iota
+	// ResetTruncate truncates all tables of the database when the test
+	// finishes and reuses the database for following tests. Sequences
+	// are restarted and database settings are restored. Tables of
+	// extensions are not truncated. Tests must not change the schema, and
+	// data loaded by the schema file is lost, so fixtures must be loaded
+	// by tests. Databases are dropped by Close.
+	ResetTruncate
+	// ResetAuto measures ResetClone and ResetTruncate with Calibrate on
+	// first use and picks the fastest one. ResetTruncate is not picked if
+	// tables of the template contain rows.
+	ResetAuto
+)
+
+func (s ResetStrategy) String() string {
+	switch s {
+	case ResetClone:
+		return "clone"
+	case ResetTruncate:
+		return "truncate"
+	case ResetAuto:
+		return "auto"
+	default:
+		return fmt.Sprintf("ResetStrategy(%d)", int(s))
+	}
+}
+
+// Number of iterations of calibration done by ResetAuto.
+const autoCalibrationRuns = 3
+
+// CalibrationReport is the mean time spent to give a test a clean
+// database with every strategy.
+type CalibrationReport struct {
+	// Clone is the time to clone a database from the template and drop
+	// it.
+	Clone time.Duration
+	// Truncate is the time to truncate all tables of a database.
+	Truncate time.Duration
+	// Rollback is the time to connect to a database, begin a transaction
+	// and roll it back, as WithTx does.
+	Rollback time.Duration
+	// Seeded is true if tables of the template database contain rows,
+	// e.g. loaded by the schema file, which ResetTruncate would lose.
+	Seeded bool
+}
+
+// Fastest returns the fastest of ResetClone and ResetTruncate strategies.
+// ResetTruncate is never returned for seeded templates. Rollback is not a
+// ResetStrategy, as tests using WithTx get a transaction instead of a
+// pool.
+func (r CalibrationReport) Fastest() ResetStrategy {
+	if !r.Seeded && r.Truncate < r.Clone {
+		return ResetTruncate
+	}
+	return ResetClone
+}
+
+func (r CalibrationReport) String() string {
+	return fmt.Sprintf("clone: %v, truncate: %v, rollback: %v", r.Clone,
+		r.Truncate, r.Rollback)
+}
+
+// Calibrate measures strategies of preparing a clean database on the
+// actual schema and server, n times each, and logs the mean times.
+func (p *Pgpool) Calibrate(t testing.TB, n int) CalibrationReport {
+	t.Helper()
+
+	r, err := p.calibrate(p.getTmpl(t), n)
+	if err != nil {
+		t.Fatalf("calibration failed: %v", err)
+	}
+	t.Logf("go-test-pg: %v, fastest strategy: %v", r, r.Fastest())
+	return r
+}
+
+func (p *Pgpool) calibrate(tmpl string, n int) (CalibrationReport, error) {
+	var r CalibrationReport
+	if n <= 0 {
+		n = 1
+	}
+
+	start := time.Now()
+	for i := 0; i < n; i++ {
+		dbName, err := p.newDB(tmpl)
+		if err != nil {
+			return r, err
+		}
+		if err = p.dropDB(dbName); err != nil {
+			return r, err
+		}
+	}
+	r.Clone = time.Since(start) / time.Duration(n)
+
+	// Every iteration truncates a fresh clone, so tables have the content
+	// of the template as after a test.
+	for i := 0; i < n; i++ {
+		dbName, err := p.newDB(tmpl)
+		if err != nil {
+			return r, err
+		}
+		if i == 0 {
+			r.Seeded, err = p.hasRows(dbName)
+		}
+		var elapsed time.Duration
+		if err == nil {
+			start = time.Now()
+			err = p.truncateDB(dbName)
+			elapsed = time.Since(start)
+		}
+		dropErr := p.dropDB(dbName)
+		if err == nil {
+			err = dropErr
+		}
+		if err != nil {
+			return r, err
+		}
+		r.Truncate += elapsed
+	}
+	r.Truncate /= time.Duration(n)
+
+	dbName, err := p.newDB(tmpl)
+	if err != nil {
+		return r, err
+	}
+	defer func() { _ = p.dropDB(dbName) }()
+
+	start = time.Now()
+	for i := 0; i < n; i++ {
+		err = p.withNewConnection(
+			dbName,
+			func(ctx context.Context, conn *pgx.Conn) error {
+				tx, err := conn.Begin(ctx)
+				if err != nil {
+					return err
+				}
+				return tx.Rollback(ctx)
+			},
+		)
+		if err != nil {
+			return r, err
+		}
+	}
+	r.Rollback = time.Since(start) / time.Duration(n)
+
+	return r, nil
+}
+
+// Returns ResetStrategy of the Pgpool. ResetAuto is resolved with
+// calibration on the first call.
+func (p *Pgpool) resetStrategy(tmpl string) ResetStrategy {
+	p.strategyOnce.Do(func() {
+		p.strategy = p.ResetStrategy
+		if p.strategy != ResetAuto {
+			return
+		}
+
+		r, err := p.calibrate(tmpl, autoCalibrationRuns)
+		if err != nil {
+			log.Printf("go-test-pg: calibration failed, using %v "+
+				"strategy: %v", ResetClone, err)
+			p.strategy = ResetClone
+			return
+		}
+		p.strategy = r.Fastest()
+		log.Printf("go-test-pg: %v, using %v strategy", r, p.strategy)
+	})
+	return p.strategy
+}
+
+// Returns user tables of the database truncated by ResetTruncate. Tables
+// of extensions, e.g. spatial_ref_sys of PostGIS, and of schemas installed
+// by the library are left out.
+func truncatedTables(ctx context.Context, conn *pgx.Conn) ([]string, error) {
+	rows, err := conn.Query(ctx, `
+SELECT format('%I.%I', n.nspname, c.relname)
+FROM pg_class c
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE c.relkind IN ('r', 'p')
+	AND NOT c.relispartition
+	AND n.nspname NOT IN ('pg_catalog', 'information_schema', 'testclock',
+		'ddlaudit')
+	AND n.nspname NOT LIKE 'pg\_%'
+	AND NOT EXISTS(
+		SELECT 1 FROM pg_depend d
+		WHERE d.classid = 'pg_class'::regclass
+			AND d.objid = c.oid
+			AND d.deptype = 'e')
+ORDER BY 1`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// Reports whether any table truncated by ResetTruncate in database dbName
+// contains rows.
+func (p *Pgpool) hasRows(dbName string) (bool, error) {
+	var seeded bool
+	err := p.withNewConnection(
+		dbName,
+		func(ctx context.Context, conn *pgx.Conn) error {
+			tables, err := truncatedTables(ctx, conn)
+			if err != nil {
+				return err
+			}
+			for _, table := range tables {
+				err = conn.QueryRow(ctx,
+					`SELECT EXISTS(SELECT 1 FROM `+table+`)`).Scan(&seeded)
+				if err != nil || seeded {
+					return err
+				}
+			}
+			return nil
+		},
+	)
+	return seeded, err
+}
+
+// Truncates all tables of database dbName, restarts sequences and restores
+// database settings. Frozen time of MockNow and the log of CaptureDDL are
+// cleared too, as they are empty in the template.
+func (p *Pgpool) truncateDB(dbName string) error {
+	err := p.withNewConnection(
+		dbName,
+		func(ctx context.Context, conn *pgx.Conn) error {
+			tables, err := truncatedTables(ctx, conn)
+			if err != nil {
+				return err
+			}
+			if p.MockNow {
+				tables = append(tables, "testclock.frozen")
+			}
+			if p.CaptureDDL {
+				tables = append(tables, "ddlaudit.log")
+			}
+			if len(tables) == 0 {
+				return nil
+			}
+			_, err = conn.Exec(ctx, `TRUNCATE TABLE `+
+				strings.Join(tables, ", ")+` RESTART IDENTITY CASCADE`)
+			return err
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	err = p.withNewConnection(
+		"",
+		func(ctx context.Context, conn *pgx.Conn) error {
+			_, err := conn.Exec(ctx,
+				`ALTER DATABASE `+quote(dbName)+` RESET ALL`)
+			return err
+		},
+	)
+	if err != nil {
+		return err
+	}
+	return p.applyDatabaseSettings(dbName)
+}
+
+// Takes a database from free list, p.freeDBs or p.freeTxDBs. Databases
+// truncated by ResetTruncate and databases left by WithTx are kept
+// apart, as their content differs.
+func (p *Pgpool) takeFreeDB(list *[]string) (string, bool) {
+	p.freeDBsM.Lock()
+	defer p.freeDBsM.Unlock()
+
+	if len(*list) == 0 {
+		return "", false
+	}
+	dbName := (*list)[len(*list)-1]
+	*list = (*list)[:len(*list)-1]
+	return dbName, true
+}
+
+// Takes databases of all free lists.
+func (p *Pgpool) takeFreeDBs() []string {
+	p.freeDBsM.Lock()
+	defer p.freeDBsM.Unlock()
+
+	dbs := append(p.freeDBs, p.freeTxDBs...)
+	p.freeDBs = nil
+	p.freeTxDBs = nil
+	return dbs
+}
+
+func (p *Pgpool) putFreeDB(list *[]string, dbName string) {
+	p.freeDBsM.Lock()
+	defer p.freeDBsM.Unlock()
+
+	*list = append(*list, dbName)
+}
+
+// WithTx returns a transaction in a clean database. The transaction is
+// rolled back when the test finishes, and the database is reused by
+// following WithTx calls, so it is cheaper than WithEmpty. The code under
+// test must not commit the transaction; if it does, the database is
+// dropped.
+func (p *Pgpool) WithTx(t testing.TB) pgx.Tx {
+	t.Helper()
+
+	tmpl := p.getTmpl(t)
+	dbName, ok := p.takeFreeDB(&p.freeTxDBs)
+	if ok {
+		p.dbTests.Store(dbName, t.Name())
+	} else {
+		var err error
+		dbName, err = p.newTestDB(t, tmpl)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	p.captureServerLog(t, dbName)
+
+	tx, err := p.beginTx(t, dbName)
+	if err != nil {
+		_ = p.dropDB(dbName)
+		t.Fatalf("can't begin transaction: %v", err)
+	}
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(),
+			p.timeout())
+		defer cancel()
+
+		err := tx.Rollback(ctx)
+		closeConn(tx.Conn())
+		if err == nil {
+			p.putFreeDB(&p.freeTxDBs, dbName)
+			return
+		}
+		if err = p.dropTestDB(dbName); err != nil {
+			t.Errorf("Can't drop DB %v: %v%v", dbName, err,
+				p.activityHint(dbName))
+		}
+	})
+	return tx
+}
+
+// Connects to database dbName and begins a transaction.
+func (p *Pgpool) beginTx(t testing.TB, dbName string) (pgx.Tx, error) {
+	cfg, err := p.testConnConfig(dbName)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Tracer = p.queryTracer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout())
+	defer cancel()
+
+	if err = p.beforeConnect(ctx, cfg); err != nil {
+		return nil, err
+	}
+	conn, err := pgx.ConnectConfig(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err = p.afterConnect(ctx, conn); err != nil {
+		closeConn(conn)
+		return nil, err
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		closeConn(conn)
+		return nil, err
+	}
+	return tx, nil
+}
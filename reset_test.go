@@ -0,0 +1,131 @@
+package go_test_pg
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCalibrationReport_Fastest(t *testing.T) {
+	r := CalibrationReport{Clone: 2 * time.Millisecond,
+		Truncate: time.Millisecond, Rollback: time.Microsecond}
+	if got := r.Fastest(); got != ResetTruncate {
+		t.Fatalf("want %v, got %v", ResetTruncate, got)
+	}
+	r.Truncate = 3 * time.Millisecond
+	if got := r.Fastest(); got != ResetClone {
+		t.Fatalf("want %v, got %v", ResetClone, got)
+	}
+	r.Truncate = time.Millisecond
+	r.Seeded = true
+	if got := r.Fastest(); got != ResetClone {
+		t.Fatalf("want %v for seeded template, got %v", ResetClone, got)
+	}
+}
+
+func TestPgpool_ResetTruncate(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:      "go_test_pg",
+		SchemaFile:    "./testdata/schema1.sql",
+		ResetStrategy: ResetTruncate,
+	}
+	defer dbPool.Close()
+
+	var dbNames []string
+	for i := 0; i < 2; i++ {
+		t.Run("test", func(t *testing.T) {
+			pool := dbPool.WithEmpty(t)
+			dbNames = append(dbNames, pool.Config().ConnConfig.Database)
+			AssertRowCount(t, pool, "table1", 0)
+
+			var id int
+			err := pool.QueryRow(context.Background(),
+				`INSERT INTO table1 (name) VALUES ('a') RETURNING id`).
+				Scan(&id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id != 1 {
+				t.Fatalf("want sequence restarted, got id %v", id)
+			}
+		})
+	}
+	if t.Failed() {
+		t.FailNow()
+	}
+	if dbNames[0] != dbNames[1] {
+		t.Fatalf("database is not reused: %v", dbNames)
+	}
+}
+
+func TestPgpool_WithTx(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+	}
+	defer dbPool.Close()
+
+	var dbNames []string
+	for i := 0; i < 2; i++ {
+		t.Run("test", func(t *testing.T) {
+			tx := dbPool.WithTx(t)
+			dbNames = append(dbNames, tx.Conn().Config().Database)
+			AssertRowCount(t, tx, "table1", 0)
+			_, err := tx.Exec(context.Background(),
+				`INSERT INTO table1 (name) VALUES ('a')`)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+	if t.Failed() {
+		t.FailNow()
+	}
+	if dbNames[0] != dbNames[1] {
+		t.Fatalf("database is not reused: %v", dbNames)
+	}
+}
+
+func TestPgpool_Calibrate(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+	}
+	r := dbPool.Calibrate(t, 2)
+	if r.Clone <= 0 || r.Truncate <= 0 || r.Rollback <= 0 || r.Seeded {
+		t.Fatalf("unexpected report: %v", r)
+	}
+
+	var seededPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema_seeded.sql",
+	}
+	if r = seededPool.Calibrate(t, 1); !r.Seeded {
+		t.Fatalf("template with rows is not seeded: %v", r)
+	}
+}
+
+func TestPgpool_WithTxAndTruncate(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:      "go_test_pg",
+		SchemaFile:    "./testdata/schema1.sql",
+		ResetStrategy: ResetTruncate,
+	}
+	defer dbPool.Close()
+
+	var txDB, truncatedDB string
+	t.Run("tx", func(t *testing.T) {
+		tx := dbPool.WithTx(t)
+		txDB = tx.Conn().Config().Database
+	})
+	t.Run("truncate", func(t *testing.T) {
+		pool := dbPool.WithEmpty(t)
+		truncatedDB = pool.Config().ConnConfig.Database
+	})
+	if t.Failed() {
+		t.FailNow()
+	}
+	if txDB == truncatedDB {
+		t.Fatalf("database %v of WithTx is reused by ResetTruncate", txDB)
+	}
+}
@@ -0,0 +1,195 @@
+package go_test_pg
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Templates used within this period are not dropped by KeepTemplates, as
+// other test processes may still clone them.
+const templateGracePeriod = time.Hour
+
+// Returns ID of the advisory lock taken while template database with
+// checksum is created, checked or dropped.
+func templateLockID(checksum []byte) int64 {
+	return int64(binary.BigEndian.Uint64(checksum[:8]))
+}
+
+type templateInfo struct {
+	name   string
+	usedAt time.Time
+}
+
+// Returns template databases named baseName_<checksum> with time they were
+// last used, from their manifests.
+func listTemplates(ctx context.Context, conn *pgx.Conn,
+	baseName string) ([]templateInfo, error) {
+
+	rows, err := conn.Query(ctx, `
+SELECT datname, shobj_description(oid, 'pg_database')
+FROM pg_database
+WHERE datname ~ $1`, `^`+regexp.QuoteMeta(baseName)+`_[0-9a-f]{32}$`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []templateInfo
+	for rows.Next() {
+		var name string
+		var comment *string
+		if err = rows.Scan(&name, &comment); err != nil {
+			return nil, err
+		}
+		templates = append(templates,
+			templateInfo{name: name, usedAt: manifestUsedAt(comment)})
+	}
+	return templates, rows.Err()
+}
+
+// Returns time the template database with manifest in comment was last
+// used, or zero time if the manifest is missing.
+func manifestUsedAt(comment *string) time.Time {
+	var m templateManifest
+	if comment == nil || json.Unmarshal([]byte(*comment), &m) != nil {
+		return time.Time{}
+	}
+	if m.UsedAt != nil && m.UsedAt.After(m.CreatedAt) {
+		return *m.UsedAt
+	}
+	return m.CreatedAt
+}
+
+// Returns names of templates to drop so keep most recently used templates
+// remain, current one among them. Templates used after notBefore are never
+// dropped.
+func templatesToDrop(templates []templateInfo, current string, keep int,
+	notBefore time.Time) []string {
+
+	sort.Slice(templates, func(i, j int) bool {
+		if templates[i].name == current || templates[j].name == current {
+			return templates[i].name == current
+		}
+		if !templates[i].usedAt.Equal(templates[j].usedAt) {
+			return templates[i].usedAt.After(templates[j].usedAt)
+		}
+		return templates[i].name < templates[j].name
+	})
+
+	var names []string
+	for i := keep; i < len(templates); i++ {
+		if !templates[i].usedAt.After(notBefore) {
+			names = append(names, templates[i].name)
+		}
+	}
+	return names
+}
+
+// Drops template databases of BaseName except the KeepTemplates most
+// recently used ones and ones used within templateGracePeriod. Templates
+// that are being created, checked or cloned by other processes can't be
+// dropped and are left until the next run.
+func (p *Pgpool) pruneTemplates(current string) {
+	err := p.withNewConnection(
+		"",
+		func(ctx context.Context, conn *pgx.Conn) error {
+			templates, err := listTemplates(ctx, conn, p.baseName())
+			if err != nil {
+				return err
+			}
+			notBefore := time.Now().Add(-templateGracePeriod)
+			for _, name := range templatesToDrop(templates, current,
+				p.opts().keepTemplates, notBefore) {
+
+				err = dropOldTemplate(ctx, conn, name, notBefore)
+				if err != nil {
+					log.Printf("go-test-pg: can't drop old template "+
+						"database %v: %v", name, err)
+				}
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		log.Printf("go-test-pg: can't prune template databases: %v", err)
+	}
+}
+
+// Drops template database name unless another process holds its advisory
+// lock or it was used after notBefore.
+func dropOldTemplate(ctx context.Context, conn *pgx.Conn, name string,
+	notBefore time.Time) error {
+
+	checksum, err := hex.DecodeString(name[strings.LastIndexByte(name,
+		'_')+1:])
+	if err != nil {
+		return err
+	}
+	lockID := templateLockID(checksum)
+
+	var locked bool
+	err = conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, lockID).
+		Scan(&locked)
+	if err != nil || !locked {
+		return err
+	}
+	defer func() {
+		_, _ = conn.Exec(ctx, `SELECT pg_advisory_unlock($1)`, lockID)
+	}()
+
+	// The template may have been touched since it was listed.
+	var comment *string
+	err = conn.QueryRow(ctx, `
+SELECT shobj_description(oid, 'pg_database')
+FROM pg_database
+WHERE datname = $1`, name).Scan(&comment)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil || manifestUsedAt(comment).After(notBefore) {
+		return err
+	}
+
+	_, err = conn.Exec(ctx, `DROP DATABASE `+quote(name))
+	return err
+}
+
+// Updates the time template database dbName was last used in its
+// manifest.
+func touchManifest(ctx context.Context, conn *pgx.Conn,
+	dbName string) error {
+
+	var comment *string
+	err := conn.QueryRow(ctx, `
+SELECT shobj_description(oid, 'pg_database')
+FROM pg_database
+WHERE datname = $1`, dbName).Scan(&comment)
+	if err != nil || comment == nil {
+		return err
+	}
+
+	var m templateManifest
+	if err = json.Unmarshal([]byte(*comment), &m); err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	m.UsedAt = &now
+	buf, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.Exec(ctx, `COMMENT ON DATABASE `+quote(dbName)+` IS `+
+		quoteLiteral(string(buf)))
+	return err
+}
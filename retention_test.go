@@ -0,0 +1,53 @@
+package go_test_pg
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTemplatesToDrop(t *testing.T) {
+	now := time.Now()
+	templates := []templateInfo{
+		{name: "a", usedAt: now.Add(-3 * time.Hour)},
+		{name: "b", usedAt: now.Add(-time.Hour)},
+		{name: "current", usedAt: now.Add(-48 * time.Hour)},
+		{name: "c"},
+		{name: "d", usedAt: now.Add(-2 * time.Hour)},
+	}
+
+	got := templatesToDrop(templates, "current", 3, now)
+	want := []string{"a", "c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+
+	if got = templatesToDrop(templates, "current", 5, now); got != nil {
+		t.Fatalf("want nothing to drop, got %v", got)
+	}
+
+	// Templates used within the grace period are kept.
+	got = templatesToDrop(templates, "current", 1, now.Add(-150*time.Minute))
+	want = []string{"a", "c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+}
+
+func TestManifestUsedAt(t *testing.T) {
+	created := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	used := created.Add(time.Hour)
+
+	comment := `{"created_at":"2020-01-01T00:00:00Z"}`
+	if got := manifestUsedAt(&comment); !got.Equal(created) {
+		t.Fatalf("want %v, got %v", created, got)
+	}
+	comment = `{"created_at":"2020-01-01T00:00:00Z",` +
+		`"used_at":"2020-01-01T01:00:00Z"}`
+	if got := manifestUsedAt(&comment); !got.Equal(used) {
+		t.Fatalf("want %v, got %v", used, got)
+	}
+	if got := manifestUsedAt(nil); !got.IsZero() {
+		t.Fatalf("want zero time, got %v", got)
+	}
+}
@@ -0,0 +1,72 @@
+package go_test_pg
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AsRole calls fn with a new pool to the database of pool. Every connection
+// of the new pool runs SET ROLE role and sets session settings, so
+// row-level security policies can be tested as the application role rather
+// than the owner of tables, e.g.
+//
+//	AsRole(t, pool, "tenant_role", map[string]string{"app.tenant_id": "42"},
+//		func(pool *pgxpool.Pool) { ... })
+//
+// The new pool is closed when fn returns. The role of pool connections
+// must be a member of role.
+func AsRole(t testing.TB, pool *pgxpool.Pool, role string,
+	settings map[string]string, fn func(pool *pgxpool.Pool)) {
+
+	t.Helper()
+
+	rolePool := newRolePool(t, pool.Config(), role, settings)
+	defer func() {
+		acquiredConns := rolePool.Stat().AcquiredConns()
+		if acquiredConns > 0 {
+			t.Errorf("unreleased connections exists in pool of role %v: %v",
+				role, acquiredConns)
+			return
+		}
+		rolePool.Close()
+	}()
+
+	fn(rolePool)
+}
+
+// Returns a new pool from cfg with connections switched to role and
+// settings applied. If role is empty, the role is not switched.
+func newRolePool(t testing.TB, cfg *pgxpool.Config, role string,
+	settings map[string]string) *pgxpool.Pool {
+
+	t.Helper()
+
+	afterConnect := cfg.AfterConnect
+	cfg.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+		if afterConnect != nil {
+			if err := afterConnect(ctx, conn); err != nil {
+				return err
+			}
+		}
+		if role != "" {
+			_, err := conn.Exec(ctx, `SET ROLE `+quote(role))
+			if err != nil {
+				return fmt.Errorf("can't set role %v: %w", role, err)
+			}
+		}
+		return setSessionSettings(ctx, conn, settings)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	rolePool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		t.Fatalf("can't create pool: %v", err)
+	}
+	return rolePool
+}
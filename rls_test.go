@@ -0,0 +1,38 @@
+package go_test_pg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestAsRole(t *testing.T) {
+	var dbPool = Pgpool{
+		Roles: []RoleSpec{
+			{Name: "go_test_pg_tenant", Options: []string{"NOLOGIN"}},
+		},
+	}
+	pool := dbPool.WithEmpty(t)
+
+	var called bool
+	AsRole(t, pool, "go_test_pg_tenant",
+		map[string]string{"app.tenant_id": "42"},
+		func(pool *pgxpool.Pool) {
+			called = true
+			var role, tenantID string
+			err := pool.QueryRow(context.Background(),
+				`SELECT current_user, current_setting('app.tenant_id')`).
+				Scan(&role, &tenantID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if role != "go_test_pg_tenant" || tenantID != "42" {
+				t.Fatalf("unexpected role and tenant: %v, %v",
+					role, tenantID)
+			}
+		})
+	if !called {
+		t.Fatal("fn was not called")
+	}
+}
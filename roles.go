@@ -0,0 +1,197 @@
+package go_test_pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ID of the advisory lock taken on master database while cluster-level
+// roles are being created.
+const rolesLockID int64 = 0x676f5f746573745f // "go_test_"
+
+// RoleSpec describes a cluster-level role that should exist before template
+// database is created.
+type RoleSpec struct {
+	// Name of the role.
+	Name string
+	// Options are appended to CREATE ROLE statement as is,
+	// e.g. LOGIN, NOINHERIT, BYPASSRLS.
+	Options []string
+	// MemberOf is a list of roles this role is granted membership in.
+	MemberOf []string
+}
+
+// Creates roles from RolesFile and Roles fields. Roles are cluster-wide
+// objects, so they are created on master database and are never dropped.
+// Existing roles are left untouched except for missing grants.
+func (p *Pgpool) bootstrapRoles() error {
+	if p.RolesFile == "" && len(p.Roles) == 0 {
+		return nil
+	}
+
+	var rolesSql []byte
+	if p.RolesFile != "" {
+		var err error
+		rolesSql, err = os.ReadFile(p.RolesFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	return p.withNewConnection(
+		"",
+		func(ctx context.Context, conn *pgx.Conn) error {
+			// Prevent parallel test processes from racing on CREATE ROLE.
+			_, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`,
+				rolesLockID)
+			if err != nil {
+				return err
+			}
+
+			for _, r := range p.Roles {
+				if err = createRole(ctx, conn, r); err != nil {
+					return err
+				}
+			}
+
+			if len(rolesSql) != 0 {
+				_, err = conn.Exec(ctx, string(rolesSql))
+				if err != nil {
+					return fmt.Errorf("can't apply roles file %v: %w",
+						p.RolesFile, err)
+				}
+			}
+
+			_, err = conn.Exec(ctx, `SELECT pg_advisory_unlock($1)`,
+				rolesLockID)
+			return err
+		},
+	)
+}
+
+func createRole(ctx context.Context, conn *pgx.Conn, r RoleSpec) error {
+	if r.Name == "" {
+		return errors.New("role name is empty")
+	}
+
+	var exists bool
+	err := conn.QueryRow(ctx,
+		`SELECT EXISTS(SELECT 1 FROM pg_roles WHERE rolname = $1)`,
+		r.Name).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		query := `CREATE ROLE ` + quote(r.Name)
+		if len(r.Options) != 0 {
+			query += ` ` + strings.Join(r.Options, " ")
+		}
+		_, err = conn.Exec(ctx, query)
+		if err != nil {
+			return fmt.Errorf("can't create role %v: %w", r.Name, err)
+		}
+	}
+
+	for _, m := range r.MemberOf {
+		// GRANT of existing membership only raises a notice.
+		_, err = conn.Exec(ctx, `GRANT `+quote(m)+` TO `+quote(r.Name))
+		if err != nil {
+			return fmt.Errorf("can't grant %v to %v: %w", m, r.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// CreateTempRole creates a role for test t with options appended to
+// CREATE ROLE, e.g. CreateTempRole(t, "readonly", "NOLOGIN"), and returns
+// its name. Roles are cluster-wide, so a random suffix is appended to name
+// to avoid clashes between parallel tests and test processes. When the
+// test finishes, objects owned by the role in any database are reassigned
+// to the user creating databases, its privileges are revoked and the role
+// is dropped.
+func (p *Pgpool) CreateTempRole(t testing.TB, name string,
+	opts ...string) string {
+
+	t.Helper()
+
+	role := fmt.Sprintf("%v_%x", name, uint32(randomUint64()))
+	err := p.withNewConnection(
+		"",
+		func(ctx context.Context, conn *pgx.Conn) error {
+			return createRole(ctx, conn, RoleSpec{Name: role, Options: opts})
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		if err := p.dropRole(role); err != nil {
+			t.Errorf("can't drop role %v: %v", role, err)
+		}
+	})
+	return role
+}
+
+// Reassigns objects owned by role to the current user and revokes its
+// privileges in all databases the role has dependencies in, then drops the
+// role.
+func (p *Pgpool) dropRole(role string) error {
+	var dbNames []string
+	err := p.withNewConnection(
+		"",
+		func(ctx context.Context, conn *pgx.Conn) error {
+			rows, err := conn.Query(ctx, `
+SELECT DISTINCT d.datname
+FROM pg_shdepend s
+	JOIN pg_database d ON d.oid = s.dbid
+WHERE s.refclassid = 'pg_authid'::regclass
+	AND s.refobjid = (SELECT oid FROM pg_roles WHERE rolname = $1)
+	AND d.datname <> current_database()
+	AND d.datallowconn
+ORDER BY 1`, role)
+			if err != nil {
+				return err
+			}
+			dbNames, err = pgx.CollectRows(rows, pgx.RowTo[string])
+			return err
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	dropOwned := `REASSIGN OWNED BY ` + quote(role) + ` TO CURRENT_USER; ` +
+		`DROP OWNED BY ` + quote(role)
+	for _, dbName := range dbNames {
+		err = p.withNewConnection(
+			dbName,
+			func(ctx context.Context, conn *pgx.Conn) error {
+				_, err := conn.Exec(ctx, dropOwned)
+				return err
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("can't drop objects owned in %v: %w", dbName,
+				err)
+		}
+	}
+
+	// DROP OWNED on master database also revokes privileges on shared
+	// objects, like databases.
+	return p.withNewConnection(
+		"",
+		func(ctx context.Context, conn *pgx.Conn) error {
+			_, err := conn.Exec(ctx, dropOwned+`; DROP ROLE `+quote(role))
+			return err
+		},
+	)
+}
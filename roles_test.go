@@ -0,0 +1,65 @@
+package go_test_pg
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPgpool_Roles(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+		Roles: []RoleSpec{
+			{Name: "go_test_pg_reader", Options: []string{"NOLOGIN"}},
+			{
+				Name:     "go_test_pg_app",
+				Options:  []string{"NOLOGIN"},
+				MemberOf: []string{"go_test_pg_reader"},
+			},
+		},
+	}
+	db := dbPool.WithEmpty(t)
+
+	var isMember bool
+	err := db.QueryRow(context.Background(),
+		`SELECT pg_has_role('go_test_pg_app', 'go_test_pg_reader', 'MEMBER')`,
+	).Scan(&isMember)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isMember {
+		t.Fatal("go_test_pg_app is not a member of go_test_pg_reader")
+	}
+}
+
+func TestPgpool_CreateTempRole(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+	}
+	db := dbPool.WithEmpty(t)
+
+	var role string
+	t.Run("role", func(t *testing.T) {
+		role = dbPool.CreateTempRole(t, "go_test_pg_tmp", "NOLOGIN")
+		_, err := db.Exec(context.Background(),
+			`CREATE TABLE owned (id int); `+
+				`ALTER TABLE owned OWNER TO `+quote(role)+`; `+
+				`GRANT SELECT ON table1 TO `+quote(role))
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	var exists bool
+	err := db.QueryRow(context.Background(),
+		`SELECT EXISTS(SELECT 1 FROM pg_roles WHERE rolname = $1)`,
+		role).Scan(&exists)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists {
+		t.Fatalf("role %v is not dropped", role)
+	}
+	AssertTableExists(t, db, "owned")
+}
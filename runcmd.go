@@ -0,0 +1,152 @@
+package go_test_pg
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RunCmd runs cmd with environment pointing to the test database of pool
+// (see SetDBEnv), logs its output with t.Log line by line and returns the
+// result of cmd.Wait. cmd.Stdout and cmd.Stderr, if set, receive the output
+// too. If the test fails before the command exits, the process is killed
+// before the database is dropped.
+func (p *Pgpool) RunCmd(t testing.TB, pool *pgxpool.Pool,
+	cmd *exec.Cmd) error {
+
+	t.Helper()
+	r := p.startCmd(t, pool, cmd)
+	<-r.done
+	return r.err
+}
+
+// StartCmd starts cmd like RunCmd, but does not wait for it to exit, e.g.
+// to run a server under test. The process is killed on test cleanup before
+// the database of pool is dropped, so pool must be created before StartCmd
+// is called. Do not call cmd.Wait.
+func (p *Pgpool) StartCmd(t testing.TB, pool *pgxpool.Pool, cmd *exec.Cmd) {
+	t.Helper()
+	p.startCmd(t, pool, cmd)
+}
+
+func (p *Pgpool) startCmd(t testing.TB, pool *pgxpool.Pool,
+	cmd *exec.Cmd) *runningCmd {
+
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	cfg, settings, err := p.clientConfig(ctx,
+		pool.Config().ConnConfig.Database)
+	if err != nil {
+		t.Fatalf("can't build database environment: %v", err)
+	}
+	env, err := dbEnv(cfg, settings, os.Getenv("PGSSLMODE"))
+	if err != nil {
+		t.Fatalf("can't build database environment: %v", err)
+	}
+
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	// Later values take precedence.
+	for name, value := range env {
+		cmd.Env = append(cmd.Env, name+"="+value)
+	}
+	return startCmd(t, cmd)
+}
+
+// Process started by startCmd. err is set when done is closed.
+type runningCmd struct {
+	done chan struct{}
+	err  error
+}
+
+// Starts cmd with output logged to t and registers cleanup killing the
+// process if it is still running.
+func startCmd(t testing.TB, cmd *exec.Cmd) *runningCmd {
+	t.Helper()
+
+	prefix := filepath.Base(cmd.Path) + ": "
+	stdout := &logWriter{t: t, prefix: prefix}
+	stderr := &logWriter{t: t, prefix: prefix}
+	cmd.Stdout = teeWriter(cmd.Stdout, stdout)
+	cmd.Stderr = teeWriter(cmd.Stderr, stderr)
+
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("can't start %v: %v", cmd.Path, err)
+	}
+
+	r := &runningCmd{done: make(chan struct{})}
+	go func() {
+		r.err = cmd.Wait()
+		stdout.flush()
+		stderr.flush()
+		close(r.done)
+	}()
+
+	// Cleanup functions run in reverse order, so the process is killed
+	// before the database created earlier in the test is dropped.
+	t.Cleanup(func() {
+		select {
+		case <-r.done:
+			return
+		default:
+		}
+		t.Logf("killing %v", cmd.Path)
+		_ = cmd.Process.Kill()
+		<-r.done
+	})
+	return r
+}
+
+func teeWriter(w io.Writer, log io.Writer) io.Writer {
+	if w == nil {
+		return log
+	}
+	return io.MultiWriter(w, log)
+}
+
+// Writes complete lines to t.Log.
+type logWriter struct {
+	t      testing.TB
+	prefix string
+
+	m   sync.Mutex
+	buf []byte
+}
+
+func (w *logWriter) Write(b []byte) (int, error) {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	w.buf = append(w.buf, b...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.t.Log(w.prefix + string(w.buf[:i]))
+		w.buf = w.buf[i+1:]
+	}
+	return len(b), nil
+}
+
+// Logs the last line if it is not terminated with new line.
+func (w *logWriter) flush() {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	if len(w.buf) != 0 {
+		w.t.Log(w.prefix + string(w.buf))
+		w.buf = nil
+	}
+}
@@ -0,0 +1,62 @@
+package go_test_pg
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+type recordingTB struct {
+	testing.TB
+	logs []string
+}
+
+func (r *recordingTB) Log(args ...any) {
+	r.logs = append(r.logs, args[0].(string))
+}
+
+func TestLogWriter(t *testing.T) {
+	tb := &recordingTB{TB: t}
+	w := &logWriter{t: tb, prefix: "cli: "}
+	_, _ = w.Write([]byte("one\ntw"))
+	_, _ = w.Write([]byte("o\nthree"))
+	w.flush()
+
+	want := []string{"cli: one", "cli: two", "cli: three"}
+	if strings.Join(tb.logs, "|") != strings.Join(want, "|") {
+		t.Fatalf("want %v, got %v", want, tb.logs)
+	}
+}
+
+func TestStartCmd_Kill(t *testing.T) {
+	cmd := exec.Command("sleep", "60")
+	t.Run("test", func(t *testing.T) {
+		startCmd(t, cmd)
+	})
+	if cmd.ProcessState == nil {
+		t.Fatal("process is not waited on cleanup")
+	}
+	if cmd.ProcessState.Success() {
+		t.Fatal("process is not killed")
+	}
+}
+
+func TestPgpool_RunCmd(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+	}
+	pool := dbPool.WithEmpty(t)
+
+	var out bytes.Buffer
+	cmd := exec.Command("sh", "-c", `echo "$PGDATABASE"`)
+	cmd.Stdout = &out
+	if err := dbPool.RunCmd(t, pool, cmd); err != nil {
+		t.Fatal(err)
+	}
+	dbName := pool.Config().ConnConfig.Database
+	if got := strings.TrimSpace(out.String()); got != dbName {
+		t.Fatalf("want PGDATABASE %v, got %v", dbName, got)
+	}
+}
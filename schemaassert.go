@@ -0,0 +1,124 @@
+package go_test_pg
+
+import (
+	"context"
+	"testing"
+)
+
+// AssertTableExists fails the test if table (or view) does not exist.
+// Table name may be schema-qualified, otherwise it is looked up using
+// search_path.
+func AssertTableExists(t testing.TB, q Querier, table string) {
+	t.Helper()
+	if !tableExists(t, q, table) {
+		t.Fatalf("table %v does not exist", table)
+	}
+}
+
+// AssertTableNotExists fails the test if table (or view) exists.
+func AssertTableNotExists(t testing.TB, q Querier, table string) {
+	t.Helper()
+	if tableExists(t, q, table) {
+		t.Fatalf("table %v exists", table)
+	}
+}
+
+func tableExists(t testing.TB, q Querier, table string) bool {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var exists bool
+	err := q.QueryRow(ctx, `SELECT to_regclass($1) IS NOT NULL`, table).
+		Scan(&exists)
+	if err != nil {
+		t.Fatalf("can't check table %v: %v", table, err)
+	}
+	return exists
+}
+
+// AssertColumnExists fails the test if table has no column.
+func AssertColumnExists(t testing.TB, q Querier, table, column string) {
+	t.Helper()
+	columnType(t, q, table, column)
+}
+
+// AssertColumnType fails the test if column of table is not of type typ.
+// Types are compared by OID, so aliases like "int" and "integer" match.
+// Type modifiers like length of varchar are not compared.
+func AssertColumnType(t testing.TB, q Querier, table, column, typ string) {
+	t.Helper()
+
+	actual := columnType(t, q, table, column)
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var sameType bool
+	err := q.QueryRow(ctx, `
+SELECT coalesce(a.atttypid = to_regtype($3)::oid, false)
+FROM pg_attribute a
+WHERE a.attrelid = to_regclass($1)
+	AND a.attname = $2`, table, column, typ).Scan(&sameType)
+	if err != nil {
+		t.Fatalf("can't check type of %v.%v: %v", table, column, err)
+	}
+	if !sameType {
+		t.Fatalf("want %v.%v of type %v, got %v", table, column, typ,
+			actual)
+	}
+}
+
+// Returns formatted type of column. Fails the test if column does not
+// exist.
+func columnType(t testing.TB, q Querier, table, column string) string {
+	t.Helper()
+
+	AssertTableExists(t, q, table)
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var typ *string
+	err := q.QueryRow(ctx, `
+SELECT (
+	SELECT format_type(a.atttypid, a.atttypmod)
+	FROM pg_attribute a
+	WHERE a.attrelid = to_regclass($1)
+		AND a.attname = $2
+		AND a.attnum > 0
+		AND NOT a.attisdropped)`, table, column).Scan(&typ)
+	if err != nil {
+		t.Fatalf("can't check column %v.%v: %v", table, column, err)
+	}
+	if typ == nil {
+		t.Fatalf("column %v.%v does not exist", table, column)
+	}
+	return *typ
+}
+
+// AssertIndexExists fails the test if table has no index with name index.
+func AssertIndexExists(t testing.TB, q Querier, table, index string) {
+	t.Helper()
+
+	AssertTableExists(t, q, table)
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var exists bool
+	err := q.QueryRow(ctx, `
+SELECT EXISTS(
+	SELECT 1
+	FROM pg_index i
+		JOIN pg_class c ON c.oid = i.indexrelid
+	WHERE i.indrelid = to_regclass($1)
+		AND c.relname = $2)`, table, index).Scan(&exists)
+	if err != nil {
+		t.Fatalf("can't check index %v: %v", index, err)
+	}
+	if !exists {
+		t.Fatalf("table %v has no index %v", table, index)
+	}
+}
@@ -0,0 +1,21 @@
+package go_test_pg
+
+import (
+	"testing"
+)
+
+func TestSchemaAssertions(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+	}
+	pool := dbPool.WithEmpty(t)
+
+	AssertTableExists(t, pool, "table1")
+	AssertTableExists(t, pool, "public.table1")
+	AssertTableNotExists(t, pool, "table2")
+	AssertColumnExists(t, pool, "table1", "name")
+	AssertColumnType(t, pool, "table1", "id", "int")
+	AssertColumnType(t, pool, "table1", "name", "varchar")
+	AssertIndexExists(t, pool, "table1", "table1_pkey")
+}
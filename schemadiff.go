@@ -0,0 +1,267 @@
+package go_test_pg
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// SchemaChange is a difference of an object between two databases found by
+// DiffSchema.
+type SchemaChange struct {
+	// Kind is schema, extension, table, view, materialized view, sequence,
+	// foreign table, column, constraint, index, trigger, function or type.
+	Kind string
+	// Name of the object. Columns, constraints and triggers are qualified
+	// with the name of their table.
+	Name string
+	// InA and InB are true if the object exists in the first and the
+	// second database.
+	InA, InB bool
+	// A and B are definitions of the object in the first and the second
+	// database, e.g. type and default of a column or CREATE INDEX
+	// statement.
+	A, B string
+}
+
+func (c SchemaChange) String() string {
+	var s string
+	switch {
+	case !c.InB:
+		s = fmt.Sprintf("- %v %v %v", c.Kind, c.Name, c.A)
+	case !c.InA:
+		s = fmt.Sprintf("+ %v %v %v", c.Kind, c.Name, c.B)
+	default:
+		s = fmt.Sprintf("~ %v %v %v -> %v", c.Kind, c.Name, c.A, c.B)
+	}
+	return strings.TrimSpace(s)
+}
+
+// SchemaDiff is a list of differences between two databases ordered by
+// kind and name of objects.
+type SchemaDiff []SchemaChange
+
+// String returns one line per change: objects only in the first database
+// are prefixed with "-", objects only in the second one with "+", and
+// changed objects with "~".
+func (d SchemaDiff) String() string {
+	lines := make([]string, 0, len(d))
+	for _, c := range d {
+		lines = append(lines, c.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// DiffSchema compares structure of databases a and b: schemas, extensions,
+// tables and other relations, columns, constraints, indexes, triggers,
+// functions and types of user schemas. Data is not compared. Names are
+// resolved with search_path of the connections, so both should use the
+// same one. Returns nil if there are no differences.
+func DiffSchema(ctx context.Context, a, b Querier) (SchemaDiff, error) {
+	objectsA, err := readSchema(ctx, a, nil)
+	if err != nil {
+		return nil, err
+	}
+	objectsB, err := readSchema(ctx, b, nil)
+	if err != nil {
+		return nil, err
+	}
+	return diffSchemaObjects(objectsA, objectsB), nil
+}
+
+// AssertSchemaEquals fails the test if structure of databases a and b
+// differs, e.g. to check that migrations from scratch and migrations from
+// the last release converge. See DiffSchema.
+func AssertSchemaEquals(t testing.TB, a, b Querier) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	diff, err := DiffSchema(ctx, a, b)
+	if err != nil {
+		t.Fatalf("can't compare schemas: %v", err)
+	}
+	if len(diff) != 0 {
+		t.Fatalf("schemas differ (- first, + second database):\n%v", diff)
+	}
+}
+
+// Object of a database schema.
+type schemaObject struct {
+	kind       string
+	name       string
+	definition string
+}
+
+// Returns objects of user schemas ordered by kind and name. Tables named in
+// ignore and their columns, constraints, indexes, triggers and owned
+// sequences are left out.
+func readSchema(ctx context.Context, q Querier,
+	ignore []string) ([]schemaObject, error) {
+
+	if ignore == nil {
+		ignore = []string{}
+	}
+	rows, err := q.Query(ctx, `
+WITH rel AS (
+	SELECT c.oid, c.relkind
+	FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+	WHERE `+lintSchemas+`
+		AND `+lintNotExtension+`
+		AND c.relkind IN ('r', 'p', 'v', 'm', 'f', 'S')
+		AND c.relname <> ALL($1)
+		AND NOT EXISTS(
+			SELECT 1 FROM pg_depend d JOIN pg_class o ON o.oid = d.refobjid
+			WHERE d.classid = 'pg_class'::regclass
+				AND d.objid = c.oid
+				AND d.deptype = 'a'
+				AND o.relname = ANY($1))
+)
+SELECT 'schema', quote_ident(n.nspname), ''
+FROM pg_namespace n
+WHERE `+lintSchemas+`
+UNION ALL
+SELECT 'extension', quote_ident(extname), extversion
+FROM pg_extension
+UNION ALL
+SELECT
+	CASE rel.relkind
+		WHEN 'r' THEN 'table'
+		WHEN 'p' THEN 'table'
+		WHEN 'v' THEN 'view'
+		WHEN 'm' THEN 'materialized view'
+		WHEN 'f' THEN 'foreign table'
+		ELSE 'sequence'
+	END,
+	rel.oid::regclass::text,
+	CASE
+		WHEN rel.relkind IN ('v', 'm') THEN pg_get_viewdef(rel.oid)
+		WHEN rel.relkind = 'p' THEN 'PARTITION BY ' ||
+			pg_get_partkeydef(rel.oid)
+		ELSE ''
+	END
+FROM rel
+UNION ALL
+SELECT 'column', format('%s.%I', rel.oid::regclass, a.attname),
+	format_type(a.atttypid, a.atttypmod) ||
+	CASE WHEN a.attnotnull THEN ' NOT NULL' ELSE '' END ||
+	coalesce(' DEFAULT ' || pg_get_expr(d.adbin, d.adrelid), '')
+FROM rel
+	JOIN pg_attribute a ON a.attrelid = rel.oid
+	LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+WHERE a.attnum > 0 AND NOT a.attisdropped AND rel.relkind <> 'S'
+UNION ALL
+SELECT 'constraint', format('%s.%I', rel.oid::regclass, con.conname),
+	pg_get_constraintdef(con.oid)
+FROM rel JOIN pg_constraint con ON con.conrelid = rel.oid
+UNION ALL
+SELECT 'index', i.indexrelid::regclass::text, pg_get_indexdef(i.indexrelid)
+FROM rel JOIN pg_index i ON i.indrelid = rel.oid
+UNION ALL
+SELECT 'trigger', format('%s.%I', rel.oid::regclass, tg.tgname),
+	pg_get_triggerdef(tg.oid)
+FROM rel JOIN pg_trigger tg ON tg.tgrelid = rel.oid
+WHERE NOT tg.tgisinternal
+UNION ALL
+SELECT 'function', p.oid::regprocedure::text, pg_get_functiondef(p.oid)
+FROM pg_proc p
+	JOIN pg_namespace n ON n.oid = p.pronamespace
+WHERE `+lintSchemas+`
+	AND p.prokind IN ('f', 'p')
+	AND NOT EXISTS(
+		SELECT 1 FROM pg_depend d
+		WHERE d.classid = 'pg_proc'::regclass
+			AND d.objid = p.oid
+			AND d.deptype = 'e')
+UNION ALL
+SELECT 'type', t.oid::regtype::text,
+	CASE t.typtype
+		WHEN 'e' THEN (SELECT string_agg(quote_literal(enumlabel), ', '
+			ORDER BY enumsortorder) FROM pg_enum WHERE enumtypid = t.oid)
+		WHEN 'd' THEN format_type(t.typbasetype, t.typtypmod)
+		WHEN 'c' THEN (SELECT string_agg(format('%I %s', attname,
+				format_type(atttypid, atttypmod)), ', ' ORDER BY attnum)
+			FROM pg_attribute
+			WHERE attrelid = t.typrelid AND attnum > 0
+				AND NOT attisdropped)
+		ELSE 'range'
+	END
+FROM pg_type t
+	JOIN pg_namespace n ON n.oid = t.typnamespace
+WHERE `+lintSchemas+`
+	AND t.typtype IN ('e', 'd', 'c', 'r')
+	AND (t.typrelid = 0 OR EXISTS(
+		SELECT 1 FROM pg_class c
+		WHERE c.oid = t.typrelid AND c.relkind = 'c'))
+	AND NOT EXISTS(
+		SELECT 1 FROM pg_depend d
+		WHERE d.classid = 'pg_type'::regclass
+			AND d.objid = t.oid
+			AND d.deptype = 'e')`, ignore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var objects []schemaObject
+	for rows.Next() {
+		var o schemaObject
+		if err = rows.Scan(&o.kind, &o.name, &o.definition); err != nil {
+			return nil, err
+		}
+		objects = append(objects, o)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	sortSchemaObjects(objects)
+	return objects, nil
+}
+
+func sortSchemaObjects(objects []schemaObject) {
+	sort.Slice(objects, func(i, j int) bool {
+		if objects[i].kind != objects[j].kind {
+			return objects[i].kind < objects[j].kind
+		}
+		return objects[i].name < objects[j].name
+	})
+}
+
+// Returns differences between objects a and b, both sorted with
+// sortSchemaObjects.
+func diffSchemaObjects(a, b []schemaObject) SchemaDiff {
+	less := func(x, y schemaObject) bool {
+		if x.kind != y.kind {
+			return x.kind < y.kind
+		}
+		return x.name < y.name
+	}
+
+	var diff SchemaDiff
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case j == len(b) || i < len(a) && less(a[i], b[j]):
+			diff = append(diff, SchemaChange{Kind: a[i].kind,
+				Name: a[i].name, InA: true, A: a[i].definition})
+			i++
+		case i == len(a) || less(b[j], a[i]):
+			diff = append(diff, SchemaChange{Kind: b[j].kind,
+				Name: b[j].name, InB: true, B: b[j].definition})
+			j++
+		default:
+			if a[i].definition != b[j].definition {
+				diff = append(diff, SchemaChange{Kind: a[i].kind,
+					Name: a[i].name, InA: true, InB: true,
+					A: a[i].definition, B: b[j].definition})
+			}
+			i++
+			j++
+		}
+	}
+	return diff
+}
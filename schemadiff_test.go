@@ -0,0 +1,66 @@
+package go_test_pg
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestDiffSchemaObjects(t *testing.T) {
+	a := []schemaObject{
+		{kind: "column", name: "users.id", definition: "integer"},
+		{kind: "column", name: "users.name", definition: "text"},
+		{kind: "table", name: "users"},
+	}
+	b := []schemaObject{
+		{kind: "column", name: "users.id", definition: "bigint"},
+		{kind: "index", name: "users_id_idx", definition: "CREATE INDEX"},
+		{kind: "table", name: "users"},
+	}
+	want := SchemaDiff{
+		{Kind: "column", Name: "users.id", InA: true, InB: true,
+			A: "integer", B: "bigint"},
+		{Kind: "column", Name: "users.name", InA: true, A: "text"},
+		{Kind: "index", Name: "users_id_idx", InB: true, B: "CREATE INDEX"},
+	}
+	diff := diffSchemaObjects(a, b)
+	if !reflect.DeepEqual(diff, want) {
+		t.Fatalf("want %+v, got %+v", want, diff)
+	}
+
+	wantText := "~ column users.id integer -> bigint\n" +
+		"- column users.name text\n" +
+		"+ index users_id_idx CREATE INDEX"
+	if diff.String() != wantText {
+		t.Fatalf("want %q, got %q", wantText, diff.String())
+	}
+
+	if diff = diffSchemaObjects(a, a); diff != nil {
+		t.Fatalf("want no diff, got %v", diff)
+	}
+}
+
+func TestDiffSchema(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+	}
+	a := dbPool.WithEmpty(t)
+	b := dbPool.WithEmpty(t)
+	AssertSchemaEquals(t, a, b)
+
+	_, err := b.Exec(context.Background(),
+		`ALTER TABLE table1 ALTER COLUMN name TYPE text`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff, err := DiffSchema(context.Background(), a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := SchemaDiff{{Kind: "column", Name: "table1.name", InA: true,
+		InB: true, A: "character varying(255)", B: "text"}}
+	if !reflect.DeepEqual(diff, want) {
+		t.Fatalf("want %+v, got %+v", want, diff)
+	}
+}
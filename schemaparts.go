@@ -0,0 +1,131 @@
+package go_test_pg
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SchemaPart is a schema file applied to the template database after
+// SchemaFile. Every part is applied in a separate session, so settings
+// like search_path set by other files do not affect it.
+type SchemaPart struct {
+	// File is the name of SQL file.
+	File string
+	// DependsOn lists files of other parts that must be applied before
+	// this one.
+	DependsOn []string
+}
+
+// Checks that dependencies of parts exist and have no cycles.
+func checkSchemaParts(parts []SchemaPart) error {
+	deps := make(map[string][]string, len(parts))
+	for _, part := range parts {
+		if _, ok := deps[part.File]; ok {
+			return fmt.Errorf("duplicate schema part %v", part.File)
+		}
+		deps[part.File] = part.DependsOn
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int, len(parts))
+	var visit func(file string) error
+	visit = func(file string) error {
+		switch state[file] {
+		case visiting:
+			return fmt.Errorf("schema part %v depends on itself", file)
+		case visited:
+			return nil
+		}
+		state[file] = visiting
+		for _, dep := range deps[file] {
+			if _, ok := deps[dep]; !ok {
+				return fmt.Errorf("schema part %v depends on unknown "+
+					"part %v", file, dep)
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[file] = visited
+		return nil
+	}
+
+	for _, part := range parts {
+		if err := visit(part.File); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Applies SchemaParts to database dbName. Every part starts as soon as
+// parts it depends on are applied. At most GOMAXPROCS parts are applied
+// at the same time.
+func (p *Pgpool) loadSchemaParts(dbName string) error {
+	if err := checkSchemaParts(p.SchemaParts); err != nil {
+		return err
+	}
+
+	done := make(map[string]chan struct{}, len(p.SchemaParts))
+	for _, part := range p.SchemaParts {
+		done[part.File] = make(chan struct{})
+	}
+
+	// Closed on the first error, so waiting parts are not applied.
+	failed := make(chan struct{})
+	var failOnce sync.Once
+	var firstErr error
+
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	var wg sync.WaitGroup
+	for _, part := range p.SchemaParts {
+		wg.Add(1)
+		go func(part SchemaPart) {
+			defer wg.Done()
+
+			for _, dep := range part.DependsOn {
+				select {
+				case <-done[dep]:
+				case <-failed:
+					return
+				}
+			}
+
+			sem <- struct{}{}
+			err := p.withNewConnection(
+				dbName,
+				func(ctx context.Context, conn *pgx.Conn) error {
+					if err := p.useSearchPath(ctx, conn); err != nil {
+						return err
+					}
+					if p.MockNow {
+						if err := useTestclock(ctx, conn); err != nil {
+							return err
+						}
+					}
+					return p.execSchemaFile(ctx, conn, part.File)
+				},
+			)
+			<-sem
+
+			if err != nil {
+				failOnce.Do(func() {
+					firstErr = err
+					close(failed)
+				})
+				return
+			}
+			close(done[part.File])
+		}(part)
+	}
+	wg.Wait()
+
+	return firstErr
+}
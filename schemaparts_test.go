@@ -0,0 +1,65 @@
+package go_test_pg
+
+import (
+	"testing"
+)
+
+func TestCheckSchemaParts(t *testing.T) {
+	testCases := []struct {
+		name  string
+		parts []SchemaPart
+		ok    bool
+	}{
+		{
+			name: "valid",
+			parts: []SchemaPart{
+				{File: "a.sql"},
+				{File: "b.sql", DependsOn: []string{"a.sql"}},
+				{File: "c.sql", DependsOn: []string{"a.sql", "b.sql"}},
+			},
+			ok: true,
+		},
+		{
+			name:  "unknown dependency",
+			parts: []SchemaPart{{File: "a.sql", DependsOn: []string{"x"}}},
+		},
+		{
+			name: "cycle",
+			parts: []SchemaPart{
+				{File: "a.sql", DependsOn: []string{"b.sql"}},
+				{File: "b.sql", DependsOn: []string{"a.sql"}},
+			},
+		},
+		{
+			name:  "duplicate",
+			parts: []SchemaPart{{File: "a.sql"}, {File: "a.sql"}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkSchemaParts(tc.parts)
+			if (err == nil) != tc.ok {
+				t.Fatalf("want ok %v, got %v", tc.ok, err)
+			}
+		})
+	}
+}
+
+func TestPgpool_SchemaParts(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+		SchemaParts: []SchemaPart{
+			{File: "./testdata/parts/users.sql"},
+			{File: "./testdata/parts/orders.sql",
+				DependsOn: []string{"./testdata/parts/users.sql"}},
+			{File: "./testdata/parts/audit.sql"},
+		},
+	}
+	pool := dbPool.WithEmpty(t)
+	for _, table := range []string{"table1", "users", "orders",
+		"audit_log"} {
+
+		AssertTableExists(t, pool, table)
+	}
+}
@@ -0,0 +1,56 @@
+package go_test_pg
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"os"
+	"sync"
+	"testing"
+)
+
+// SeedEnv is the environment variable that changes data seeds returned by
+// Rand. Seeds still depend on test names, so every test gets its own
+// reproducible sequence.
+const SeedEnv = "GO_TEST_PG_SEED"
+
+var (
+	testRandsM sync.Mutex
+	testRands  = make(map[testing.TB]*rand.Rand)
+)
+
+// Rand returns random generator of the test seeded from a hash of t.Name()
+// and SeedEnv environment variable. All calls for the same test return the
+// same generator, so data generated with it is the same on every run. The
+// generator is not safe for concurrent use.
+func Rand(t testing.TB) *rand.Rand {
+	testRandsM.Lock()
+	defer testRandsM.Unlock()
+
+	if r, ok := testRands[t]; ok {
+		return r
+	}
+
+	seed := testSeed(t.Name(), os.Getenv(SeedEnv))
+	r := rand.New(rand.NewSource(seed))
+	testRands[t] = r
+	t.Cleanup(func() {
+		if t.Failed() {
+			t.Logf("go-test-pg: data seed of %v: %v (%v=%q)", t.Name(),
+				seed, SeedEnv, os.Getenv(SeedEnv))
+		}
+		testRandsM.Lock()
+		delete(testRands, t)
+		testRandsM.Unlock()
+	})
+	return r
+}
+
+func testSeed(name, env string) int64 {
+	h := fnv.New64a()
+	if env != "" {
+		_, _ = h.Write([]byte(env))
+		_, _ = h.Write([]byte{0})
+	}
+	_, _ = h.Write([]byte(name))
+	return int64(h.Sum64())
+}
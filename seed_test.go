@@ -0,0 +1,39 @@
+package go_test_pg
+
+import (
+	"math/rand"
+	"os"
+	"testing"
+)
+
+func TestRand(t *testing.T) {
+	r := Rand(t)
+	if Rand(t) != r {
+		t.Fatal("want the same generator for the same test")
+	}
+
+	want := rand.New(rand.NewSource(testSeed(t.Name(), os.Getenv(SeedEnv))))
+	for i := 0; i < 3; i++ {
+		if got, want := r.Int63(), want.Int63(); got != want {
+			t.Fatalf("want %v, got %v", want, got)
+		}
+	}
+
+	t.Run("sub", func(t *testing.T) {
+		if Rand(t) == r {
+			t.Fatal("want separate generator for subtest")
+		}
+	})
+}
+
+func TestTestSeed(t *testing.T) {
+	if testSeed("TestX", "") != testSeed("TestX", "") {
+		t.Fatal("seed is not deterministic")
+	}
+	if testSeed("TestX", "") == testSeed("TestY", "") {
+		t.Fatal("want different seeds for different tests")
+	}
+	if testSeed("TestX", "") == testSeed("TestX", "42") {
+		t.Fatal("want env to change the seed")
+	}
+}
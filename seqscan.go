@@ -0,0 +1,103 @@
+package go_test_pg
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// SeqScanOption modifies AssertNoSeqScan. Options are passed together
+// with query arguments.
+type SeqScanOption struct {
+	allow []string
+}
+
+// AllowSeqScan allows sequential scans of tables, e.g. small dictionaries.
+// Names may be schema-qualified.
+func AllowSeqScan(tables ...string) SeqScanOption {
+	return SeqScanOption{allow: tables}
+}
+
+// AssertNoSeqScan fails the test if the plan of query contains a
+// sequential scan, e.g. because an index used by a hot path query was
+// dropped. Test tables are small and would be scanned sequentially anyway,
+// so the query is planned with enable_seqscan off: a sequential scan is
+// chosen only if no index can be used. SeqScanOption values may be mixed
+// with args. The query is only planned, not executed.
+func AssertNoSeqScan(t testing.TB, q Querier, query string, args ...any) {
+	t.Helper()
+
+	var allow []string
+	queryArgs := make([]any, 0, len(args))
+	for _, arg := range args {
+		if o, ok := arg.(SeqScanOption); ok {
+			allow = append(allow, o.allow...)
+			continue
+		}
+		queryArgs = append(queryArgs, arg)
+	}
+
+	b, ok := q.(txBeginner)
+	if !ok {
+		t.Fatalf("can't begin transaction on %T", q)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	tx, err := b.Begin(ctx)
+	if err != nil {
+		t.Fatalf("can't begin transaction: %v", err)
+	}
+	defer func() { _ = tx.Rollback(context.Background()) }()
+
+	_, err = tx.Exec(ctx, `SET LOCAL enable_seqscan = off`)
+	if err != nil {
+		t.Fatalf("can't disable sequential scans: %v", err)
+	}
+	var plan string
+	err = tx.QueryRow(ctx, `EXPLAIN (FORMAT JSON, VERBOSE) `+query,
+		queryArgs...).Scan(&plan)
+	if err != nil {
+		t.Fatalf("can't explain query: %v%v", err, querierHint(q))
+	}
+
+	scans, err := seqScans(plan, allow)
+	if err != nil {
+		t.Fatalf("can't parse query plan: %v", err)
+	}
+	if len(scans) != 0 {
+		t.Fatalf("query scans %v sequentially:\n%v", strings.Join(scans, ", "),
+			plan)
+	}
+}
+
+// Returns schema-qualified names of tables scanned sequentially in plan,
+// except allowed ones.
+func seqScans(plan string, allow []string) ([]string, error) {
+	root, err := parsePlan(plan)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := make(map[string]bool, len(allow))
+	for _, table := range allow {
+		allowed[table] = true
+	}
+
+	var scans []string
+	var walk func(n planNode)
+	walk = func(n planNode) {
+		if n.NodeType == "Seq Scan" {
+			name := n.relation()
+			if !allowed[n.RelationName] && !allowed[name] {
+				scans = append(scans, name)
+			}
+		}
+		for _, child := range n.Plans {
+			walk(child)
+		}
+	}
+	walk(root)
+	return scans, nil
+}
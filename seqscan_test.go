@@ -0,0 +1,49 @@
+package go_test_pg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSeqScans(t *testing.T) {
+	plan := `[{"Plan": {"Node Type": "Nested Loop", "Plans": [
+		{"Node Type": "Seq Scan", "Relation Name": "users",
+			"Schema": "public"},
+		{"Node Type": "Index Scan", "Relation Name": "orders",
+			"Schema": "public"},
+		{"Node Type": "Seq Scan", "Relation Name": "countries",
+			"Schema": "public"}
+	]}}]`
+
+	scans, err := seqScans(plan, []string{"countries"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"public.users"}; !reflect.DeepEqual(scans, want) {
+		t.Fatalf("want %v, got %v", want, scans)
+	}
+
+	scans, err = seqScans(plan, []string{"public.users", "countries"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scans) != 0 {
+		t.Fatalf("want no scans, got %v", scans)
+	}
+
+	if _, err = seqScans(`[]`, nil); err == nil {
+		t.Fatal("want error for empty plan")
+	}
+}
+
+func TestAssertNoSeqScan(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+	}
+	pool := dbPool.WithEmpty(t)
+
+	AssertNoSeqScan(t, pool, `SELECT * FROM table1 WHERE id = $1`, 1)
+	AssertNoSeqScan(t, pool, `SELECT * FROM table1 WHERE name = $1`, "a",
+		AllowSeqScan("table1"))
+}
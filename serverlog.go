@@ -0,0 +1,160 @@
+package go_test_pg
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Maximum size of server log read for a failed test.
+const maxServerLogBytes = 1 << 20
+
+// Columns of csvlog records.
+const (
+	csvlogTime     = 0
+	csvlogDatabase = 2
+	csvlogSeverity = 11
+	csvlogMessage  = 13
+	csvlogDetail   = 14
+	csvlogQuery    = 19
+)
+
+// Returns log_statement and log_min_duration_statement settings of test
+// databases.
+func (p *Pgpool) serverLogSettings() map[string]string {
+	settings := make(map[string]string)
+	if p.ServerLogStatement != "" {
+		settings["log_statement"] = p.ServerLogStatement
+	}
+	if p.ServerLogMinDuration > 0 {
+		settings["log_min_duration_statement"] = strconv.FormatInt(
+			p.ServerLogMinDuration.Milliseconds(), 10)
+	}
+	return settings
+}
+
+// Position in the server log file.
+type serverLogPos struct {
+	file   string
+	offset int64
+	csv    bool
+}
+
+// Remembers the end of the server log when test t starts using database
+// dbName, and logs lines written for the database since then if the test
+// fails.
+func (p *Pgpool) captureServerLog(t testing.TB, dbName string) {
+	if !p.CaptureServerLogs {
+		return
+	}
+
+	pos, err := p.serverLogPos()
+	if err != nil {
+		t.Logf("go-test-pg: can't capture server log: %v", err)
+		return
+	}
+	t.Cleanup(func() {
+		if !t.Failed() {
+			return
+		}
+		lines, err := p.serverLogLines(pos, dbName)
+		if err != nil {
+			t.Logf("go-test-pg: can't read server log: %v", err)
+			return
+		}
+		if len(lines) != 0 {
+			t.Logf("go-test-pg: server log of database %v:\n%v", dbName,
+				strings.Join(lines, "\n"))
+		}
+	})
+}
+
+// Returns the current end of the server log. csvlog is preferred, as it
+// has database name in every record.
+func (p *Pgpool) serverLogPos() (serverLogPos, error) {
+	var pos serverLogPos
+	err := p.withMasterConnection(
+		func(ctx context.Context, conn *pgx.Conn) error {
+			var file *string
+			err := conn.QueryRow(ctx,
+				`SELECT coalesce(pg_current_logfile('csvlog'),
+					pg_current_logfile())`).Scan(&file)
+			if err != nil {
+				return err
+			}
+			if file == nil {
+				return errors.New("logging_collector is off")
+			}
+			pos.file = *file
+			pos.csv = strings.HasSuffix(pos.file, ".csv")
+			return conn.QueryRow(ctx,
+				`SELECT size FROM pg_stat_file($1)`, pos.file).
+				Scan(&pos.offset)
+		})
+	return pos, err
+}
+
+// Reads lines of the server log written for database dbName after pos.
+// Lines written after log rotation are missed.
+func (p *Pgpool) serverLogLines(pos serverLogPos,
+	dbName string) ([]string, error) {
+
+	var data string
+	err := p.withMasterConnection(
+		func(ctx context.Context, conn *pgx.Conn) error {
+			return conn.QueryRow(ctx, `SELECT pg_read_file($1, $2, $3)`,
+				pos.file, pos.offset, maxServerLogBytes).Scan(&data)
+		})
+	if err != nil {
+		return nil, err
+	}
+	if pos.csv {
+		return filterCSVLog(data, dbName), nil
+	}
+	return filterStderrLog(data, dbName), nil
+}
+
+// Returns records of csvlog data for database dbName formatted like stderr
+// log lines. A truncated record at the end is skipped.
+func filterCSVLog(data, dbName string) []string {
+	r := csv.NewReader(strings.NewReader(data))
+	r.FieldsPerRecord = -1
+
+	var lines []string
+	for {
+		rec, err := r.Read()
+		if err != nil {
+			// io.EOF or the truncated last record.
+			return lines
+		}
+		if len(rec) <= csvlogQuery || rec[csvlogDatabase] != dbName {
+			continue
+		}
+		line := rec[csvlogTime] + " " + rec[csvlogSeverity] + ":  " +
+			rec[csvlogMessage]
+		if rec[csvlogDetail] != "" {
+			line += "\n\tDETAIL:  " + rec[csvlogDetail]
+		}
+		if rec[csvlogQuery] != "" {
+			line += "\n\tSTATEMENT:  " + rec[csvlogQuery]
+		}
+		lines = append(lines, line)
+	}
+}
+
+// Returns lines of stderr log data mentioning database dbName. Only lines
+// with database name in log_line_prefix (%d) are found.
+func filterStderrLog(data, dbName string) []string {
+	var lines []string
+	for _, line := range strings.Split(data, "\n") {
+		if strings.Contains(line, dbName) {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
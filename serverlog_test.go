@@ -0,0 +1,58 @@
+package go_test_pg
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPgpool_databaseSettings_ServerLog(t *testing.T) {
+	p := Pgpool{
+		DatabaseSettings:     map[string]string{"work_mem": "64MB"},
+		ServerLogStatement:   "all",
+		ServerLogMinDuration: 1500 * time.Millisecond,
+	}
+	want := map[string]string{
+		"work_mem":                   "64MB",
+		"log_statement":              "all",
+		"log_min_duration_statement": "1500",
+	}
+	if got := p.databaseSettings(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected settings: %v", got)
+	}
+}
+
+func TestFilterCSVLog(t *testing.T) {
+	data := `2024-01-02 03:04:05.678 UTC,"postgres","db_1",42,` +
+		`"[local]",1,1,"SELECT",2024-01-02 03:04:05 UTC,3/4,0,` +
+		`ERROR,42P01,"relation ""t"" does not exist",,,,,,` +
+		`"SELECT * FROM t",15,,"psql","client backend",,0
+2024-01-02 03:04:06.000 UTC,"postgres","db_2",43,"[local]",1,1,"idle",` +
+		`2024-01-02 03:04:05 UTC,3/5,0,LOG,00000,"statement: SELECT 1",` +
+		`,,,,,,,,"psql","client backend",,0
+2024-01-02 03:04:07.000 UTC,"postgres","db_1",42,"[local]",2,1,"idle",` +
+		`2024-01-02 03:04:05 UTC,3/6,0,LOG,00000,"statement: SELECT 2",` +
+		`"some detail",,,,,,,,"psql","client backend",,0
+2024-01-02 03:04:08.000 UTC,"postgres","db_1",42,"[local]",3,1,"idle`
+
+	want := []string{
+		`2024-01-02 03:04:05.678 UTC ERROR:  relation "t" does not exist` +
+			"\n\tSTATEMENT:  SELECT * FROM t",
+		`2024-01-02 03:04:07.000 UTC LOG:  statement: SELECT 2` +
+			"\n\tDETAIL:  some detail",
+	}
+	if got := filterCSVLog(data, "db_1"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected lines: %#v", got)
+	}
+}
+
+func TestFilterStderrLog(t *testing.T) {
+	data := "2024-01-02 03:04:05 UTC [42] db_1 LOG:  statement: SELECT 1\n" +
+		"2024-01-02 03:04:05 UTC [43] db_2 LOG:  statement: SELECT 2\n"
+	want := []string{
+		"2024-01-02 03:04:05 UTC [42] db_1 LOG:  statement: SELECT 1",
+	}
+	if got := filterStderrLog(data, "db_1"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected lines: %#v", got)
+	}
+}
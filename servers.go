@@ -0,0 +1,139 @@
+package go_test_pg
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// ServersEnv is the environment variable with servers of ServerSet when
+// its Servers field is empty: comma-separated entries of server name and
+// Hosts entry, e.g. GO_TEST_PG_SERVERS=pg13=localhost:5413,pg17=/run/pg17.
+const ServersEnv = "GO_TEST_PG_SERVERS"
+
+// Server is one of servers of ServerSet.
+type Server struct {
+	// Name of the subtest, e.g. "pg13".
+	Name string
+	// Hosts and Port replace those of the Pgpool of the server. See
+	// Pgpool.Hosts.
+	Hosts []string
+	Port  uint16
+}
+
+// ServerSet runs tests against several servers, e.g. containers with
+// different PostgreSQL versions, so compatibility is checked in one
+// go test run. A Pgpool is created for every server on first use and
+// kept, so template databases are created once per server.
+type ServerSet struct {
+	// Servers to run tests against. If empty, servers are read from
+	// ServersEnv. If it is not set either, tests run against the server of
+	// the environment only.
+	Servers []Server
+	// NewPool returns the configuration of the pool of server s, e.g. a
+	// Pgpool with the same schema for all servers. Hosts and Port of the
+	// result are replaced with those of s.
+	NewPool func(s Server) *Pgpool
+
+	m     sync.Mutex
+	pools map[string]*Pgpool
+}
+
+// ForEachServer runs fn as a subtest named after the server for every
+// server of the set.
+func (s *ServerSet) ForEachServer(t *testing.T,
+	fn func(t *testing.T, p *Pgpool, srv Server)) {
+
+	t.Helper()
+
+	servers, err := s.servers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, srv := range servers {
+		srv := srv
+		t.Run(srv.Name, func(t *testing.T) {
+			fn(t, s.pool(t, srv), srv)
+		})
+	}
+}
+
+// Close closes pools of all servers. See Pgpool.Close.
+func (s *ServerSet) Close() {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	for _, p := range s.pools {
+		p.Close()
+	}
+}
+
+func (s *ServerSet) servers() ([]Server, error) {
+	if len(s.Servers) != 0 {
+		return s.Servers, nil
+	}
+	if env := os.Getenv(ServersEnv); env != "" {
+		servers, err := parseServers(env)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %v: %w", ServersEnv, err)
+		}
+		return servers, nil
+	}
+	return []Server{{Name: "default"}}, nil
+}
+
+// Returns the pool of server srv, creating it on first call.
+func (s *ServerSet) pool(t testing.TB, srv Server) *Pgpool {
+	t.Helper()
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if p, ok := s.pools[srv.Name]; ok {
+		return p
+	}
+
+	if s.NewPool == nil {
+		t.Fatal("ServerSet.NewPool is not set")
+	}
+	p := s.NewPool(srv)
+	if p == nil {
+		t.Fatalf("ServerSet.NewPool returned nil for server %v", srv.Name)
+	}
+	if len(srv.Hosts) != 0 {
+		p.Hosts = srv.Hosts
+		p.SocketDir = ""
+	}
+	if srv.Port != 0 {
+		p.Port = srv.Port
+	}
+
+	if s.pools == nil {
+		s.pools = make(map[string]*Pgpool)
+	}
+	s.pools[srv.Name] = p
+	return p
+}
+
+// Parses servers in ServersEnv format.
+func parseServers(env string) ([]Server, error) {
+	var servers []Server
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(env, ",") {
+		name, host, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || name == "" || host == "" {
+			return nil, fmt.Errorf("entry %q is not name=host", entry)
+		}
+		if _, _, err := parseHost(host); err != nil {
+			return nil, err
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate server %v", name)
+		}
+		seen[name] = true
+		servers = append(servers, Server{Name: name, Hosts: []string{host}})
+	}
+	return servers, nil
+}
@@ -0,0 +1,65 @@
+package go_test_pg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseServers(t *testing.T) {
+	servers, err := parseServers("pg13=localhost:5413, pg17=/run/pg17")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Server{
+		{Name: "pg13", Hosts: []string{"localhost:5413"}},
+		{Name: "pg17", Hosts: []string{"/run/pg17"}},
+	}
+	if !reflect.DeepEqual(servers, want) {
+		t.Fatalf("want %v, got %v", want, servers)
+	}
+
+	for _, env := range []string{"pg13", "=localhost", "a=x,a=y",
+		"a=localhost:0"} {
+
+		if _, err = parseServers(env); err == nil {
+			t.Errorf("want error for %q", env)
+		}
+	}
+}
+
+func TestServerSetPool(t *testing.T) {
+	set := &ServerSet{
+		Servers: []Server{{Name: "pg17", Hosts: []string{"db"}, Port: 5417}},
+		NewPool: func(s Server) *Pgpool {
+			return &Pgpool{BaseName: "go_test_pg_" + s.Name,
+				SocketDir: "/tmp"}
+		},
+	}
+	srv := set.Servers[0]
+	p := set.pool(t, srv)
+	if p.BaseName != "go_test_pg_pg17" || p.Port != 5417 ||
+		p.SocketDir != "" || !reflect.DeepEqual(p.Hosts, srv.Hosts) {
+
+		t.Fatalf("unexpected pool: %+v", p)
+	}
+	if set.pool(t, srv) != p {
+		t.Fatal("pool is not reused")
+	}
+}
+
+func TestForEachServer(t *testing.T) {
+	set := &ServerSet{
+		NewPool: func(s Server) *Pgpool {
+			return &Pgpool{
+				BaseName:   "go_test_pg",
+				SchemaFile: "./testdata/schema1.sql",
+			}
+		},
+	}
+	defer set.Close()
+
+	set.ForEachServer(t, func(t *testing.T, p *Pgpool, srv Server) {
+		pool := p.WithEmpty(t)
+		AssertTableExists(t, pool, "table1")
+	})
+}
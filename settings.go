@@ -0,0 +1,203 @@
+package go_test_pg
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var gucNameRe = regexp.MustCompile(
+	`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// Returns ALTER DATABASE statements that set settings as defaults for
+// database dbName. Statements are sorted by setting name. The value is set
+// with set_config first and stored FROM CURRENT, so values of list
+// settings like search_path are parsed the same way as in SET.
+func alterDatabaseSQLs(dbName string,
+	settings map[string]string) ([]string, error) {
+
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		if !gucNameRe.MatchString(name) {
+			return nil, fmt.Errorf("invalid setting name: %q", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	sqls := make([]string, 0, len(names))
+	for _, name := range names {
+		sqls = append(sqls, `SELECT set_config(`+quoteLiteral(name)+`, `+
+			quoteLiteral(settings[name])+`, true); ALTER DATABASE `+
+			quote(dbName)+` SET `+name+` FROM CURRENT`)
+	}
+	return sqls, nil
+}
+
+// Sets DatabaseSettings as defaults for database dbName and its
+// ConnectionLimit. New connections to the database pick them up.
+func (p *Pgpool) applyDatabaseSettings(dbName string) error {
+	settings := p.databaseSettings()
+	if len(settings) == 0 && p.ConnectionLimit <= 0 {
+		return nil
+	}
+
+	sqls, err := alterDatabaseSQLs(dbName, settings)
+	if err != nil {
+		return err
+	}
+	if p.ConnectionLimit > 0 {
+		sqls = append(sqls, connectionLimitSQL(dbName, p.ConnectionLimit))
+	}
+
+	return p.withNewConnection(
+		"",
+		func(ctx context.Context, conn *pgx.Conn) error {
+			for _, s := range sqls {
+				if _, err := conn.Exec(ctx, s); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	)
+}
+
+// Returns settings to be set as database defaults, including server log
+// and guardrail settings. In PgBouncer mode
+// session settings are set as database defaults too, as server connections
+// are shared between clients.
+func (p *Pgpool) databaseSettings() map[string]string {
+	var sessionSettings map[string]string
+	if p.opts().pgBouncer {
+		sessionSettings = p.sessionSettings()
+	}
+	logSettings := p.serverLogSettings()
+	guardSettings := p.guardSettings()
+	if len(sessionSettings) == 0 && len(logSettings) == 0 &&
+		len(guardSettings) == 0 {
+
+		return p.DatabaseSettings
+	}
+
+	settings := make(map[string]string, len(p.DatabaseSettings)+
+		len(logSettings)+len(guardSettings)+len(sessionSettings))
+	for k, v := range guardSettings {
+		settings[k] = v
+	}
+	for k, v := range p.DatabaseSettings {
+		settings[k] = v
+	}
+	for k, v := range logSettings {
+		settings[k] = v
+	}
+	for k, v := range sessionSettings {
+		settings[k] = v
+	}
+	return settings
+}
+
+// Returns settings to be set on every connection of returned pools.
+func (p *Pgpool) sessionSettings() map[string]string {
+	if !p.MockNow && len(p.SearchPath) == 0 {
+		return p.SessionSettings
+	}
+
+	settings := make(map[string]string, len(p.SessionSettings)+1)
+	for k, v := range p.SessionSettings {
+		settings[k] = v
+	}
+	if _, ok := settings["search_path"]; !ok && len(p.SearchPath) != 0 {
+		settings["search_path"] = p.searchPath()
+	}
+	if !p.MockNow {
+		return settings
+	}
+	if searchPath, ok := settings["search_path"]; ok {
+		settings["search_path"] = "testclock, pg_catalog, " + searchPath
+	} else {
+		settings["search_path"] = testclockSearchPath
+	}
+	return settings
+}
+
+// Returns SearchPath as a value of search_path setting.
+func (p *Pgpool) searchPath() string {
+	schemas := make([]string, len(p.SearchPath))
+	for i, schema := range p.SearchPath {
+		schemas[i] = quote(schema)
+	}
+	return strings.Join(schemas, ", ")
+}
+
+// Sets search_path of conn loading the schema to SearchPath.
+func (p *Pgpool) useSearchPath(ctx context.Context, conn *pgx.Conn) error {
+	if len(p.SearchPath) == 0 {
+		return nil
+	}
+	_, err := conn.Exec(ctx, `SELECT set_config('search_path', $1, false)`,
+		p.searchPath())
+	if err != nil {
+		return fmt.Errorf("can't set search_path: %w", err)
+	}
+	return nil
+}
+
+// Sets session settings on a new connection of a returned pool.
+func (p *Pgpool) afterConnect(ctx context.Context, conn *pgx.Conn) error {
+	if p.opts().pgBouncer {
+		return nil
+	}
+	return setSessionSettings(ctx, conn, p.sessionSettings())
+}
+
+func setSessionSettings(ctx context.Context, conn *pgx.Conn,
+	settings map[string]string) error {
+
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		_, err := conn.Exec(ctx, `SELECT set_config($1, $2, false)`,
+			name, settings[name])
+		if err != nil {
+			return fmt.Errorf("can't set %v: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Logs a warning if the server runs with fsync turned on. Tests do not need
+// durability, and fsync dominates the cost of write-heavy test suites.
+func (p *Pgpool) warnFsync() {
+	err := p.withNewConnection(
+		"",
+		func(ctx context.Context, conn *pgx.Conn) error {
+			var fsync string
+			err := conn.QueryRow(ctx, `SHOW fsync`).Scan(&fsync)
+			if err != nil {
+				return err
+			}
+			if fsync == "on" {
+				log.Printf("go-test-pg: PostgreSQL server runs with " +
+					"fsync=on, consider turning it off for tests")
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		log.Printf("go-test-pg: can't check fsync setting: %v", err)
+	}
+}
+
+func quoteLiteral(s string) string {
+	return `'` + strings.ReplaceAll(s, `'`, `''`) + `'`
+}
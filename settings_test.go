@@ -0,0 +1,133 @@
+package go_test_pg
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestAlterDatabaseSQLs(t *testing.T) {
+	sqls, err := alterDatabaseSQLs("db_1", map[string]string{
+		"work_mem":           "64MB",
+		"synchronous_commit": "off",
+		"app.tenant":         "it's",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`SELECT set_config('app.tenant', 'it''s', true); ` +
+			`ALTER DATABASE "db_1" SET app.tenant FROM CURRENT`,
+		`SELECT set_config('synchronous_commit', 'off', true); ` +
+			`ALTER DATABASE "db_1" SET synchronous_commit FROM CURRENT`,
+		`SELECT set_config('work_mem', '64MB', true); ` +
+			`ALTER DATABASE "db_1" SET work_mem FROM CURRENT`,
+	}
+	if !reflect.DeepEqual(sqls, want) {
+		t.Fatalf("unexpected SQLs: %#v", sqls)
+	}
+
+	_, err = alterDatabaseSQLs("db_1", map[string]string{"a; DROP": "1"})
+	if err == nil {
+		t.Fatal("expected error on invalid setting name")
+	}
+}
+
+func TestPgpool_databaseSettings_PgBouncer(t *testing.T) {
+	p := Pgpool{
+		PgBouncer:        true,
+		DatabaseSettings: map[string]string{"work_mem": "64MB"},
+		SessionSettings:  map[string]string{"timezone": "UTC"},
+	}
+	want := map[string]string{"work_mem": "64MB", "timezone": "UTC"}
+	if got := p.databaseSettings(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected settings: %v", got)
+	}
+
+	p.PgBouncer = false
+	want = map[string]string{"work_mem": "64MB"}
+	if got := p.databaseSettings(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected settings: %v", got)
+	}
+}
+
+func TestPgpool_sessionSettings_MockNow(t *testing.T) {
+	p := Pgpool{MockNow: true}
+	want := map[string]string{"search_path": testclockSearchPath}
+	if got := p.sessionSettings(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected settings: %v", got)
+	}
+
+	p.SessionSettings = map[string]string{"search_path": "app"}
+	want = map[string]string{"search_path": "testclock, pg_catalog, app"}
+	if got := p.sessionSettings(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected settings: %v", got)
+	}
+}
+
+func TestPgpool_sessionSettings_SearchPath(t *testing.T) {
+	p := Pgpool{SearchPath: []string{"app", "$user", "public"}}
+	want := map[string]string{"search_path": `"app", "$user", "public"`}
+	if got := p.sessionSettings(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected settings: %v", got)
+	}
+
+	p.MockNow = true
+	want = map[string]string{
+		"search_path": `testclock, pg_catalog, "app", "$user", "public"`,
+	}
+	if got := p.sessionSettings(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected settings: %v", got)
+	}
+
+	p.MockNow = false
+	p.SessionSettings = map[string]string{"search_path": "other"}
+	want = map[string]string{"search_path": "other"}
+	if got := p.sessionSettings(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected settings: %v", got)
+	}
+}
+
+func TestPgpool_SearchPath(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema_search_path.sql",
+		SearchPath: []string{"app", "public"},
+	}
+	pool := dbPool.WithEmpty(t)
+
+	// The table is created in the first schema of the search path.
+	AssertTableExists(t, pool, "app.accounts")
+	AssertTableExists(t, pool, "accounts")
+}
+
+func TestPgpool_SessionSettings(t *testing.T) {
+	var dbPool = Pgpool{
+		SessionSettings: map[string]string{
+			"timezone":      "Asia/Tokyo",
+			"app.tenant_id": "42",
+		},
+	}
+
+	pool := dbPool.WithEmpty(t)
+	var tz, tenantID string
+	err := pool.QueryRow(context.Background(),
+		`SELECT current_setting('timezone'),
+			current_setting('app.tenant_id')`).Scan(&tz, &tenantID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tz != "Asia/Tokyo" || tenantID != "42" {
+		t.Fatalf("unexpected settings: %v, %v", tz, tenantID)
+	}
+
+	db := dbPool.WithStdEmpty(t)
+	err = db.QueryRow(`SELECT current_setting('app.tenant_id')`).
+		Scan(&tenantID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tenantID != "42" {
+		t.Fatalf("unexpected app.tenant_id: %v", tenantID)
+	}
+}
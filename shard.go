@@ -0,0 +1,49 @@
+package go_test_pg
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"strings"
+)
+
+// ShardEnv is the environment variable with the identifier of the CI job
+// or shard, used when Pgpool.Shard is empty, e.g.
+// GO_TEST_PG_SHARD=$CI_JOB_ID.
+const ShardEnv = "GO_TEST_PG_SHARD"
+
+// Maximum length of the shard identifier in database names. Longer
+// identifiers are replaced with their 8 character hash, so names of test
+// databases, dbtestpg_<shard>_<32 hex digits>_<up to 10 digits>, fit in
+// 63 bytes.
+const maxShardLen = 10
+
+// Returns the shard identifier to append to BaseName, or empty string if
+// it is not set.
+func (p *Pgpool) shard() string {
+	shard := p.Shard
+	if shard == "" {
+		shard = os.Getenv(ShardEnv)
+	}
+	return shardSuffix(shard)
+}
+
+// Returns shard lowered with characters other than letters, digits and
+// underscore replaced with underscore, or hash of shard if it is too long.
+func shardSuffix(shard string) string {
+	if len(shard) > maxShardLen {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(shard))
+		return fmt.Sprintf("%08x", h.Sum32())
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		default:
+			return '_'
+		}
+	}, shard)
+}
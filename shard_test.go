@@ -0,0 +1,56 @@
+package go_test_pg
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestShardSuffix(t *testing.T) {
+	testCases := []struct {
+		shard string
+		want  string
+	}{
+		{"", ""},
+		{"3", "3"},
+		{"Job-42/a", "job_42_a"},
+		{"very-long-job-identifier", "aa616c30"},
+		{"job-123456", "job_123456"},
+		{"job-1234567", "be884939"},
+	}
+	for _, tc := range testCases {
+		if got := shardSuffix(tc.shard); got != tc.want {
+			t.Errorf("shard %q: want %q, got %q", tc.shard, tc.want, got)
+		}
+	}
+}
+
+func TestPgpool_baseName_Shard(t *testing.T) {
+	t.Setenv(ShardEnv, "env")
+
+	p := &Pgpool{BaseName: "app"}
+	if got := p.baseName(); got != "app_env" {
+		t.Fatalf("want app_env, got %v", got)
+	}
+
+	p.Shard = "job1"
+	if got := p.baseName(); got != "app_job1" {
+		t.Fatalf("want app_job1, got %v", got)
+	}
+
+	t.Setenv(ShardEnv, "")
+	p = &Pgpool{}
+	if got := p.baseName(); got != "dbtestpg" {
+		t.Fatalf("want dbtestpg, got %v", got)
+	}
+}
+
+func TestPgpool_baseName_ShardLength(t *testing.T) {
+	p := &Pgpool{Shard: strings.Repeat("j", maxShardLen)}
+	tmplName := fmt.Sprintf("%v_%v", p.baseName(), strings.Repeat("f", 32))
+	dbName := fmt.Sprintf("%v_%v", tmplName, int32(math.MaxInt32))
+	if len(dbName) > 63 {
+		t.Fatalf("database name %v is %v bytes long", dbName, len(dbName))
+	}
+}
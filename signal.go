@@ -0,0 +1,106 @@
+package go_test_pg
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Test databases existing in the process, by Pgpool values which created
+// them.
+var (
+	createdDBsM sync.Mutex
+	createdDBs  = make(map[string]*Pgpool)
+
+	dropOnSignalOnce sync.Once
+)
+
+func registerCreatedDB(dbName string, p *Pgpool) {
+	createdDBsM.Lock()
+	defer createdDBsM.Unlock()
+
+	createdDBs[dbName] = p
+}
+
+func unregisterCreatedDB(dbName string) {
+	createdDBsM.Lock()
+	defer createdDBsM.Unlock()
+
+	delete(createdDBs, dbName)
+}
+
+// DropOnSignal installs a handler of SIGINT and SIGTERM that drops test
+// databases created by the process before it exits, so interrupting
+// go test does not leave them on the server. Template databases are kept.
+// Call it from TestMain before m.Run. Drops are best effort: connections
+// to the databases are terminated, and errors are logged.
+func DropOnSignal() {
+	dropOnSignalOnce.Do(func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			sig := <-signals
+			log.Printf("go-test-pg: %v received, dropping test databases",
+				sig)
+			dropCreatedDBs()
+
+			// Exit the way the signal would without the handler.
+			signal.Stop(signals)
+			proc, err := os.FindProcess(os.Getpid())
+			if err == nil {
+				err = proc.Signal(sig)
+			}
+			if err != nil {
+				os.Exit(1)
+			}
+		}()
+	})
+}
+
+// Drops all test databases created by the process.
+func dropCreatedDBs() {
+	createdDBsM.Lock()
+	dbs := make(map[string]*Pgpool, len(createdDBs))
+	for dbName, p := range createdDBs {
+		dbs[dbName] = p
+	}
+	createdDBsM.Unlock()
+
+	forceDropDBs(dbs)
+}
+
+// Drops databases dbs created by Pgpool values they are mapped to
+// concurrently.
+func forceDropDBs(dbs map[string]*Pgpool) {
+	var wg sync.WaitGroup
+	for dbName, p := range dbs {
+		wg.Add(1)
+		go func(dbName string, p *Pgpool) {
+			defer wg.Done()
+			if err := p.forceDropDB(dbName); err != nil {
+				log.Printf("go-test-pg: can't drop database %v: %v",
+					dbName, err)
+			}
+		}(dbName, p)
+	}
+	wg.Wait()
+}
+
+// Terminates connections to database dbName and drops it.
+func (p *Pgpool) forceDropDB(dbName string) error {
+	if p.Backend == nil {
+		err := p.withMasterConnection(
+			func(ctx context.Context, conn *pgx.Conn) error {
+				return terminateConnections(ctx, conn, dbName)
+			})
+		if err != nil {
+			return err
+		}
+	}
+	return p.dropDB(dbName)
+}
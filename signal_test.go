@@ -0,0 +1,33 @@
+package go_test_pg
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestForceDropDBs(t *testing.T) {
+	b := &fakeBackend{}
+	p := &Pgpool{Backend: b, rnd: rand.New(rand.NewSource(1))}
+
+	dbName, err := p.newDB("tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	createdDBsM.Lock()
+	registered := createdDBs[dbName] == p
+	createdDBsM.Unlock()
+	if !registered {
+		t.Fatalf("database %v is not registered", dbName)
+	}
+
+	forceDropDBs(map[string]*Pgpool{dbName: p})
+	if len(b.dropped) != 1 || b.dropped[0] != dbName {
+		t.Fatalf("unexpected dropped databases: %v", b.dropped)
+	}
+	createdDBsM.Lock()
+	_, registered = createdDBs[dbName]
+	createdDBsM.Unlock()
+	if registered {
+		t.Fatalf("dropped database %v is registered", dbName)
+	}
+}
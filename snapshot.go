@@ -0,0 +1,223 @@
+package go_test_pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DBSnapshot is a copy of the test database state created by Snapshot.
+type DBSnapshot struct {
+	p        *Pgpool
+	pool     *pgxpool.Pool
+	dbName   string
+	snapName string
+}
+
+// Snapshot creates a copy of the database of pool using it as a template,
+// so the state may be restored later with Restore. pool must be created by
+// p. Connections of pool must be released: they are closed, as PostgreSQL
+// does not allow to clone a database with active connections. The copy is
+// created with Backend and dropped when the test finishes. Databases
+// created by schema replay can't be copied.
+func (p *Pgpool) Snapshot(t testing.TB, pool *pgxpool.Pool) *DBSnapshot {
+	t.Helper()
+
+	s := &DBSnapshot{
+		p:        p,
+		pool:     pool,
+		dbName:   pool.Config().ConnConfig.Database,
+		snapName: fmt.Sprintf("%v_snap_%x", p.baseName(), randomUint64()),
+	}
+
+	_, err := p.prepareCopy(pool, s.dbName)
+	if err == nil {
+		err = p.copyDB(s.snapName, s.dbName)
+	}
+	if err != nil {
+		t.Fatalf("can't create snapshot of %v: %v", s.dbName, err)
+	}
+
+	t.Cleanup(func() {
+		if err := p.dropDB(s.snapName); err != nil {
+			t.Errorf("can't drop snapshot %v: %v", s.snapName, err)
+		}
+	})
+	return s
+}
+
+// Restore brings the database of pool back to the state of the snapshot.
+// The database is recreated, so connections of pool must be released.
+// Its settings and ConnectionLimit are kept. Restore may be called many
+// times.
+func (s *DBSnapshot) Restore(t testing.TB) {
+	t.Helper()
+
+	settings, err := s.p.prepareCopy(s.pool, s.dbName)
+	if err == nil {
+		err = s.p.dropDB(s.dbName)
+	}
+	if err == nil {
+		err = s.p.copyDB(s.dbName, s.snapName)
+	}
+	if err == nil {
+		err = s.p.restoreDatabaseSettings(s.dbName, settings)
+	}
+	if err != nil {
+		t.Fatalf("can't restore snapshot of %v: %v", s.dbName, err)
+	}
+}
+
+// CloneDB creates a new test database using the database of pool as a
+// template and returns a pool of connections to it and its name. pool must
+// be created by p. Database settings are copied, and the clone is
+// configured and released like other test databases of p. Connections of
+// pool must be released: they are closed, as PostgreSQL does not allow to
+// clone a database with active connections.
+func (p *Pgpool) CloneDB(t testing.TB,
+	pool *pgxpool.Pool) (*pgxpool.Pool, string) {
+
+	t.Helper()
+
+	srcName := pool.Config().ConnConfig.Database
+	settings, err := p.prepareCopy(pool, srcName)
+	if err != nil {
+		t.Fatalf("can't clone database %v: %v", srcName, err)
+	}
+
+	dbName, err := p.newNamedTestDB(t,
+		fmt.Sprintf("%v_clone_%x", p.baseName(), randomUint64()), srcName)
+	if err != nil {
+		t.Fatalf("can't clone database %v: %v", srcName, err)
+	}
+	if err = p.restoreDatabaseSettings(dbName, settings); err != nil {
+		_ = p.dropTestDB(dbName)
+		t.Fatalf("can't copy settings of database %v: %v", srcName, err)
+	}
+	p.captureServerLog(t, dbName)
+
+	clone := p.openTestPool(t, dbName)
+	cleanupFn := p.poolCleanup(t, clone, dbName, p.dropTestDB)
+	t.Cleanup(func() {
+		if err := cleanupFn(); err != nil {
+			t.Error(err)
+		}
+	})
+	return clone, dbName
+}
+
+// Prepares database dbName of pool to be copied: closes connections of
+// pool and terminates other connections to the database. Returns settings
+// of the database, as they are not copied with it.
+func (p *Pgpool) prepareCopy(pool *pgxpool.Pool,
+	dbName string) (map[string]string, error) {
+
+	p.m.RLock()
+	replay := p.replay || p.CloneStrategy == CloneReplay || p.Yugabyte
+	p.m.RUnlock()
+	if replay && p.Backend == nil {
+		return nil, errors.New("databases created by schema replay " +
+			"can't be copied")
+	}
+
+	if n := pool.Stat().AcquiredConns(); n > 0 {
+		return nil, fmt.Errorf("%w: %v", ErrUnreleasedConnections, n)
+	}
+	pool.Reset()
+
+	var settings map[string]string
+	err := p.withMasterConnection(
+		func(ctx context.Context, conn *pgx.Conn) error {
+			var err error
+			settings, err = databaseSettingsOf(ctx, conn, dbName)
+			if err != nil {
+				return err
+			}
+			return terminateConnections(ctx, conn, dbName)
+		})
+	return settings, err
+}
+
+// Creates database to using database from as a template with Backend.
+func (p *Pgpool) copyDB(to, from string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout())
+	defer cancel()
+
+	err := p.backend().CreateFromTemplate(ctx, to, from)
+	if err != nil {
+		return err
+	}
+	registerCreatedDB(to, p)
+	return nil
+}
+
+// Sets settings as defaults of database dbName and applies
+// ConnectionLimit, as they are not copied with the database.
+func (p *Pgpool) restoreDatabaseSettings(dbName string,
+	settings map[string]string) error {
+
+	sqls, err := alterDatabaseSQLs(dbName, settings)
+	if err != nil {
+		return err
+	}
+	if p.ConnectionLimit > 0 {
+		sqls = append(sqls, connectionLimitSQL(dbName, p.ConnectionLimit))
+	}
+	if len(sqls) == 0 {
+		return nil
+	}
+
+	return p.withNewConnection(
+		"",
+		func(ctx context.Context, conn *pgx.Conn) error {
+			for _, s := range sqls {
+				if _, err := conn.Exec(ctx, s); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	)
+}
+
+// Terminates all other connections to database dbName.
+func terminateConnections(ctx context.Context, conn *pgx.Conn,
+	dbName string) error {
+
+	_, err := conn.Exec(ctx, `
+SELECT pg_terminate_backend(pid)
+FROM pg_stat_activity
+WHERE datname = $1 AND pid <> pg_backend_pid()`, dbName)
+	return err
+}
+
+// Returns settings stored with ALTER DATABASE ... SET for database dbName.
+// They are not copied when the database is used as a template.
+func databaseSettingsOf(ctx context.Context, conn *pgx.Conn,
+	dbName string) (map[string]string, error) {
+
+	rows, err := conn.Query(ctx, `
+SELECT split_part(c, '=', 1), substr(c, strpos(c, '=') + 1)
+FROM pg_db_role_setting s, unnest(s.setconfig) c
+WHERE s.setrole = 0
+	AND s.setdatabase = (SELECT oid FROM pg_database WHERE datname = $1)`,
+		dbName)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := make(map[string]string)
+	var name, value string
+	_, err = pgx.ForEachRow(rows, []any{&name, &value}, func() error {
+		settings[name] = value
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
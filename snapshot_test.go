@@ -0,0 +1,83 @@
+package go_test_pg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestSnapshot(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:         "go_test_pg",
+		SchemaFile:       "./testdata/schema1.sql",
+		DatabaseSettings: map[string]string{"work_mem": "8MB"},
+		ConnectionLimit:  10,
+	}
+	pool := dbPool.WithSQLs(t, []string{
+		`INSERT INTO table1 (name) VALUES ('a'), ('b')`,
+	})
+	ctx := context.Background()
+
+	snap := dbPool.Snapshot(t, pool)
+
+	for i := 0; i < 2; i++ {
+		_, err := pool.Exec(ctx, `DELETE FROM table1 WHERE name = 'a'`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		AssertRowCount(t, pool, "table1", 1)
+
+		snap.Restore(t)
+
+		AssertRowCount(t, pool, "table1", 2)
+		var workMem string
+		err = pool.QueryRow(ctx, `SHOW work_mem`).Scan(&workMem)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if workMem != "8MB" {
+			t.Fatalf("database settings are lost: work_mem = %v", workMem)
+		}
+		assertConnectionLimit(t, pool, 10)
+	}
+}
+
+func TestCloneDB(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:        "go_test_pg",
+		SchemaFile:      "./testdata/schema1.sql",
+		ConnectionLimit: 10,
+	}
+	pool := dbPool.WithSQLs(t, []string{
+		`INSERT INTO table1 (name) VALUES ('a'), ('b')`,
+	})
+
+	clone, dbName := dbPool.CloneDB(t, pool)
+	if got := clone.Config().ConnConfig.Database; got != dbName {
+		t.Fatalf("want clone pool connected to %v, got %v", dbName, got)
+	}
+
+	_, err := clone.Exec(context.Background(), `DELETE FROM table1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertRowCount(t, clone, "table1", 0)
+	AssertRowCount(t, pool, "table1", 2)
+	assertConnectionLimit(t, clone, 10)
+}
+
+func assertConnectionLimit(t testing.TB, pool *pgxpool.Pool, want int) {
+	t.Helper()
+
+	var limit int
+	err := pool.QueryRow(context.Background(), `
+SELECT datconnlimit FROM pg_database WHERE datname = current_database()`).
+		Scan(&limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limit != want {
+		t.Fatalf("want connection limit %v, got %v", want, limit)
+	}
+}
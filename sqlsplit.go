@@ -0,0 +1,181 @@
+package go_test_pg
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+)
+
+// Minimum size of a chunk of schema file sent to the server in one Exec.
+const schemaChunkSize = 1 << 20
+
+// States of sqlChunker.
+const (
+	sqlNormal = iota
+	sqlSingleQuote
+	sqlDoubleQuote
+	sqlLineComment
+	sqlBlockComment
+	sqlDollarQuote
+)
+
+// sqlChunker reads SQL script and splits it into chunks of whole
+// statements. It understands quoted strings and identifiers, dollar
+// quoting and comments, so semicolons inside them do not end a statement.
+type sqlChunker struct {
+	r   *bufio.Reader
+	buf bytes.Buffer
+
+	state int
+	prev  byte
+	// Nesting level of block comments.
+	depth int
+	// Closing tag of dollar quoted string and its position in buf.
+	tag      []byte
+	tagStart int
+	// String supports backslash escapes (E'...').
+	backslash bool
+	escaped   bool
+
+	// Number of statements ending with semicolon read so far.
+	statements int
+}
+
+func newSQLChunker(r io.Reader) *sqlChunker {
+	return &sqlChunker{r: bufio.NewReader(r)}
+}
+
+// Returns the next chunk of at least minSize bytes (unless it is the last
+// one) ending with a whole statement. Returns io.EOF when the script is
+// over.
+func (c *sqlChunker) next(minSize int) (string, error) {
+	for {
+		b, err := c.r.ReadByte()
+		if err == io.EOF {
+			chunk := c.buf.String()
+			c.buf.Reset()
+			if strings.TrimSpace(chunk) == "" {
+				return "", io.EOF
+			}
+			return chunk, nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		c.buf.WriteByte(b)
+		end := c.step(b)
+		if end {
+			c.statements++
+		}
+		if end && c.buf.Len() >= minSize {
+			chunk := c.buf.String()
+			c.buf.Reset()
+			return chunk, nil
+		}
+	}
+}
+
+// Advances the state with the next byte of the script. Returns true if b
+// ends a statement.
+func (c *sqlChunker) step(b byte) bool {
+	prev := c.prev
+	c.prev = b
+
+	switch c.state {
+	case sqlNormal:
+		switch {
+		case b == ';':
+			return true
+		case b == '\'':
+			c.state = sqlSingleQuote
+			c.backslash = prev == 'E' || prev == 'e'
+		case b == '"':
+			c.state = sqlDoubleQuote
+		case b == '-' && prev == '-':
+			c.state = sqlLineComment
+		case b == '*' && prev == '/':
+			c.state = sqlBlockComment
+			c.depth = 1
+			// "/*/" does not close the comment
+			c.prev = 0
+		case b == '$' && !isIdentByte(prev):
+			if tag := c.dollarTag(); tag != nil {
+				// Consume the rest of opening tag.
+				_, _ = c.r.Discard(len(tag) - 1)
+				c.buf.Write(tag[1:])
+				c.tag = tag
+				c.tagStart = c.buf.Len()
+				c.state = sqlDollarQuote
+				c.prev = 0
+			}
+		}
+	case sqlSingleQuote:
+		switch {
+		case c.escaped:
+			c.escaped = false
+		case c.backslash && b == '\\':
+			c.escaped = true
+		case b == '\'':
+			// Doubled quote reenters the string with the next byte.
+			c.state = sqlNormal
+		}
+	case sqlDoubleQuote:
+		if b == '"' {
+			c.state = sqlNormal
+		}
+	case sqlLineComment:
+		if b == '\n' {
+			c.state = sqlNormal
+		}
+	case sqlBlockComment:
+		switch {
+		case b == '*' && prev == '/':
+			c.depth++
+			c.prev = 0
+		case b == '/' && prev == '*':
+			c.depth--
+			c.prev = 0
+			if c.depth == 0 {
+				c.state = sqlNormal
+			}
+		}
+	case sqlDollarQuote:
+		if b == '$' && c.buf.Len()-c.tagStart >= len(c.tag) &&
+			bytes.HasSuffix(c.buf.Bytes(), c.tag) {
+
+			c.state = sqlNormal
+			c.prev = 0
+		}
+	}
+	return false
+}
+
+// Returns opening tag of dollar quoted string, like "$$" or "$body$", if
+// the last read "$" starts one, or nil otherwise.
+func (c *sqlChunker) dollarTag() []byte {
+	for n := 1; ; n++ {
+		p, _ := c.r.Peek(n)
+		if len(p) < n {
+			return nil
+		}
+		ch := p[n-1]
+		if ch == '$' {
+			if n > 1 && p[0] >= '0' && p[0] <= '9' {
+				// positional parameter like $1
+				return nil
+			}
+			return append([]byte{'$'}, p...)
+		}
+		if !isIdentByte(ch) {
+			return nil
+		}
+	}
+}
+
+func isIdentByte(b byte) bool {
+	return b == '_' || b >= 0x80 ||
+		(b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') ||
+		(b >= '0' && b <= '9')
+}
@@ -0,0 +1,90 @@
+package go_test_pg
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func chunks(t *testing.T, script string, minSize int) []string {
+	c := newSQLChunker(strings.NewReader(script))
+	var result []string
+	for {
+		chunk, err := c.next(minSize)
+		if err == io.EOF {
+			return result
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, chunk)
+	}
+}
+
+func TestSQLChunker(t *testing.T) {
+	testCases := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{
+			name:   "statements",
+			script: "SELECT 1; SELECT 2;\n",
+			// trailing whitespace is not a chunk
+			want: []string{"SELECT 1;", " SELECT 2;"},
+		},
+		{
+			name:   "quotes",
+			script: `SELECT 'a;''b', "c;""d", E'\';'; SELECT 2`,
+			want: []string{`SELECT 'a;''b', "c;""d", E'\';';`,
+				" SELECT 2"},
+		},
+		{
+			name:   "comments",
+			script: "SELECT 1 -- a;\n; /* b; /* c; */ d;*/ SELECT 2;",
+			want: []string{"SELECT 1 -- a;\n;",
+				" /* b; /* c; */ d;*/ SELECT 2;"},
+		},
+		{
+			name: "dollar quotes",
+			script: "CREATE FUNCTION f() AS $body$ SELECT $$;$$; " +
+				"$body$; SELECT $1; SELECT a$b;",
+			want: []string{
+				"CREATE FUNCTION f() AS $body$ SELECT $$;$$; $body$;",
+				" SELECT $1;", " SELECT a$b;"},
+		},
+		{
+			name:   "empty dollar quote",
+			script: "SELECT $$$$; SELECT 2;",
+			want:   []string{"SELECT $$$$;", " SELECT 2;"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := chunks(t, tc.script, 0)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSQLChunker_minSize(t *testing.T) {
+	got := chunks(t, "SELECT 1; SELECT 2; SELECT 3;", 12)
+	want := []string{"SELECT 1; SELECT 2;", " SELECT 3;"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+}
+
+func TestSQLChunker_statements(t *testing.T) {
+	c := newSQLChunker(strings.NewReader(
+		"SELECT ';'; -- ;\nSELECT 2; SELECT $$;$$;"))
+	if _, err := c.next(1 << 20); err != nil {
+		t.Fatal(err)
+	}
+	if c.statements != 3 {
+		t.Fatalf("want 3 statements, got %v", c.statements)
+	}
+}
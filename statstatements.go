@@ -0,0 +1,135 @@
+package go_test_pg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Number of statements logged on test cleanup.
+const statStatementsLogTop = 10
+
+// StatStatement is statistics of a single statement from
+// pg_stat_statements.
+type StatStatement struct {
+	Query     string
+	Calls     int64
+	Rows      int64
+	TotalTime time.Duration
+	MeanTime  time.Duration
+}
+
+// StatOrder defines the order of statements returned by
+// StatStatements.Top.
+type StatOrder int
+
+const (
+	// ByTotalTime orders statements by total execution time.
+	ByTotalTime StatOrder = iota
+	// ByCalls orders statements by number of calls.
+	ByCalls
+)
+
+// StatStatements gives access to pg_stat_statements statistics of the test
+// database.
+type StatStatements struct {
+	pool *pgxpool.Pool
+}
+
+// CaptureStatStatements creates pg_stat_statements extension in the test
+// database if needed and resets its statistics for the database. On test
+// cleanup the top statements by total time are logged. The extension must
+// be in shared_preload_libraries of the server. Requires PostgreSQL 13+.
+func CaptureStatStatements(t testing.TB,
+	pool *pgxpool.Pool) *StatStatements {
+
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	_, err := pool.Exec(ctx,
+		`CREATE EXTENSION IF NOT EXISTS pg_stat_statements`)
+	if err != nil {
+		t.Fatalf("can't create pg_stat_statements extension: %v", err)
+	}
+
+	_, err = pool.Exec(ctx, `
+SELECT pg_stat_statements_reset(0,
+	(SELECT oid FROM pg_database WHERE datname = current_database()), 0)`)
+	if err != nil {
+		t.Fatalf("can't reset pg_stat_statements: %v", err)
+	}
+
+	s := &StatStatements{pool: pool}
+	t.Cleanup(func() {
+		stmts, err := s.top(statStatementsLogTop, ByTotalTime)
+		if err != nil {
+			t.Errorf("can't get pg_stat_statements: %v", err)
+			return
+		}
+		for _, st := range stmts {
+			t.Logf("calls: %v, total: %v, mean: %v, rows: %v: %v",
+				st.Calls, st.TotalTime, st.MeanTime, st.Rows, st.Query)
+		}
+	})
+	return s
+}
+
+// Top returns top n statements executed in the test database since
+// CaptureStatStatements call, ordered by order. Statements querying
+// pg_stat_statements itself are skipped.
+func (s *StatStatements) Top(t testing.TB, n int,
+	order StatOrder) []StatStatement {
+
+	t.Helper()
+
+	stmts, err := s.top(n, order)
+	if err != nil {
+		t.Fatalf("can't get pg_stat_statements: %v", err)
+	}
+	return stmts
+}
+
+func (s *StatStatements) top(n int, order StatOrder) ([]StatStatement,
+	error) {
+
+	orderBy := `total_exec_time DESC`
+	if order == ByCalls {
+		orderBy = `calls DESC`
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, `
+SELECT query, calls, rows,
+	(total_exec_time * 1000)::bigint, (mean_exec_time * 1000)::bigint
+FROM pg_stat_statements
+WHERE dbid = (SELECT oid FROM pg_database WHERE datname = current_database())
+	AND query NOT LIKE '%pg_stat_statements%'
+ORDER BY `+orderBy+`
+LIMIT $1`, n)
+	if err != nil {
+		return nil, err
+	}
+
+	var stmts []StatStatement
+	var st StatStatement
+	var totalUs, meanUs int64
+	_, err = pgx.ForEachRow(rows,
+		[]any{&st.Query, &st.Calls, &st.Rows, &totalUs, &meanUs},
+		func() error {
+			st.TotalTime = time.Duration(totalUs) * time.Microsecond
+			st.MeanTime = time.Duration(meanUs) * time.Microsecond
+			stmts = append(stmts, st)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return stmts, nil
+}
@@ -0,0 +1,65 @@
+package go_test_pg
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCaptureStatStatements(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+	}
+	pool := dbPool.WithSQLs(t, []string{
+		`INSERT INTO table1 (name) VALUES ('a'), ('b')`,
+	})
+	ctx := context.Background()
+
+	var libraries string
+	err := pool.QueryRow(ctx, `SHOW shared_preload_libraries`).
+		Scan(&libraries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(libraries, "pg_stat_statements") {
+		t.Skip("pg_stat_statements is not in shared_preload_libraries")
+	}
+
+	stats := CaptureStatStatements(t, pool)
+
+	for i := 0; i < 5; i++ {
+		rows, err := pool.Query(ctx, `SELECT name FROM table1`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = pool.Exec(ctx, `SELECT pg_sleep(0.1)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	top := stats.Top(t, 1, ByCalls)
+	if len(top) != 1 {
+		t.Fatalf("want 1 statement, got %+v", top)
+	}
+	if top[0].Query != `SELECT name FROM table1` || top[0].Calls != 5 ||
+		top[0].Rows != 10 {
+
+		t.Fatalf("unexpected top statement by calls: %+v", top[0])
+	}
+
+	top = stats.Top(t, 2, ByTotalTime)
+	if len(top) != 2 {
+		t.Fatalf("want 2 statements, got %+v", top)
+	}
+	if !strings.Contains(top[0].Query, "pg_sleep") || top[0].Calls != 1 ||
+		top[0].TotalTime < top[1].TotalTime {
+
+		t.Fatalf("unexpected top statements by total time: %+v", top)
+	}
+}
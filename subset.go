@@ -0,0 +1,481 @@
+package go_test_pg
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Number of rows in one INSERT statement written by SubsetDatabase.
+const subsetInsertRows = 100
+
+// SubsetRoot selects rows of a table to start a subset with.
+type SubsetRoot struct {
+	// Table is the name of the table, e.g. public.orders.
+	Table string
+	// Where limits selected rows, e.g. "created_at > '2024-01-01'". If
+	// empty, all rows are selected.
+	Where string
+	// Limit is the maximum number of selected rows. Zero means no limit.
+	Limit int
+}
+
+// SubsetDatabase extracts a small referentially consistent dataset from
+// the database dsn and writes it to w as SQL with INSERT statements, e.g.
+// to create a fixture file from production-like data. Rows selected by
+// roots are written together with all rows they reference by foreign keys,
+// recursively. Rows referencing selected rows are not included. Tables are
+// written so referenced rows are inserted first, tables referencing each
+// other in a cycle need deferrable constraints. Sequences of serial and
+// identity columns are moved past inserted values.
+func SubsetDatabase(ctx context.Context, dsn string, w io.Writer,
+	roots []SubsetRoot) error {
+
+	conn, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	// Read a consistent snapshot of the source.
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{
+		IsoLevel:   pgx.RepeatableRead,
+		AccessMode: pgx.ReadOnly,
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(context.Background()) }()
+
+	s := &subsetter{conn: tx.Conn(), tables: map[uint32]*subsetTable{}}
+	if err = s.loadForeignKeys(ctx); err != nil {
+		return err
+	}
+	for _, root := range roots {
+		if err = s.selectRoot(ctx, root); err != nil {
+			return fmt.Errorf("can't select rows of %v: %w", root.Table, err)
+		}
+	}
+	for len(s.queue) != 0 {
+		tbl := s.queue[0]
+		s.queue = s.queue[1:]
+		if err = s.selectParents(ctx, tbl); err != nil {
+			return fmt.Errorf("can't select rows referenced by %v: %w",
+				tbl.name, err)
+		}
+	}
+
+	return s.write(ctx, w)
+}
+
+// Foreign key of a table.
+type subsetForeignKey struct {
+	parent        uint32
+	columns       []string
+	parentColumns []string
+	// Types of parentColumns.
+	parentTypes []string
+}
+
+// Table of a subset with selected rows.
+type subsetTable struct {
+	oid     uint32
+	name    string
+	columns []string
+	// True if the table has GENERATED ALWAYS identity columns, which
+	// need OVERRIDING SYSTEM VALUE on insert.
+	identityAlways bool
+	// Selected rows in order of selection, values are in text format.
+	rows [][]*string
+	// Keys of selected rows.
+	selected map[string]bool
+	// Number of rows that were checked for referenced rows.
+	walked int
+}
+
+type subsetter struct {
+	conn        *pgx.Conn
+	tables      map[uint32]*subsetTable
+	foreignKeys map[uint32][]subsetForeignKey
+	// Tables with rows to check for referenced rows.
+	queue []*subsetTable
+}
+
+func (s *subsetter) loadForeignKeys(ctx context.Context) error {
+	rows, err := s.conn.Query(ctx, `
+SELECT c.conrelid::oid, c.confrelid::oid,
+	array(SELECT attname FROM unnest(c.conkey) WITH ORDINALITY k(n, i)
+		JOIN pg_attribute ON attrelid = c.conrelid AND attnum = k.n
+		ORDER BY k.i),
+	array(SELECT attname FROM unnest(c.confkey) WITH ORDINALITY k(n, i)
+		JOIN pg_attribute ON attrelid = c.confrelid AND attnum = k.n
+		ORDER BY k.i),
+	array(SELECT format_type(atttypid, atttypmod)
+		FROM unnest(c.confkey) WITH ORDINALITY k(n, i)
+		JOIN pg_attribute ON attrelid = c.confrelid AND attnum = k.n
+		ORDER BY k.i)
+FROM pg_constraint c
+WHERE c.contype = 'f'
+ORDER BY c.conname`)
+	if err != nil {
+		return err
+	}
+
+	s.foreignKeys = make(map[uint32][]subsetForeignKey)
+	var child uint32
+	var fk subsetForeignKey
+	_, err = pgx.ForEachRow(rows,
+		[]any{&child, &fk.parent, &fk.columns, &fk.parentColumns,
+			&fk.parentTypes},
+		func() error {
+			s.foreignKeys[child] = append(s.foreignKeys[child], fk)
+			return nil
+		})
+	return err
+}
+
+// Returns table by its OID, loading its name and columns on first use.
+func (s *subsetter) table(ctx context.Context,
+	oid uint32) (*subsetTable, error) {
+
+	if tbl, ok := s.tables[oid]; ok {
+		return tbl, nil
+	}
+
+	tbl := &subsetTable{oid: oid, selected: map[string]bool{}}
+	err := s.conn.QueryRow(ctx, `
+SELECT format('%I.%I', n.nspname, c.relname),
+	array(SELECT attname FROM pg_attribute
+		WHERE attrelid = c.oid AND attnum > 0 AND NOT attisdropped
+			AND attgenerated = ''
+		ORDER BY attnum),
+	EXISTS(SELECT 1 FROM pg_attribute
+		WHERE attrelid = c.oid AND attnum > 0 AND NOT attisdropped
+			AND attidentity = 'a')
+FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE c.oid = $1`, oid).Scan(&tbl.name, &tbl.columns, &tbl.identityAlways)
+	if err != nil {
+		return nil, err
+	}
+	s.tables[oid] = tbl
+	return tbl, nil
+}
+
+// Returns select list of columns of tbl in text format.
+func (tbl *subsetTable) selectList(alias string) string {
+	list := make([]string, 0, len(tbl.columns))
+	for _, column := range tbl.columns {
+		list = append(list, alias+"."+quote(column)+"::text")
+	}
+	return strings.Join(list, ", ")
+}
+
+func (s *subsetter) selectRoot(ctx context.Context, root SubsetRoot) error {
+	var oid uint32
+	err := s.conn.QueryRow(ctx, `SELECT $1::regclass::oid`, root.Table).
+		Scan(&oid)
+	if err != nil {
+		return err
+	}
+	tbl, err := s.table(ctx, oid)
+	if err != nil {
+		return err
+	}
+
+	query := `SELECT ` + tbl.selectList("t") + ` FROM ` + tbl.name + ` t`
+	if root.Where != "" {
+		query += ` WHERE ` + root.Where
+	}
+	if root.Limit > 0 {
+		query += fmt.Sprintf(` LIMIT %d`, root.Limit)
+	}
+	return s.addRows(ctx, tbl, query)
+}
+
+// Adds rows returned by query to tbl and queues it if new rows are found.
+func (s *subsetter) addRows(ctx context.Context, tbl *subsetTable,
+	query string, args ...any) error {
+
+	rows, err := s.conn.Query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	values := make([]*string, len(tbl.columns))
+	dest := make([]any, len(values))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	var added bool
+	_, err = pgx.ForEachRow(rows, dest, func() error {
+		key := rowKey(values)
+		if tbl.selected[key] {
+			return nil
+		}
+		tbl.selected[key] = true
+		tbl.rows = append(tbl.rows, append([]*string(nil), values...))
+		added = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if added {
+		s.queue = append(s.queue, tbl)
+	}
+	return nil
+}
+
+// Returns identity of a row made of its values.
+func rowKey(values []*string) string {
+	var b strings.Builder
+	for _, v := range values {
+		if v == nil {
+			b.WriteString("\x00n")
+			continue
+		}
+		b.WriteString("\x00v")
+		b.WriteString(strings.ReplaceAll(*v, "\x00", "\x00\x00"))
+	}
+	return b.String()
+}
+
+// Selects rows referenced by rows of tbl not walked yet.
+func (s *subsetter) selectParents(ctx context.Context,
+	tbl *subsetTable) error {
+
+	rows := tbl.rows[tbl.walked:]
+	tbl.walked = len(tbl.rows)
+
+	for _, fk := range s.foreignKeys[tbl.oid] {
+		parent, err := s.table(ctx, fk.parent)
+		if err != nil {
+			return err
+		}
+
+		// Values of foreign key columns by column, rows with NULL in any
+		// column do not reference anything.
+		idx := columnIndexes(tbl.columns, fk.columns)
+		if len(idx) != len(fk.columns) {
+			// Generated columns are not selected.
+			continue
+		}
+		keys := make([][]string, len(idx))
+		for _, row := range rows {
+			if !hasValues(row, idx) {
+				continue
+			}
+			for i, col := range idx {
+				keys[i] = append(keys[i], *row[col])
+			}
+		}
+		if len(keys[0]) == 0 {
+			continue
+		}
+
+		// Keys are converted to column types, so indexes are used.
+		var cond, unnest []string
+		args := make([]any, len(keys))
+		for i, column := range fk.parentColumns {
+			cond = append(cond, "p."+quote(column))
+			unnest = append(unnest, fmt.Sprintf("$%d::text[]::%v[]", i+1,
+				fk.parentTypes[i]))
+			args[i] = keys[i]
+		}
+		query := `SELECT ` + parent.selectList("p") + ` FROM ` +
+			parent.name + ` p WHERE (` + strings.Join(cond, ", ") +
+			`) IN (SELECT * FROM unnest(` + strings.Join(unnest, ", ") +
+			`))`
+		if err = s.addRows(ctx, parent, query, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns indexes of names in columns.
+func columnIndexes(columns, names []string) []int {
+	idx := make([]int, 0, len(names))
+	for _, name := range names {
+		for i, column := range columns {
+			if column == name {
+				idx = append(idx, i)
+				break
+			}
+		}
+	}
+	return idx
+}
+
+func hasValues(row []*string, idx []int) bool {
+	for _, i := range idx {
+		if row[i] == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Writes INSERT statements of selected rows and sequence updates.
+func (s *subsetter) write(ctx context.Context, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, tbl := range s.order() {
+		// Rows referenced from the same table are selected after rows
+		// referencing them, so rows are inserted in reverse order.
+		rows := make([][]*string, 0, len(tbl.rows))
+		for i := len(tbl.rows) - 1; i >= 0; i-- {
+			rows = append(rows, tbl.rows[i])
+		}
+		for len(rows) != 0 {
+			n := len(rows)
+			if n > subsetInsertRows {
+				n = subsetInsertRows
+			}
+			_, _ = bw.WriteString(insertStatement(tbl.name, tbl.columns,
+				tbl.identityAlways, rows[:n]))
+			rows = rows[n:]
+		}
+
+		sequences, err := s.sequences(ctx, tbl)
+		if err != nil {
+			return err
+		}
+		for _, seq := range sequences {
+			fmt.Fprintf(bw, "SELECT setval(%v, max(%v)) FROM %v;\n",
+				quoteLiteral(seq[1]), quote(seq[0]), tbl.name)
+		}
+	}
+	return bw.Flush()
+}
+
+// Returns pairs of column names and names of sequences owned by them.
+func (s *subsetter) sequences(ctx context.Context,
+	tbl *subsetTable) ([][2]string, error) {
+
+	rows, err := s.conn.Query(ctx, `
+SELECT attname, pg_get_serial_sequence($1, attname)
+FROM pg_attribute
+WHERE attrelid = $2
+	AND attnum > 0
+	AND NOT attisdropped
+	AND pg_get_serial_sequence($1, attname) IS NOT NULL
+ORDER BY attnum`, tbl.name, tbl.oid)
+	if err != nil {
+		return nil, err
+	}
+	var column, sequence string
+	var sequences [][2]string
+	_, err = pgx.ForEachRow(rows, []any{&column, &sequence}, func() error {
+		sequences = append(sequences, [2]string{column, sequence})
+		return nil
+	})
+	return sequences, err
+}
+
+// Returns tables with selected rows, referenced tables first. Tables in
+// foreign key cycles follow in order of names.
+func (s *subsetter) order() []*subsetTable {
+	var names []string
+	byName := make(map[string]*subsetTable)
+	for _, tbl := range s.tables {
+		if len(tbl.rows) != 0 {
+			names = append(names, tbl.name)
+			byName[tbl.name] = tbl
+		}
+	}
+	sort.Strings(names)
+
+	parents := make(map[string][]string)
+	for _, name := range names {
+		tbl := byName[name]
+		for _, fk := range s.foreignKeys[tbl.oid] {
+			parent, ok := s.tables[fk.parent]
+			if ok && parent != tbl && len(parent.rows) != 0 {
+				parents[name] = append(parents[name], parent.name)
+			}
+		}
+	}
+
+	var order []*subsetTable
+	for _, name := range parentsFirst(names, parents) {
+		order = append(order, byName[name])
+	}
+	return order
+}
+
+// Orders names so parents of every name come before it. Names in cycles
+// are appended in the original order.
+func parentsFirst(names []string, parents map[string][]string) []string {
+	done := make(map[string]bool)
+	var order []string
+	for len(order) != len(names) {
+		progress := false
+		for _, name := range names {
+			if done[name] {
+				continue
+			}
+			ready := true
+			for _, parent := range parents[name] {
+				if !done[parent] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				done[name] = true
+				order = append(order, name)
+				progress = true
+			}
+		}
+		if !progress {
+			for _, name := range names {
+				if !done[name] {
+					done[name] = true
+					order = append(order, name)
+				}
+			}
+		}
+	}
+	return order
+}
+
+// Returns INSERT statement of rows with values in text format. Values are
+// written as untyped literals, so they are converted to column types. If
+// overriding is true, values of GENERATED ALWAYS identity columns are
+// inserted with OVERRIDING SYSTEM VALUE.
+func insertStatement(table string, columns []string, overriding bool,
+	rows [][]*string) string {
+
+	quoted := make([]string, 0, len(columns))
+	for _, column := range columns {
+		quoted = append(quoted, quote(column))
+	}
+
+	var b strings.Builder
+	b.WriteString("INSERT INTO " + table + " (" +
+		strings.Join(quoted, ", ") + ")")
+	if overriding {
+		b.WriteString(" OVERRIDING SYSTEM VALUE")
+	}
+	b.WriteString(" VALUES\n")
+	for i, row := range rows {
+		values := make([]string, 0, len(row))
+		for _, v := range row {
+			if v == nil {
+				values = append(values, "NULL")
+			} else {
+				values = append(values, quoteLiteral(*v))
+			}
+		}
+		b.WriteString("\t(" + strings.Join(values, ", ") + ")")
+		if i != len(rows)-1 {
+			b.WriteString(",\n")
+		}
+	}
+	b.WriteString(";\n")
+	return b.String()
+}
@@ -0,0 +1,103 @@
+package go_test_pg
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestParentsFirst(t *testing.T) {
+	names := []string{"a", "b", "c", "d", "e"}
+	parents := map[string][]string{
+		"a": {"b"},
+		"b": {"c"},
+		"d": {"e"},
+		"e": {"d"},
+	}
+	want := []string{"c", "b", "a", "d", "e"}
+	if got := parentsFirst(names, parents); !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+}
+
+func TestInsertStatement(t *testing.T) {
+	name := "it's"
+	got := insertStatement(`public.users`, []string{"id", "Name"}, false,
+		[][]*string{{&name, nil}, {&name, &name}})
+	want := `INSERT INTO public.users ("id", "Name") VALUES
+	('it''s', NULL),
+	('it''s', 'it''s');
+`
+	if got != want {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+
+	got = insertStatement(`public.orders`, []string{"id"}, true,
+		[][]*string{{&name}})
+	want = `INSERT INTO public.orders ("id") OVERRIDING SYSTEM VALUE VALUES
+	('it''s');
+`
+	if got != want {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+}
+
+func TestRowKey(t *testing.T) {
+	a, b, empty := "a", "b", ""
+	keys := map[string]bool{}
+	for _, row := range [][]*string{
+		{&a, &b}, {&a, nil}, {&a, &empty}, {nil, &a}, {&empty, &a},
+	} {
+		keys[rowKey(row)] = true
+	}
+	if len(keys) != 5 {
+		t.Fatalf("want 5 distinct keys, got %v", len(keys))
+	}
+}
+
+func TestSubsetDatabase(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema_subset.sql",
+	}
+	source := dbPool.WithSQLs(t, []string{`
+INSERT INTO users (id, name, invited_by) VALUES
+	(1, 'alice', NULL), (2, 'bob', 1), (3, 'carol', 2), (4, 'dave', NULL);
+INSERT INTO orders (user_id, note) VALUES (3, 'first'), (4, 'second');
+SELECT setval('users_id_seq', 4);`})
+
+	var subset bytes.Buffer
+	err := SubsetDatabase(context.Background(),
+		dbPool.ConnString(t, source), &subset, []SubsetRoot{
+			{Table: "orders", Where: "note = 'first'"},
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := dbPool.WithSQLs(t, []string{subset.String()})
+	AssertRowCount(t, pool, "orders", 1)
+	AssertRowCount(t, pool, "users", 3)
+	AssertNotExists(t, pool, "users", "name = $1", "dave")
+
+	var id int
+	err = pool.QueryRow(context.Background(),
+		`INSERT INTO users (name) VALUES ('eve') RETURNING id`).Scan(&id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 4 {
+		t.Fatalf("want sequence moved past inserted rows, got id %v", id)
+	}
+
+	err = pool.QueryRow(context.Background(), `
+INSERT INTO orders (user_id, note) VALUES (1, 'third') RETURNING id`).
+		Scan(&id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 2 {
+		t.Fatalf("want identity moved past inserted rows, got id %v", id)
+	}
+}
@@ -0,0 +1,211 @@
+package go_test_pg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// TableOption modifies behavior of AssertTableEquals.
+type TableOption func(*tableOptions)
+
+type tableOptions struct {
+	ignore map[string]bool
+}
+
+// IgnoreColumns excludes columns from comparison, e.g. generated IDs and
+// timestamps.
+func IgnoreColumns(columns ...string) TableOption {
+	return func(o *tableOptions) {
+		for _, c := range columns {
+			o.ignore[c] = true
+		}
+	}
+}
+
+// AssertTableEquals fails the test if table does not contain exactly
+// expected rows. Order of rows is not significant. Every row must set all
+// columns of the table except ignored ones. JSON columns are compared with
+// decoded values, e.g. map[string]any, arrays with slices.
+func AssertTableEquals(t testing.TB, q Querier, table string,
+	expected []map[string]any, opts ...TableOption) {
+
+	t.Helper()
+	assertRows(t, q, "table "+table, `SELECT * FROM `+quoteQualified(table),
+		expected, opts)
+}
+
+// Fails the test if query does not return exactly expected rows. what
+// names the rows in messages.
+func assertRows(t testing.TB, q Querier, what, query string,
+	expected []map[string]any, opts []TableOption, args ...any) {
+
+	t.Helper()
+
+	o := tableOptions{ignore: make(map[string]bool)}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		t.Fatalf("can't query %v: %v%v", what, err, querierHint(q))
+	}
+	actual, err := pgx.CollectRows(rows, pgx.RowToMap)
+	if err != nil {
+		t.Fatalf("can't query %v: %v%v", what, err, querierHint(q))
+	}
+
+	if diff := diffRows(expected, actual, o.ignore); diff != "" {
+		t.Fatalf("%v differs from expected:\n%v%v", what, diff,
+			querierHint(q))
+	}
+}
+
+// Returns human-readable difference between expected and actual rows or
+// empty string if they are equal as multisets.
+func diffRows(expected, actual []map[string]any,
+	ignore map[string]bool) string {
+
+	expectedRows := canonicalRows(expected, ignore)
+	actualRows := canonicalRows(actual, ignore)
+
+	counts := make(map[string]int)
+	for _, r := range actualRows {
+		counts[r]++
+	}
+
+	var b strings.Builder
+	for _, r := range expectedRows {
+		if counts[r] > 0 {
+			counts[r]--
+			continue
+		}
+		b.WriteString("- ")
+		b.WriteString(r)
+		b.WriteString("\n")
+	}
+	for _, r := range actualRows {
+		if counts[r] > 0 {
+			counts[r]--
+			b.WriteString("+ ")
+			b.WriteString(r)
+			b.WriteString("\n")
+		}
+	}
+
+	if b.Len() == 0 {
+		return ""
+	}
+	return fmt.Sprintf("(- missing rows, + unexpected rows)\n%v"+
+		"expected %v rows, got %v", b.String(), len(expected), len(actual))
+}
+
+// Returns rows as sorted canonical JSON strings.
+func canonicalRows(rows []map[string]any, ignore map[string]bool) []string {
+	result := make([]string, 0, len(rows))
+	for _, row := range rows {
+		r := make(map[string]any, len(row))
+		for k, v := range row {
+			if !ignore[k] {
+				r[k] = canonicalValue(v)
+			}
+		}
+		// map keys are sorted by encoding/json
+		buf, err := json.Marshal(r)
+		if err != nil {
+			buf = []byte(fmt.Sprint(r))
+		}
+		result = append(result, string(buf))
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Converts values returned by pgx and values from expected rows to the
+// same representation.
+func canonicalValue(v any) any {
+	switch v := v.(type) {
+	case nil, bool, string, float64:
+		return v
+	case int:
+		return float64(v)
+	case int8:
+		return float64(v)
+	case int16:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint:
+		return float64(v)
+	case uint8:
+		return float64(v)
+	case uint16:
+		return float64(v)
+	case uint32:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case json.Number:
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	case pgtype.Numeric:
+		f, err := v.Float64Value()
+		if err != nil || !f.Valid {
+			return nil
+		}
+		return f.Float64
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case [16]byte:
+		return formatValue(v)
+	case []byte:
+		return formatValue(v)
+	case netip.Prefix:
+		return v.String()
+	case netip.Addr:
+		return v.String()
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, e := range v {
+			m[k] = canonicalValue(e)
+		}
+		return m
+	case []any:
+		s := make([]any, len(v))
+		for i, e := range v {
+			s[i] = canonicalValue(e)
+		}
+		return s
+	case fmt.Stringer:
+		return v.String()
+	}
+
+	// Slices of other types, e.g. []int or []string in expected rows.
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	var decoded any
+	if err = json.Unmarshal(buf, &decoded); err != nil {
+		return fmt.Sprint(v)
+	}
+	return canonicalValue(decoded)
+}
@@ -0,0 +1,68 @@
+package go_test_pg
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiffRows(t *testing.T) {
+	createdAt := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	actual := []map[string]any{
+		{
+			"id":         int32(1),
+			"tags":       []any{"a", "b"},
+			"meta":       map[string]any{"k": float64(1)},
+			"created_at": createdAt,
+		},
+		{
+			"id":         int32(2),
+			"tags":       []any{},
+			"meta":       nil,
+			"created_at": createdAt,
+		},
+	}
+
+	expected := []map[string]any{
+		{"id": 2, "tags": []string{}, "meta": nil},
+		{
+			"id":   1,
+			"tags": []string{"a", "b"},
+			"meta": map[string]any{"k": 1},
+		},
+	}
+	ignore := map[string]bool{"created_at": true}
+	if diff := diffRows(expected, actual, ignore); diff != "" {
+		t.Fatalf("unexpected diff:\n%v", diff)
+	}
+
+	expected[0]["id"] = 3
+	diff := diffRows(expected, actual, ignore)
+	wantDiff := `(- missing rows, + unexpected rows)
+- {"id":3,"meta":null,"tags":[]}
++ {"id":2,"meta":null,"tags":[]}
+expected 2 rows, got 2`
+	if diff != wantDiff {
+		t.Fatalf("unexpected diff:\n%v", diff)
+	}
+
+	diff = diffRows(expected[:1], actual, ignore)
+	if !strings.Contains(diff, "expected 1 rows, got 2") {
+		t.Fatalf("unexpected diff:\n%v", diff)
+	}
+}
+
+func TestAssertTableEquals(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+	}
+	pool := dbPool.WithSQLs(t, []string{
+		`INSERT INTO table1 (name) VALUES ('one'), ('two')`,
+	})
+
+	AssertTableEquals(t, pool, "table1", []map[string]any{
+		{"name": "two"},
+		{"name": "one"},
+	}, IgnoreColumns("id"))
+}
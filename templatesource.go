@@ -0,0 +1,203 @@
+package go_test_pg
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"hash"
+	"io"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TemplateFromDatabase builds the template database from another running
+// database, e.g. staging, instead of schema files: the schema is copied
+// with pg_dump and data of selected tables with COPY. The template is
+// rebuilt when the schema of the source changes, changes of data are not
+// tracked.
+type TemplateFromDatabase struct {
+	// DSN is the connection string of the source database.
+	DSN string
+	// Tables are schema-qualified names of tables to copy data of, e.g.
+	// public.countries. Tables are copied in the listed order, so tables
+	// referenced by foreign keys must come first. Schema of all tables is
+	// copied.
+	Tables []string
+	// WhereClauses limit copied rows of Tables, e.g.
+	// {"public.users": "created_at > now() - interval '7 days'"}.
+	WhereClauses map[string]string
+	// PgDump is the path of pg_dump binary. Default is pg_dump from PATH.
+	// Its major version must not be older than the source server version.
+	PgDump string
+}
+
+// Returns schema-only dump of the source database without psql
+// meta-commands, which can't be executed by the server.
+func (s *TemplateFromDatabase) dumpSchema(ctx context.Context) ([]byte,
+	error) {
+
+	pgDump := s.PgDump
+	if pgDump == "" {
+		pgDump = "pg_dump"
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, pgDump, "--schema-only", "--no-owner",
+		"--no-privileges", "--dbname="+s.DSN)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("pg_dump of source database failed: %v: %w",
+			strings.TrimSpace(stderr.String()), err)
+	}
+	return stripMetaCommands(stdout.Bytes()), nil
+}
+
+// Removes lines with psql meta-commands, e.g. \restrict emitted by recent
+// pg_dump versions with a random key.
+func stripMetaCommands(dump []byte) []byte {
+	var out bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(dump))
+	scanner.Buffer(nil, len(dump)+1)
+	for scanner.Scan() {
+		if bytes.HasPrefix(scanner.Bytes(), []byte(`\`)) {
+			continue
+		}
+		out.Write(scanner.Bytes())
+		out.WriteByte('\n')
+	}
+	return out.Bytes()
+}
+
+// Mixes the source schema and copied data selection into the template
+// checksum.
+func (s *TemplateFromDatabase) hash(h hash.Hash, schema []byte) {
+	h.Write([]byte("\x00source\x00"))
+	h.Write(schema)
+	for _, table := range s.Tables {
+		h.Write([]byte("\x00table\x00" + table))
+	}
+	where := make([]string, 0, len(s.WhereClauses))
+	for table := range s.WhereClauses {
+		where = append(where, table)
+	}
+	sort.Strings(where)
+	for _, table := range where {
+		h.Write([]byte("\x00where\x00" + table + "\x00" +
+			s.WhereClauses[table]))
+	}
+}
+
+// Applies the dumped schema of the source database to conn and copies data
+// of selected tables.
+func (s *TemplateFromDatabase) load(ctx context.Context, conn *pgx.Conn,
+	schema []byte) error {
+
+	// The dump changes session settings, including search_path.
+	var searchPath string
+	err := conn.QueryRow(ctx, `SELECT current_setting('search_path')`).
+		Scan(&searchPath)
+	if err != nil {
+		return err
+	}
+	if _, err = conn.Exec(ctx, string(schema)); err != nil {
+		return fmt.Errorf("can't apply schema of source database: %w", err)
+	}
+	_, err = conn.Exec(ctx,
+		`RESET ALL; SELECT set_config('search_path', $1, false)`, searchPath)
+	if err != nil {
+		return fmt.Errorf("can't restore session settings: %w", err)
+	}
+
+	if len(s.Tables) == 0 {
+		return nil
+	}
+	src, err := pgx.Connect(ctx, s.DSN)
+	if err != nil {
+		return fmt.Errorf("can't connect to source database: %w", err)
+	}
+	defer src.Close(context.Background())
+
+	for _, table := range s.Tables {
+		err = copyTableData(ctx, src, conn, table, s.WhereClauses[table])
+		if err != nil {
+			return fmt.Errorf("can't copy data of table %v: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// Copies rows of table matching where from src to dst with COPY and moves
+// sequences owned by columns of the table past copied values.
+func copyTableData(ctx context.Context, src, dst *pgx.Conn, table,
+	where string) error {
+
+	// Generated columns can't be copied to.
+	var columns string
+	err := dst.QueryRow(ctx, `
+SELECT string_agg(quote_ident(attname), ', ' ORDER BY attnum)
+FROM pg_attribute
+WHERE attrelid = $1::regclass
+	AND attnum > 0
+	AND NOT attisdropped
+	AND attgenerated = ''`, table).Scan(&columns)
+	if err != nil {
+		return err
+	}
+
+	query := `SELECT ` + columns + ` FROM ` + quoteQualified(table)
+	if where != "" {
+		query += ` WHERE ` + where
+	}
+
+	r, w := io.Pipe()
+	copyErr := make(chan error, 1)
+	go func() {
+		_, err := src.PgConn().CopyTo(ctx, w, `COPY (`+query+`) TO STDOUT`)
+		w.CloseWithError(err)
+		copyErr <- err
+	}()
+	_, err = dst.PgConn().CopyFrom(ctx, r,
+		`COPY `+quoteQualified(table)+` (`+columns+`) FROM STDIN`)
+	// Unblock the writer if COPY FROM failed before reading everything.
+	r.Close()
+	if err2 := <-copyErr; err2 != nil {
+		return err2
+	}
+	if err != nil {
+		return err
+	}
+
+	rows, err := dst.Query(ctx, `
+SELECT quote_ident(attname), pg_get_serial_sequence($1, attname)
+FROM pg_attribute
+WHERE attrelid = $1::regclass
+	AND attnum > 0
+	AND NOT attisdropped
+	AND pg_get_serial_sequence($1, attname) IS NOT NULL`, table)
+	if err != nil {
+		return err
+	}
+	var column, sequence string
+	var sequences [][2]string
+	_, err = pgx.ForEachRow(rows, []any{&column, &sequence}, func() error {
+		sequences = append(sequences, [2]string{column, sequence})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, seq := range sequences {
+		_, err = dst.Exec(ctx, `
+SELECT setval($1, coalesce(max(`+seq[0]+`), 0) + 1, false)
+FROM `+quoteQualified(table), seq[1])
+		if err != nil {
+			return fmt.Errorf("can't set sequence %v: %w", seq[1], err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package go_test_pg
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+)
+
+func TestStripMetaCommands(t *testing.T) {
+	dump := "\\restrict abc\nCREATE TABLE t (\n\tid int\n);\n\\unrestrict abc\n"
+	want := "CREATE TABLE t (\n\tid int\n);\n"
+	if got := string(stripMetaCommands([]byte(dump))); got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+}
+
+func TestPgpool_TemplateFrom(t *testing.T) {
+	if _, err := exec.LookPath("pg_dump"); err != nil {
+		t.Skip("pg_dump is not found")
+	}
+
+	var sourcePool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+	}
+	source := sourcePool.WithEmpty(t)
+	_, err := source.Exec(context.Background(),
+		`INSERT INTO table1 (name) VALUES ('a'), ('b'), ('c')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var dbPool = Pgpool{
+		BaseName: "go_test_pg",
+		TemplateFrom: &TemplateFromDatabase{
+			DSN:          sourcePool.ConnString(t, source),
+			Tables:       []string{"public.table1"},
+			WhereClauses: map[string]string{"public.table1": "id > 1"},
+		},
+	}
+	defer dbPool.Close()
+	pool := dbPool.WithEmpty(t)
+	AssertRowCount(t, pool, "table1", 2)
+
+	var id int
+	err = pool.QueryRow(context.Background(),
+		`INSERT INTO table1 (name) VALUES ('d') RETURNING id`).Scan(&id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 4 {
+		t.Fatalf("want sequence moved past copied rows, got id %v", id)
+	}
+}
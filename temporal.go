@@ -0,0 +1,77 @@
+package go_test_pg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Begins a transaction, implemented by pools, connections and
+// transactions.
+type txBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// ExecAt executes sql with now() frozen at tm in the test database created
+// with MockNow option, e.g. to insert a row "as of" a past time, so column
+// defaults and audit triggers calling now() see tm. Time is frozen for the
+// statement only: it runs in a transaction (a savepoint if q is a
+// transaction) with testclock.now setting. q must be a pool, a connection
+// or a transaction.
+func ExecAt(t testing.TB, q Querier, tm time.Time, sql string,
+	args ...any) {
+
+	t.Helper()
+
+	b, ok := q.(txBeginner)
+	if !ok {
+		t.Fatalf("can't begin transaction on %T", q)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	tx, err := b.Begin(ctx)
+	if err != nil {
+		t.Fatalf("can't begin transaction: %v", err)
+	}
+	defer func() { _ = tx.Rollback(context.Background()) }()
+
+	// A local setting survives release of a savepoint, so the previous
+	// value is restored for the enclosing transaction.
+	var prev string
+	err = tx.QueryRow(ctx, `
+SELECT coalesce(current_setting('testclock.now', true), ''),
+	set_config('testclock.now', $1, true)`,
+		tm.Format(time.RFC3339Nano)).Scan(&prev, nil)
+	if err != nil {
+		t.Fatalf("can't set test time: %v", err)
+	}
+	if _, err = tx.Exec(ctx, sql, args...); err != nil {
+		t.Fatalf("can't execute query at %v: %v%v", tm, err, querierHint(q))
+	}
+	_, err = tx.Exec(ctx, `SELECT set_config('testclock.now', $1, true)`,
+		prev)
+	if err != nil {
+		t.Fatalf("can't restore test time: %v", err)
+	}
+	if err = tx.Commit(ctx); err != nil {
+		t.Fatalf("can't commit query at %v: %v", tm, err)
+	}
+}
+
+// AssertHistory fails the test if rows of history (audit) table matching
+// where condition are not exactly expected, e.g. versions of a row
+// recorded by an audit trigger after updates. Order of rows is not
+// significant; with MockNow and ExecAt timestamps of versions are stable
+// and may be compared too, otherwise ignore them with IgnoreColumns.
+func AssertHistory(t testing.TB, q Querier, table, where string,
+	expected []map[string]any, args []any, opts ...TableOption) {
+
+	t.Helper()
+	assertRows(t, q, "history "+table,
+		`SELECT * FROM `+quoteQualified(table)+` WHERE `+where,
+		expected, opts, args...)
+}
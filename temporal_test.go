@@ -0,0 +1,35 @@
+package go_test_pg
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExecAt(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema_history.sql",
+		MockNow:    true,
+	}
+	pool := dbPool.WithEmpty(t)
+
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	updated := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	ExecAt(t, pool, created, `INSERT INTO prices (id, amount) VALUES (1, 10)`)
+	ExecAt(t, pool, updated, `UPDATE prices SET amount = 20 WHERE id = $1`, 1)
+
+	AssertHistory(t, pool, "prices_history", "id = $1",
+		[]map[string]any{{
+			"id": 1, "amount": 10, "valid_from": created,
+			"valid_to": updated,
+		}}, []any{1})
+	AssertTableEquals(t, pool, "prices", []map[string]any{
+		{"id": 1, "amount": 20, "updated_at": updated},
+	})
+
+	// Time is frozen in transactions too and restored after the statement.
+	tx := dbPool.WithTx(t)
+	ExecAt(t, tx, created, `INSERT INTO prices (id, amount) VALUES (2, 10)`)
+	AssertExists(t, tx, "prices", "id = 2 AND updated_at = $1", created)
+	AssertExists(t, tx, "prices", "coalesce(current_setting('testclock.now', true), '') = ''")
+}
@@ -0,0 +1,79 @@
+package go_test_pg
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Default search_path with testclock schema put before pg_catalog, so
+// testclock.now() shadows pg_catalog.now().
+const testclockSearchPath = `testclock, pg_catalog, "$user", public`
+
+// Installs testclock schema with now() function returning frozen time.
+// Time is frozen for the whole database with SetTestTime or for a single
+// session with testclock.now setting.
+const testclockSQL = `
+CREATE SCHEMA testclock;
+CREATE TABLE testclock.frozen (at timestamptz NOT NULL);
+CREATE FUNCTION testclock.now() RETURNS timestamptz
+LANGUAGE sql STABLE AS $$
+	SELECT coalesce(
+		nullif(pg_catalog.current_setting('testclock.now', true), '')
+			::timestamptz,
+		(SELECT at FROM testclock.frozen LIMIT 1),
+		pg_catalog.now())
+$$;
+` + testclockSearchPathSQL
+
+// Puts testclock schema to search_path of the session.
+const testclockSearchPathSQL = `
+SELECT pg_catalog.set_config('search_path',
+	'testclock, pg_catalog, ' || pg_catalog.current_setting('search_path'),
+	false);
+`
+
+// Installs testclock schema and puts it to search_path of conn, so
+// now() calls in the schema file bind to testclock.now().
+func installTestclock(ctx context.Context, conn *pgx.Conn) error {
+	_, err := conn.Exec(ctx, testclockSQL)
+	if err != nil {
+		return fmt.Errorf("can't install testclock schema: %w", err)
+	}
+	return nil
+}
+
+// Puts testclock schema to search_path of conn to a database with testclock
+// installed.
+func useTestclock(ctx context.Context, conn *pgx.Conn) error {
+	_, err := conn.Exec(ctx, testclockSearchPathSQL)
+	if err != nil {
+		return fmt.Errorf("can't set search_path: %w", err)
+	}
+	return nil
+}
+
+// SetTestTime freezes now() in the test database created with MockNow
+// option. Zero tm unfreezes the clock. CURRENT_TIMESTAMP and other SQL
+// standard functions are not affected.
+func SetTestTime(t testing.TB, q Querier, tm time.Time) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var err error
+	if tm.IsZero() {
+		_, err = q.Exec(ctx, `DELETE FROM testclock.frozen`)
+	} else {
+		_, err = q.Exec(ctx, `
+WITH d AS (DELETE FROM testclock.frozen)
+INSERT INTO testclock.frozen (at) VALUES ($1)`, tm)
+	}
+	if err != nil {
+		t.Fatalf("can't set test time (is MockNow enabled?): %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package go_test_pg
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSetTestTime(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema2.sql",
+		MockNow:    true,
+	}
+	pool := dbPool.WithEmpty(t)
+	ctx := context.Background()
+
+	frozen := time.Date(2020, 2, 29, 12, 0, 0, 0, time.UTC)
+	SetTestTime(t, pool, frozen)
+
+	var now, createdAt time.Time
+	err := pool.QueryRow(ctx, `SELECT now()`).Scan(&now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !now.Equal(frozen) {
+		t.Fatalf("want now() = %v, got %v", frozen, now)
+	}
+
+	err = pool.QueryRow(ctx,
+		`INSERT INTO events DEFAULT VALUES RETURNING created_at`).
+		Scan(&createdAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !createdAt.Equal(frozen) {
+		t.Fatalf("want created_at = %v, got %v", frozen, createdAt)
+	}
+
+	SetTestTime(t, pool, time.Time{})
+	err = pool.QueryRow(ctx, `SELECT now()`).Scan(&now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if now.Equal(frozen) {
+		t.Fatal("clock is still frozen")
+	}
+}
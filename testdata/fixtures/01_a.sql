@@ -0,0 +1 @@
+INSERT INTO table1 (name) VALUES ('a');
@@ -0,0 +1 @@
+CREATE TABLE audit_log (id SERIAL PRIMARY KEY, message TEXT);
@@ -0,0 +1 @@
+CREATE TABLE users (id SERIAL PRIMARY KEY, name TEXT NOT NULL);
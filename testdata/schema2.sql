@@ -0,0 +1,4 @@
+CREATE TABLE events (
+    id SERIAL PRIMARY KEY,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
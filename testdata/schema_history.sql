@@ -0,0 +1,22 @@
+CREATE TABLE prices (
+    id INT PRIMARY KEY,
+    amount INT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE TABLE prices_history (
+    id INT NOT NULL,
+    amount INT NOT NULL,
+    valid_from TIMESTAMPTZ NOT NULL,
+    valid_to TIMESTAMPTZ NOT NULL
+);
+CREATE FUNCTION prices_audit() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    INSERT INTO prices_history (id, amount, valid_from, valid_to)
+    VALUES (OLD.id, OLD.amount, OLD.updated_at, now());
+    NEW.updated_at = now();
+    RETURN NEW;
+END
+$$;
+CREATE TRIGGER prices_audit BEFORE UPDATE ON prices
+    FOR EACH ROW EXECUTE FUNCTION prices_audit();
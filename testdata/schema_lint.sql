@@ -0,0 +1,2 @@
+CREATE TABLE users (id SERIAL PRIMARY KEY, "createdAt" TIMESTAMP);
+CREATE TABLE orders (id SERIAL PRIMARY KEY, user_id INT REFERENCES users);
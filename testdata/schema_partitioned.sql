@@ -0,0 +1,5 @@
+CREATE TABLE measurements (
+    id SERIAL,
+    taken_at timestamptz NOT NULL,
+    value double precision
+) PARTITION BY RANGE (taken_at);
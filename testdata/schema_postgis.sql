@@ -0,0 +1,4 @@
+CREATE TABLE places (
+    id SERIAL PRIMARY KEY,
+    geom geometry(Point, 4326) NOT NULL
+);
@@ -0,0 +1,6 @@
+CREATE SCHEMA app;
+
+CREATE TABLE accounts (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100)
+);
@@ -0,0 +1,5 @@
+CREATE TABLE countries (
+    code CHAR(2) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL
+);
+INSERT INTO countries (code, name) VALUES ('de', 'Germany'), ('fr', 'France');
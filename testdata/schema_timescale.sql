@@ -0,0 +1,4 @@
+CREATE TABLE metrics (
+    time timestamptz NOT NULL,
+    value double precision NOT NULL
+);
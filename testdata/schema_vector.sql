@@ -0,0 +1,4 @@
+CREATE TABLE items (
+    id SERIAL PRIMARY KEY,
+    embedding vector(3) NOT NULL
+);
@@ -0,0 +1,95 @@
+package go_test_pg
+
+import (
+	"context"
+	"hash/fnv"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Namespace of advisory locks taken by AcquireTestLock. Locks with two
+// int4 keys do not conflict with single int8 locks used for template
+// creation.
+const testLockClassID int32 = 0x67747067 // "gtpg"
+
+// AcquireTestLock blocks until an exclusive lock named key is acquired and
+// holds it until the end of the test. The lock is a session advisory lock
+// on the master database, so it serializes tests across all test processes
+// using the same server, e.g. tests of parallel packages that modify
+// cluster-level objects. Waiting is bounded only by the test deadline.
+// Does not work through PgBouncer in transaction pooling mode.
+func (p *Pgpool) AcquireTestLock(t testing.TB, key string) {
+	t.Helper()
+
+	if p.Skip {
+		t.Skip("Skip database tests")
+	}
+
+	cfg, err := p.connConfig("")
+	if err != nil {
+		t.Fatalf("can't acquire test lock %v: %v", key, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	if err = p.beforeConnect(ctx, cfg); err != nil {
+		t.Fatalf("can't acquire test lock %v: %v", key, err)
+	}
+
+	conn, err := pgx.ConnectConfig(ctx, cfg)
+	if err != nil {
+		t.Fatalf("can't acquire test lock %v: %v", key, err)
+	}
+
+	lockCtx, lockCancel := testContext(t)
+	defer lockCancel()
+
+	_, err = conn.Exec(lockCtx, `SELECT pg_advisory_lock($1, $2)`,
+		testLockClassID, testLockObjID(key))
+	if err != nil {
+		closeConn(conn)
+		t.Fatalf("can't acquire test lock %v: %v", key, err)
+	}
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(),
+			defaultTimeout)
+		defer cancel()
+		_, err := conn.Exec(ctx, `SELECT pg_advisory_unlock($1, $2)`,
+			testLockClassID, testLockObjID(key))
+		if err != nil {
+			t.Errorf("can't release test lock %v: %v", key, err)
+		}
+		// Lock is released with the session anyway.
+		closeConn(conn)
+	})
+}
+
+// Returns second key of advisory lock for key.
+func testLockObjID(key string) int32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int32(h.Sum32())
+}
+
+// Returns context that is done at the test deadline, if the test has one.
+func testContext(t testing.TB) (context.Context, context.CancelFunc) {
+	if dt, ok := t.(interface{ Deadline() (time.Time, bool) }); ok {
+		if deadline, ok := dt.Deadline(); ok {
+			return context.WithDeadline(context.Background(), deadline)
+		}
+	}
+	return context.WithCancel(context.Background())
+}
+
+func closeConn(conn *pgx.Conn) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+	if err := conn.Close(ctx); err != nil {
+		log.Printf("error closing DB connection: %v", err)
+	}
+}
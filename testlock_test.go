@@ -0,0 +1,37 @@
+package go_test_pg
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPgpool_AcquireTestLock(t *testing.T) {
+	var dbPool = Pgpool{BaseName: "go_test_pg"}
+	pool := dbPool.WithEmpty(t)
+	const key = "TestPgpool_AcquireTestLock"
+
+	countLocks := func(t *testing.T) int {
+		var n int
+		err := pool.QueryRow(context.Background(), `
+SELECT count(*) FROM pg_locks
+WHERE locktype = 'advisory' AND classid = $1::oid AND objid = $2::oid`,
+			uint32(testLockClassID),
+			uint32(testLockObjID(key))).Scan(&n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	t.Run("lock", func(t *testing.T) {
+		dbPool.AcquireTestLock(t, key)
+		if n := countLocks(t); n != 1 {
+			t.Fatalf("want 1 lock, got %v", n)
+		}
+	})
+
+	t.Run("relock", func(t *testing.T) {
+		// lock is released after the first subtest
+		dbPool.AcquireTestLock(t, key)
+	})
+}
@@ -0,0 +1,36 @@
+package go_test_pg
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// CreateHypertable converts table to TimescaleDB hypertable partitioned by
+// timeColumn. If chunkInterval is zero, the default interval of the
+// extension is used. Existing rows are moved to chunks.
+func CreateHypertable(t testing.TB, q Querier, table, timeColumn string,
+	chunkInterval time.Duration) {
+
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	query := `SELECT create_hypertable($1::regclass, $2, ` +
+		`migrate_data => true)`
+	args := []any{table, timeColumn}
+	if chunkInterval != 0 {
+		query = `SELECT create_hypertable($1::regclass, $2, ` +
+			`chunk_time_interval => $3::interval, migrate_data => true)`
+		args = append(args,
+			strconv.FormatInt(chunkInterval.Microseconds(), 10)+
+				" microseconds")
+	}
+
+	if _, err := q.Exec(ctx, query, args...); err != nil {
+		t.Fatalf("can't create hypertable %v: %v%v", table, err,
+			querierHint(q))
+	}
+}
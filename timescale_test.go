@@ -0,0 +1,52 @@
+package go_test_pg
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPgpool_templateChecksum_Timescale(t *testing.T) {
+	p := Pgpool{
+		SchemaFile:  "./testdata/schema_timescale.sql",
+		TimescaleDB: true,
+	}
+	checksums := map[[16]byte]string{}
+	for _, version := range []string{"2.11.0", "2.12.0"} {
+		checksum, err := p.templateChecksum([]string{version})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if prev, ok := checksums[checksum]; ok {
+			t.Fatalf("versions %q and %q have the same checksum", prev,
+				version)
+		}
+		checksums[checksum] = version
+	}
+}
+
+func TestCreateHypertable(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:    "go_test_pg",
+		SchemaFile:  "./testdata/schema_timescale.sql",
+		TimescaleDB: true,
+	}
+	_, err := dbPool.extensionVersions([]string{"timescaledb"})
+	if err != nil {
+		t.Skip(err)
+	}
+
+	pool := dbPool.WithEmpty(t)
+	CreateHypertable(t, pool, "metrics", "time", time.Hour)
+
+	var interval time.Duration
+	err = pool.QueryRow(context.Background(), `
+SELECT time_interval FROM timescaledb_information.dimensions
+WHERE hypertable_name = 'metrics'`).Scan(&interval)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if interval != time.Hour {
+		t.Fatalf("want chunk interval 1h, got %v", interval)
+	}
+}
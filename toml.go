@@ -0,0 +1,154 @@
+package go_test_pg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Parses the subset of TOML used by profile files: table headers, and
+// keys with strings, integers, booleans and single-line arrays of them.
+// Returns keys by names of their tables, keys before the first header are
+// in the table with empty name.
+func parseTOML(data string) (map[string]map[string]any, error) {
+	tables := map[string]map[string]any{"": {}}
+	table := ""
+	for i, line := range strings.Split(data, "\n") {
+		lineErr := func(format string, args ...any) error {
+			return fmt.Errorf("line %v: %v", i+1, fmt.Sprintf(format, args...))
+		}
+
+		rest, err := stripTOMLComment(line)
+		if err != nil {
+			return nil, lineErr("%v", err)
+		}
+		rest = strings.TrimSpace(rest)
+		if rest == "" {
+			continue
+		}
+
+		if strings.HasPrefix(rest, "[") {
+			if !strings.HasSuffix(rest, "]") || strings.HasPrefix(rest, "[[") {
+				return nil, lineErr("invalid table header %q", rest)
+			}
+			table = strings.TrimSpace(rest[1 : len(rest)-1])
+			if table == "" {
+				return nil, lineErr("empty table name")
+			}
+			if _, ok := tables[table]; ok {
+				return nil, lineErr("duplicate table %v", table)
+			}
+			tables[table] = map[string]any{}
+			continue
+		}
+
+		key, value, ok := strings.Cut(rest, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, lineErr("expected key = value")
+		}
+		if _, ok = tables[table][key]; ok {
+			return nil, lineErr("duplicate key %v", key)
+		}
+		v, rest, err := parseTOMLValue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, lineErr("%v", err)
+		}
+		if rest != "" {
+			return nil, lineErr("unexpected %q after value", rest)
+		}
+		tables[table][key] = v
+	}
+	return tables, nil
+}
+
+// Returns line without comment, keeping # inside strings.
+func stripTOMLComment(line string) (string, error) {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote == 0 && c == '#':
+			return line[:i], nil
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		}
+	}
+	if quote != 0 {
+		return "", fmt.Errorf("unterminated string")
+	}
+	return line, nil
+}
+
+// Parses the value at the beginning of s. Returns the value and the rest
+// of s without leading spaces.
+func parseTOMLValue(s string) (any, string, error) {
+	switch {
+	case s == "":
+		return nil, "", fmt.Errorf("missing value")
+	case s[0] == '"':
+		end := 1
+		for ; end < len(s) && s[end] != '"'; end++ {
+			if s[end] == '\\' {
+				end++
+			}
+		}
+		if end >= len(s) {
+			return nil, "", fmt.Errorf("unterminated string")
+		}
+		v, err := strconv.Unquote(s[:end+1])
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid string %v", s[:end+1])
+		}
+		return v, strings.TrimSpace(s[end+1:]), nil
+	case s[0] == '\'':
+		end := strings.IndexByte(s[1:], '\'')
+		if end < 0 {
+			return nil, "", fmt.Errorf("unterminated string")
+		}
+		return s[1 : end+1], strings.TrimSpace(s[end+2:]), nil
+	case s[0] == '[':
+		values := []any{}
+		s = strings.TrimSpace(s[1:])
+		for !strings.HasPrefix(s, "]") {
+			v, rest, err := parseTOMLValue(s)
+			if err != nil {
+				return nil, "", err
+			}
+			if _, ok := v.([]any); ok {
+				return nil, "", fmt.Errorf("nested arrays are not supported")
+			}
+			values = append(values, v)
+			switch {
+			case strings.HasPrefix(rest, ","):
+				s = strings.TrimSpace(rest[1:])
+			case strings.HasPrefix(rest, "]"):
+				s = rest
+			default:
+				return nil, "", fmt.Errorf("unterminated array")
+			}
+		}
+		return values, strings.TrimSpace(s[1:]), nil
+	}
+
+	end := strings.IndexAny(s, " \t,]")
+	if end < 0 {
+		end = len(s)
+	}
+	token, rest := s[:end], strings.TrimSpace(s[end:])
+	switch token {
+	case "true":
+		return true, rest, nil
+	case "false":
+		return false, rest, nil
+	}
+	n, err := strconv.ParseInt(strings.ReplaceAll(token, "_", ""), 10, 64)
+	if err != nil {
+		return nil, "", fmt.Errorf("unsupported value %v", token)
+	}
+	return n, rest, nil
+}
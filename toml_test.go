@@ -0,0 +1,57 @@
+package go_test_pg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	tables, err := parseTOML(`
+# profiles
+default = "local"
+
+[profiles.local]
+socket_dir = '/var/run/postgresql' # comment
+port = 5_432
+unlogged_tables = true
+
+[profiles.ci]
+dsn = "user=postgres password='a#b' sslmode=disable"
+hosts = ["db1:5432", "db2"]
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]map[string]any{
+		"": {"default": "local"},
+		"profiles.local": {
+			"socket_dir":      "/var/run/postgresql",
+			"port":            int64(5432),
+			"unlogged_tables": true,
+		},
+		"profiles.ci": {
+			"dsn":   "user=postgres password='a#b' sslmode=disable",
+			"hosts": []any{"db1:5432", "db2"},
+		},
+	}
+	if !reflect.DeepEqual(tables, want) {
+		t.Fatalf("want %v, got %v", want, tables)
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	for _, data := range []string{
+		`key`,
+		`key = "unterminated`,
+		`key = 1 2`,
+		`key = 1` + "\n" + `key = 2`,
+		`[a]` + "\n" + `[a]`,
+		`[[a]]`,
+		`key = [1, [2]]`,
+		`key = 1.5`,
+	} {
+		if _, err := parseTOML(data); err == nil {
+			t.Errorf("want error for %q", data)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package go_test_pg
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// multiTracer passes trace events to all tracers that support them.
+type multiTracer []pgx.QueryTracer
+
+// Returns tracer passing events to all non-nil tracers.
+func newMultiTracer(tracers ...pgx.QueryTracer) pgx.QueryTracer {
+	var mt multiTracer
+	for _, tr := range tracers {
+		if tr != nil {
+			mt = append(mt, tr)
+		}
+	}
+	switch len(mt) {
+	case 0:
+		return nil
+	case 1:
+		return mt[0]
+	default:
+		return mt
+	}
+}
+
+func (mt multiTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn,
+	data pgx.TraceQueryStartData) context.Context {
+
+	for _, tr := range mt {
+		ctx = tr.TraceQueryStart(ctx, conn, data)
+	}
+	return ctx
+}
+
+func (mt multiTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn,
+	data pgx.TraceQueryEndData) {
+
+	for _, tr := range mt {
+		tr.TraceQueryEnd(ctx, conn, data)
+	}
+}
+
+func (mt multiTracer) TraceBatchStart(ctx context.Context, conn *pgx.Conn,
+	data pgx.TraceBatchStartData) context.Context {
+
+	for _, tr := range mt {
+		if bt, ok := tr.(pgx.BatchTracer); ok {
+			ctx = bt.TraceBatchStart(ctx, conn, data)
+		}
+	}
+	return ctx
+}
+
+func (mt multiTracer) TraceBatchQuery(ctx context.Context, conn *pgx.Conn,
+	data pgx.TraceBatchQueryData) {
+
+	for _, tr := range mt {
+		if bt, ok := tr.(pgx.BatchTracer); ok {
+			bt.TraceBatchQuery(ctx, conn, data)
+		}
+	}
+}
+
+func (mt multiTracer) TraceBatchEnd(ctx context.Context, conn *pgx.Conn,
+	data pgx.TraceBatchEndData) {
+
+	for _, tr := range mt {
+		if bt, ok := tr.(pgx.BatchTracer); ok {
+			bt.TraceBatchEnd(ctx, conn, data)
+		}
+	}
+}
+
+func (mt multiTracer) TraceCopyFromStart(ctx context.Context,
+	conn *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context {
+
+	for _, tr := range mt {
+		if ct, ok := tr.(pgx.CopyFromTracer); ok {
+			ctx = ct.TraceCopyFromStart(ctx, conn, data)
+		}
+	}
+	return ctx
+}
+
+func (mt multiTracer) TraceCopyFromEnd(ctx context.Context, conn *pgx.Conn,
+	data pgx.TraceCopyFromEndData) {
+
+	for _, tr := range mt {
+		if ct, ok := tr.(pgx.CopyFromTracer); ok {
+			ct.TraceCopyFromEnd(ctx, conn, data)
+		}
+	}
+}
+
+func (mt multiTracer) TracePrepareStart(ctx context.Context,
+	conn *pgx.Conn, data pgx.TracePrepareStartData) context.Context {
+
+	for _, tr := range mt {
+		if pt, ok := tr.(pgx.PrepareTracer); ok {
+			ctx = pt.TracePrepareStart(ctx, conn, data)
+		}
+	}
+	return ctx
+}
+
+func (mt multiTracer) TracePrepareEnd(ctx context.Context, conn *pgx.Conn,
+	data pgx.TracePrepareEndData) {
+
+	for _, tr := range mt {
+		if pt, ok := tr.(pgx.PrepareTracer); ok {
+			pt.TracePrepareEnd(ctx, conn, data)
+		}
+	}
+}
+
+func (mt multiTracer) TraceConnectStart(ctx context.Context,
+	data pgx.TraceConnectStartData) context.Context {
+
+	for _, tr := range mt {
+		if ct, ok := tr.(pgx.ConnectTracer); ok {
+			ctx = ct.TraceConnectStart(ctx, data)
+		}
+	}
+	return ctx
+}
+
+func (mt multiTracer) TraceConnectEnd(ctx context.Context,
+	data pgx.TraceConnectEndData) {
+
+	for _, tr := range mt {
+		if ct, ok := tr.(pgx.ConnectTracer); ok {
+			ct.TraceConnectEnd(ctx, data)
+		}
+	}
+}
+
+type slowQueryCtxKey struct{}
+
+type slowQueryStart struct {
+	sql   string
+	start time.Time
+}
+
+// slowQueryTracer records queries that run longer than threshold.
+type slowQueryTracer struct {
+	threshold time.Duration
+
+	m       sync.Mutex
+	queries map[string]*slowQuery
+}
+
+type slowQuery struct {
+	sql   string
+	count int
+	total time.Duration
+	max   time.Duration
+}
+
+func newSlowQueryTracer(threshold time.Duration) *slowQueryTracer {
+	return &slowQueryTracer{
+		threshold: threshold,
+		queries:   make(map[string]*slowQuery),
+	}
+}
+
+func (st *slowQueryTracer) TraceQueryStart(ctx context.Context,
+	_ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+
+	return context.WithValue(ctx, slowQueryCtxKey{},
+		slowQueryStart{sql: data.SQL, start: time.Now()})
+}
+
+func (st *slowQueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn,
+	_ pgx.TraceQueryEndData) {
+
+	qs, ok := ctx.Value(slowQueryCtxKey{}).(slowQueryStart)
+	if !ok {
+		return
+	}
+	st.record(qs.sql, time.Since(qs.start))
+}
+
+func (st *slowQueryTracer) record(sql string, d time.Duration) {
+	if d < st.threshold {
+		return
+	}
+
+	st.m.Lock()
+	defer st.m.Unlock()
+
+	q, ok := st.queries[sql]
+	if !ok {
+		q = &slowQuery{sql: sql}
+		st.queries[sql] = q
+	}
+	q.count++
+	q.total += d
+	if d > q.max {
+		q.max = d
+	}
+}
+
+// Returns recorded slow queries ordered by total duration.
+func (st *slowQueryTracer) slowQueries() []slowQuery {
+	st.m.Lock()
+	defer st.m.Unlock()
+
+	queries := make([]slowQuery, 0, len(st.queries))
+	for _, q := range st.queries {
+		queries = append(queries, *q)
+	}
+	sort.Slice(queries, func(i, j int) bool {
+		if queries[i].total != queries[j].total {
+			return queries[i].total > queries[j].total
+		}
+		return queries[i].sql < queries[j].sql
+	})
+	return queries
+}
+
+// Logs summary of slow queries with t.Log.
+func (st *slowQueryTracer) report(t testing.TB) {
+	queries := st.slowQueries()
+	if len(queries) == 0 {
+		return
+	}
+	t.Logf("queries slower than %v:", st.threshold)
+	for _, q := range queries {
+		t.Logf("count: %v, total: %v, max: %v: %v",
+			q.count, q.total, q.max, q.sql)
+	}
+}
+
+// Returns tracer recording slow queries if SlowQueryThreshold is set. The
+// report is logged on test cleanup.
+func (p *Pgpool) slowQueryTracer(t testing.TB) pgx.QueryTracer {
+	if p.SlowQueryThreshold <= 0 {
+		return nil
+	}
+	st := newSlowQueryTracer(p.SlowQueryThreshold)
+	t.Cleanup(func() { st.report(t) })
+	return st
+}
+
+type sqlLogCtxKey struct{}
+
+type sqlLogStart struct {
+	sql   string
+	args  []any
+	start time.Time
+}
+
+// sqlLogTracer logs every query with t.Logf when it finishes.
+type sqlLogTracer struct {
+	t      testing.TB
+	redact bool
+}
+
+func (lt sqlLogTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn,
+	data pgx.TraceQueryStartData) context.Context {
+
+	return context.WithValue(ctx, sqlLogCtxKey{},
+		sqlLogStart{sql: data.SQL, args: data.Args, start: time.Now()})
+}
+
+func (lt sqlLogTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn,
+	data pgx.TraceQueryEndData) {
+
+	qs, ok := ctx.Value(sqlLogCtxKey{}).(sqlLogStart)
+	if !ok {
+		return
+	}
+	lt.t.Log(formatSQLLog(qs.sql, qs.args, time.Since(qs.start), data.Err,
+		lt.redact))
+}
+
+func formatSQLLog(sql string, args []any, d time.Duration, err error,
+	redact bool) string {
+
+	msg := fmt.Sprintf("SQL (%v): %v", d.Round(time.Microsecond),
+		strings.TrimSpace(sql))
+	if len(args) != 0 {
+		if redact {
+			msg += fmt.Sprintf(" [%v args redacted]", len(args))
+		} else {
+			msg += fmt.Sprintf(" %v", args)
+		}
+	}
+	if err != nil {
+		msg += fmt.Sprintf(": %v", err)
+	}
+	return msg
+}
+
+// Returns tracer of connections of returned pools and transactions.
+func (p *Pgpool) queryTracer(t testing.TB) pgx.QueryTracer {
+	var lt pgx.QueryTracer
+	if p.LogSQL {
+		lt = sqlLogTracer{t: t, redact: p.RedactSQLArgs}
+	}
+	return newMultiTracer(p.slowQueryTracer(t), lt)
+}
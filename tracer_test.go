@@ -0,0 +1,67 @@
+package go_test_pg
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSlowQueryTracer(t *testing.T) {
+	st := newSlowQueryTracer(100 * time.Millisecond)
+	st.record("SELECT 1", 10*time.Millisecond)
+	st.record("SELECT 2", 200*time.Millisecond)
+	st.record("SELECT 3", 150*time.Millisecond)
+	st.record("SELECT 3", 250*time.Millisecond)
+
+	queries := st.slowQueries()
+	if len(queries) != 2 {
+		t.Fatalf("want 2 slow queries, got %v", len(queries))
+	}
+	want := slowQuery{
+		sql:   "SELECT 3",
+		count: 2,
+		total: 400 * time.Millisecond,
+		max:   250 * time.Millisecond,
+	}
+	if queries[0] != want {
+		t.Fatalf("unexpected slow query: %+v", queries[0])
+	}
+	if queries[1].sql != "SELECT 2" {
+		t.Fatalf("unexpected slow query: %+v", queries[1])
+	}
+}
+
+func TestNewMultiTracer(t *testing.T) {
+	if tr := newMultiTracer(nil, nil); tr != nil {
+		t.Fatalf("want nil tracer, got %v", tr)
+	}
+	st := newSlowQueryTracer(time.Second)
+	if tr := newMultiTracer(nil, st); tr != st {
+		t.Fatalf("want single tracer, got %v", tr)
+	}
+	if _, ok := newMultiTracer(st, st).(multiTracer); !ok {
+		t.Fatal("want multiTracer")
+	}
+}
+
+func TestFormatSQLLog(t *testing.T) {
+	testCases := []struct {
+		args   []any
+		err    error
+		redact bool
+		want   string
+	}{
+		{nil, nil, false, "SQL (1.5ms): SELECT $1"},
+		{[]any{42, "secret"}, nil, false, "SQL (1.5ms): SELECT $1 [42 secret]"},
+		{[]any{42, "secret"}, nil, true,
+			"SQL (1.5ms): SELECT $1 [2 args redacted]"},
+		{nil, errors.New("boom"), false, "SQL (1.5ms): SELECT $1: boom"},
+	}
+	for _, tc := range testCases {
+		got := formatSQLLog("\n\tSELECT $1\n", tc.args,
+			1500*time.Microsecond, tc.err, tc.redact)
+		if got != tc.want {
+			t.Errorf("want %q, got %q", tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package go_test_pg
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Rewrites all permanent user tables of the database to UNLOGGED.
+//
+// A table may be turned to UNLOGGED only when all tables referencing it
+// with foreign keys are UNLOGGED already. Tables referenced by tables that
+// can't be converted (partitioned tables, extension tables, reference
+// cycles) stay permanent.
+func setTablesUnlogged(ctx context.Context, conn *pgx.Conn) error {
+	rows, err := conn.Query(ctx, `
+SELECT c.oid::regclass::text
+FROM pg_class c
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE c.relkind = 'r'
+	AND c.relpersistence = 'p'
+	AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+	AND n.nspname NOT LIKE 'pg\_toast%'
+	AND NOT EXISTS(
+		SELECT 1 FROM pg_depend d
+		WHERE d.classid = 'pg_class'::regclass
+			AND d.objid = c.oid
+			AND d.deptype = 'e')`)
+	if err != nil {
+		return err
+	}
+	tables, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return err
+	}
+
+	rows, err = conn.Query(ctx, `
+SELECT oid::regclass::text
+FROM pg_class
+WHERE relkind = 'r' AND relpersistence = 'u'`)
+	if err != nil {
+		return err
+	}
+	unlogged, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return err
+	}
+
+	referencedBy := make(map[string][]string)
+	rows, err = conn.Query(ctx, `
+SELECT conrelid::regclass::text, confrelid::regclass::text
+FROM pg_constraint
+WHERE contype = 'f'`)
+	if err != nil {
+		return err
+	}
+	var referencing, referenced string
+	_, err = pgx.ForEachRow(rows, []any{&referencing, &referenced},
+		func() error {
+			referencedBy[referenced] = append(referencedBy[referenced],
+				referencing)
+			return nil
+		})
+	if err != nil {
+		return err
+	}
+
+	order, skipped := unloggedOrder(tables, unlogged, referencedBy)
+	if len(skipped) != 0 {
+		log.Printf("go-test-pg: tables left permanent because of foreign "+
+			"keys: %v", strings.Join(skipped, ", "))
+	}
+
+	for _, table := range order {
+		_, err = conn.Exec(ctx, `ALTER TABLE `+table+` SET UNLOGGED`)
+		if err != nil {
+			return fmt.Errorf("can't set table %v unlogged: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// Returns the order in which tables may be turned to UNLOGGED, and the
+// list of tables that can't be converted. unlogged are tables that are
+// UNLOGGED already, so they do not block tables they reference.
+// referencedBy maps a table to the tables that reference it with foreign
+// keys.
+func unloggedOrder(tables, unlogged []string,
+	referencedBy map[string][]string) (order, skipped []string) {
+
+	candidates := make(map[string]bool, len(tables))
+	for _, t := range tables {
+		candidates[t] = true
+	}
+	isUnlogged := make(map[string]bool, len(unlogged))
+	for _, t := range unlogged {
+		isUnlogged[t] = true
+	}
+
+	// Drop tables referenced by tables that stay permanent. Repeat until
+	// nothing changes as every dropped table may block other tables.
+	for changed := true; changed; {
+		changed = false
+		for t := range candidates {
+			for _, r := range referencedBy[t] {
+				if r != t && !candidates[r] && !isUnlogged[r] {
+					delete(candidates, t)
+					skipped = append(skipped, t)
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	converted := make(map[string]bool, len(candidates))
+	for len(converted) < len(candidates) {
+		var ready []string
+		for t := range candidates {
+			if converted[t] {
+				continue
+			}
+			isReady := true
+			for _, r := range referencedBy[t] {
+				if r != t && !converted[r] && !isUnlogged[r] {
+					isReady = false
+					break
+				}
+			}
+			if isReady {
+				ready = append(ready, t)
+			}
+		}
+
+		if len(ready) == 0 {
+			// Only reference cycles left.
+			for t := range candidates {
+				if !converted[t] {
+					skipped = append(skipped, t)
+				}
+			}
+			break
+		}
+
+		sort.Strings(ready)
+		for _, t := range ready {
+			converted[t] = true
+		}
+		order = append(order, ready...)
+	}
+
+	sort.Strings(skipped)
+	return order, skipped
+}
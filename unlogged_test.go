@@ -0,0 +1,38 @@
+package go_test_pg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnloggedOrder(t *testing.T) {
+	tables := []string{"users", "orders", "items", "a", "b", "logs", "ref",
+		"tags"}
+	// cache is unlogged already
+	unlogged := []string{"cache"}
+	referencedBy := map[string][]string{
+		// orders reference users, items reference orders
+		"users":  {"orders"},
+		"orders": {"items"},
+		// cycle a <-> b
+		"a": {"b"},
+		"b": {"a"},
+		// self reference does not block conversion
+		"logs": {"logs"},
+		// ref is referenced by partitioned table that stays permanent
+		"ref": {"parted"},
+		// tags are referenced by cache that is unlogged already
+		"tags": {"cache"},
+	}
+
+	order, skipped := unloggedOrder(tables, unlogged, referencedBy)
+
+	wantOrder := []string{"items", "logs", "tags", "orders", "users"}
+	if !reflect.DeepEqual(order, wantOrder) {
+		t.Errorf("unexpected order: %v", order)
+	}
+	wantSkipped := []string{"a", "b", "ref"}
+	if !reflect.DeepEqual(skipped, wantSkipped) {
+		t.Errorf("unexpected skipped tables: %v", skipped)
+	}
+}
@@ -0,0 +1,54 @@
+package go_test_pg
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"sync"
+	"testing"
+)
+
+var (
+	testUUIDsM sync.Mutex
+	testUUIDs  = make(map[testing.TB]uint64)
+)
+
+// UUID returns a UUID derived from t.Name() and the number of previous
+// UUID calls of the test, so fixtures, golden files and table diffs do not
+// change between runs. UUIDs of different tests do not overlap. It is safe
+// for concurrent use, but the order of calls must be deterministic for
+// UUIDs to be stable.
+func UUID(t testing.TB) string {
+	testUUIDsM.Lock()
+	n, ok := testUUIDs[t]
+	testUUIDs[t] = n + 1
+	testUUIDsM.Unlock()
+
+	if !ok {
+		t.Cleanup(func() {
+			testUUIDsM.Lock()
+			delete(testUUIDs, t)
+			testUUIDsM.Unlock()
+		})
+	}
+	return testUUID(t.Name(), n)
+}
+
+// Returns n-th UUID of test name. It is a version 8 UUID made of SHA-256
+// hash of the name and n.
+func testUUID(name string, n uint64) string {
+	h := sha256.New()
+	_, _ = h.Write([]byte(name))
+	var nb [9]byte
+	binary.BigEndian.PutUint64(nb[1:], n)
+	_, _ = h.Write(nb[:])
+
+	var b [16]byte
+	copy(b[:], h.Sum(nil))
+	b[6] = b[6]&0x0f | 0x80
+	b[8] = b[8]&0x3f | 0x80
+
+	s := hex.EncodeToString(b[:])
+	return s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" +
+		s[20:]
+}
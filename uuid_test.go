@@ -0,0 +1,28 @@
+package go_test_pg
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestUUID(t *testing.T) {
+	re := regexp.MustCompile(
+		`^[0-9a-f]{8}-[0-9a-f]{4}-8[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	first, second := UUID(t), UUID(t)
+	if !re.MatchString(first) || !re.MatchString(second) {
+		t.Fatalf("invalid UUIDs: %v, %v", first, second)
+	}
+	if first == second {
+		t.Fatal("want different UUIDs")
+	}
+	if first != testUUID(t.Name(), 0) || second != testUUID(t.Name(), 1) {
+		t.Fatal("UUIDs are not deterministic")
+	}
+
+	t.Run("sub", func(t *testing.T) {
+		if UUID(t) == first {
+			t.Fatal("want different UUIDs for subtest")
+		}
+	})
+}
@@ -0,0 +1,151 @@
+package go_test_pg
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Header of COPY binary format: signature, flags and header extension
+// length.
+const copyBinaryHeader = "PGCOPY\n\xff\r\n\x00" +
+	"\x00\x00\x00\x00" + "\x00\x00\x00\x00"
+
+// LoadEmbeddings loads vectors into column of type vector of table with
+// COPY in binary format, in order. Other columns get default values. q
+// must be *pgxpool.Pool, *pgxpool.Conn, *pgx.Conn or pgx.Tx.
+func LoadEmbeddings(t testing.TB, q Querier, table, column string,
+	vectors [][]float32) {
+
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	data, err := encodeVectorsCopy(vectors)
+	if err != nil {
+		t.Fatalf("can't encode embeddings: %v", err)
+	}
+
+	sql := `COPY ` + quoteQualified(table) + ` (` + quote(column) +
+		`) FROM STDIN (FORMAT binary)`
+	err = withPgConn(ctx, q, func(conn *pgconn.PgConn) error {
+		_, err := conn.CopyFrom(ctx, bytes.NewReader(data), sql)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("can't load embeddings into %v: %v%v", table, err,
+			querierHint(q))
+	}
+}
+
+// Calls fn with the low-level connection of q.
+func withPgConn(ctx context.Context, q Querier,
+	fn func(conn *pgconn.PgConn) error) error {
+
+	switch q := q.(type) {
+	case *pgxpool.Pool:
+		conn, err := q.Acquire(ctx)
+		if err != nil {
+			return err
+		}
+		defer conn.Release()
+		return fn(conn.Conn().PgConn())
+	case *pgxpool.Conn:
+		return fn(q.Conn().PgConn())
+	case *pgx.Conn:
+		return fn(q.PgConn())
+	case pgx.Tx:
+		return fn(q.Conn().PgConn())
+	default:
+		return fmt.Errorf("unsupported querier %T", q)
+	}
+}
+
+// Returns vectors as a single-column COPY stream in binary format. Binary
+// representation of vector is the number of dimensions (int16), unused
+// int16 and float32 elements.
+func encodeVectorsCopy(vectors [][]float32) ([]byte, error) {
+	buf := bytes.NewBufferString(copyBinaryHeader)
+	for _, v := range vectors {
+		if len(v) == 0 || len(v) > math.MaxInt16 {
+			return nil, fmt.Errorf("invalid vector dimensions: %v",
+				len(v))
+		}
+		_ = binary.Write(buf, binary.BigEndian, int16(1))
+		_ = binary.Write(buf, binary.BigEndian, int32(4+4*len(v)))
+		_ = binary.Write(buf, binary.BigEndian, int16(len(v)))
+		_ = binary.Write(buf, binary.BigEndian, int16(0))
+		_ = binary.Write(buf, binary.BigEndian, v)
+	}
+	_ = binary.Write(buf, binary.BigEndian, int16(-1))
+	return buf.Bytes(), nil
+}
+
+// AssertNearestNeighbors fails the test if the nearest neighbors of vec by
+// L2 distance in column of table do not match want, a list of values of
+// idColumn. The first len(want) neighbors are compared as a set. Index
+// scans are approximate, so at least minRecall fraction of want must be
+// found, e.g. 0.9; 1 requires the exact result.
+func AssertNearestNeighbors(t testing.TB, q Querier, table, idColumn,
+	column string, vec []float32, want []int64, minRecall float64) {
+
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	rows, err := q.Query(ctx, `SELECT `+quote(idColumn)+`::int8 FROM `+
+		quoteQualified(table)+` ORDER BY `+quote(column)+
+		` <-> $1::vector LIMIT $2`, vectorText(vec), len(want))
+	if err != nil {
+		t.Fatalf("can't query nearest neighbors in %v: %v%v", table, err,
+			querierHint(q))
+	}
+	got, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		t.Fatalf("can't query nearest neighbors in %v: %v%v", table, err,
+			querierHint(q))
+	}
+
+	if r := recall(want, got); r < minRecall {
+		t.Fatalf("want nearest neighbors %v, got %v: recall %.2f is below "+
+			"%.2f%v", want, got, r, minRecall, querierHint(q))
+	}
+}
+
+// Returns the fraction of want found in got.
+func recall(want, got []int64) float64 {
+	if len(want) == 0 {
+		return 1
+	}
+	found := make(map[int64]bool, len(got))
+	for _, id := range got {
+		found[id] = true
+	}
+	var n int
+	for _, id := range want {
+		if found[id] {
+			n++
+		}
+	}
+	return float64(n) / float64(len(want))
+}
+
+// Returns text representation of vector, e.g. [1,2.5,3].
+func vectorText(v []float32) string {
+	elems := make([]string, len(v))
+	for i, x := range v {
+		elems[i] = strconv.FormatFloat(float64(x), 'g', -1, 32)
+	}
+	return "[" + strings.Join(elems, ",") + "]"
+}
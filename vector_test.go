@@ -0,0 +1,65 @@
+package go_test_pg
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncodeVectorsCopy(t *testing.T) {
+	data, err := encodeVectorsCopy([][]float32{{1, -2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte(copyBinaryHeader)
+	want = append(want,
+		0, 1, // fields
+		0, 0, 0, 12, // field length
+		0, 2, 0, 0, // dimensions, unused
+		0x3f, 0x80, 0, 0, // 1
+		0xc0, 0, 0, 0, // -2
+		0xff, 0xff, // trailer
+	)
+	if !bytes.Equal(data, want) {
+		t.Fatalf("want %x, got %x", want, data)
+	}
+
+	if _, err = encodeVectorsCopy([][]float32{{}}); err == nil {
+		t.Fatal("expected error on empty vector")
+	}
+}
+
+func TestRecall(t *testing.T) {
+	if r := recall([]int64{1, 2, 3, 4}, []int64{4, 2, 7, 8}); r != 0.5 {
+		t.Fatalf("want recall 0.5, got %v", r)
+	}
+	if r := recall(nil, nil); r != 1 {
+		t.Fatalf("want recall 1, got %v", r)
+	}
+}
+
+func TestVectorText(t *testing.T) {
+	if got := vectorText([]float32{1, 2.5, -0.1}); got != "[1,2.5,-0.1]" {
+		t.Fatalf("unexpected vector text: %v", got)
+	}
+}
+
+func TestLoadEmbeddings(t *testing.T) {
+	var dbPool = Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema_vector.sql",
+		PgVector:   true,
+	}
+	if _, err := dbPool.extensionVersions([]string{"vector"}); err != nil {
+		t.Skip(err)
+	}
+
+	pool := dbPool.WithEmpty(t)
+	LoadEmbeddings(t, pool, "items", "embedding", [][]float32{
+		{0, 0, 0},
+		{1, 1, 1},
+		{10, 10, 10},
+	})
+	AssertRowCount(t, pool, "items", 3)
+	AssertNearestNeighbors(t, pool, "items", "id", "embedding",
+		[]float32{0.9, 1, 1.1}, []int64{2, 1}, 1)
+}
@@ -0,0 +1,156 @@
+package go_test_pg
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Time before the test deadline when the watchdog reports activity of the
+// test database.
+const watchdogMargin = 5 * time.Second
+
+// Activity of a backend connected to the test database.
+type backendActivity struct {
+	pid       int32
+	state     string
+	duration  time.Duration
+	waitEvent string
+	waitLocks string
+	blockedBy []int32
+	query     string
+}
+
+// Starts a timer that logs activity of database dbName shortly before the
+// test deadline, so the reason of a hung test is visible before the test
+// binary panics. The timer is stopped when the test finishes.
+func (p *Pgpool) startWatchdog(t testing.TB, dbName string) {
+	dt, ok := t.(interface{ Deadline() (time.Time, bool) })
+	if !ok {
+		return
+	}
+	deadline, ok := dt.Deadline()
+	if !ok {
+		return
+	}
+	d := time.Until(deadline) - watchdogMargin
+	if d <= 0 {
+		return
+	}
+
+	testName := t.Name()
+	timer := time.AfterFunc(d, func() {
+		p.reportActivity(testName, dbName)
+	})
+	t.Cleanup(func() { timer.Stop() })
+}
+
+func (p *Pgpool) reportActivity(testName, dbName string) {
+	var activity []backendActivity
+	err := p.withNewConnection(
+		dbName,
+		func(ctx context.Context, conn *pgx.Conn) error {
+			var err error
+			activity, err = queryActivity(ctx, conn, dbName, false)
+			return err
+		},
+	)
+	if err != nil {
+		log.Printf("go-test-pg: %v is about to time out, can't get "+
+			"activity of database %v: %v", testName, dbName, err)
+		return
+	}
+	log.Printf("go-test-pg: %v is about to time out, activity of "+
+		"database %v:%v", testName, dbName, formatActivity(activity))
+}
+
+// Returns backends connected to database dbName. Idle backends are
+// returned only if withIdle is set.
+func queryActivity(ctx context.Context, conn *pgx.Conn, dbName string,
+	withIdle bool) ([]backendActivity, error) {
+
+	rows, err := conn.Query(ctx, `
+SELECT a.pid, coalesce(a.state, ''),
+	coalesce((extract(epoch FROM now() - a.state_change) * 1000)::bigint, 0),
+	coalesce(a.wait_event_type || ':' || a.wait_event, ''),
+	coalesce((
+		SELECT string_agg(l.mode || ' on ' ||
+			coalesce(l.relation::regclass::text, l.locktype), ', ')
+		FROM pg_locks l
+		WHERE l.pid = a.pid AND NOT l.granted), ''),
+	pg_blocking_pids(a.pid),
+	coalesce(a.query, '')
+FROM pg_stat_activity a
+WHERE a.datname = $1
+	AND a.pid <> pg_backend_pid()
+	AND ($2 OR a.state <> 'idle')
+ORDER BY a.pid`, dbName, withIdle)
+	if err != nil {
+		return nil, err
+	}
+
+	var activity []backendActivity
+	var a backendActivity
+	var durationMs int64
+	_, err = pgx.ForEachRow(rows,
+		[]any{&a.pid, &a.state, &durationMs, &a.waitEvent, &a.waitLocks,
+			&a.blockedBy, &a.query},
+		func() error {
+			a.duration = time.Duration(durationMs) * time.Millisecond
+			activity = append(activity, a)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return activity, nil
+}
+
+// Returns backends connected to database dbName to append to cleanup
+// errors, so the reason of unreleased connections or a failed drop is
+// visible. Returns empty string if there are none or they can't be
+// queried.
+func (p *Pgpool) activityHint(dbName string) string {
+	var activity []backendActivity
+	err := p.withMasterConnection(
+		func(ctx context.Context, conn *pgx.Conn) error {
+			var err error
+			activity, err = queryActivity(ctx, conn, dbName, true)
+			return err
+		})
+	if err != nil || len(activity) == 0 {
+		return ""
+	}
+	return "\nbackends connected to database " + dbName + ":" +
+		formatActivity(activity)
+}
+
+func formatActivity(activity []backendActivity) string {
+	if len(activity) == 0 {
+		return "\n  no active queries"
+	}
+
+	var b strings.Builder
+	for _, a := range activity {
+		fmt.Fprintf(&b, "\n  pid %v (%v for %v", a.pid, a.state,
+			a.duration)
+		if a.waitEvent != "" {
+			fmt.Fprintf(&b, ", waiting for %v", a.waitEvent)
+		}
+		if a.waitLocks != "" {
+			fmt.Fprintf(&b, ", lock %v", a.waitLocks)
+		}
+		b.WriteString(")")
+		if len(a.blockedBy) != 0 {
+			fmt.Fprintf(&b, " blocked by %v", a.blockedBy)
+		}
+		b.WriteString(": ")
+		b.WriteString(strings.Join(strings.Fields(a.query), " "))
+	}
+	return b.String()
+}
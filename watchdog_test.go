@@ -0,0 +1,70 @@
+package go_test_pg
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatActivity(t *testing.T) {
+	got := formatActivity([]backendActivity{
+		{
+			pid:       10,
+			state:     "active",
+			duration:  3 * time.Second,
+			waitEvent: "Lock:relation",
+			waitLocks: "AccessExclusiveLock on table1",
+			blockedBy: []int32{11},
+			query:     "LOCK TABLE\n\ttable1",
+		},
+		{
+			pid:      11,
+			state:    "idle in transaction",
+			duration: 4 * time.Second,
+			query:    "SELECT * FROM table1",
+		},
+	})
+	want := "\n  pid 10 (active for 3s, waiting for Lock:relation, " +
+		"lock AccessExclusiveLock on table1) blocked by [11]: " +
+		"LOCK TABLE table1" +
+		"\n  pid 11 (idle in transaction for 4s): SELECT * FROM table1"
+	if got != want {
+		t.Fatalf("unexpected output:\n%v", got)
+	}
+
+	if got := formatActivity(nil); got != "\n  no active queries" {
+		t.Fatalf("unexpected output: %v", got)
+	}
+}
+
+func TestPgpool_activityHint(t *testing.T) {
+	var dbPool = Pgpool{BaseName: "go_test_pg"}
+	pool, cleanupFn := dbPool.NewEmpty(t)
+
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = tx.Exec(ctx, `SELECT 42`); err != nil {
+		t.Fatal(err)
+	}
+
+	err = cleanupFn()
+	if !errors.Is(err, ErrUnreleasedConnections) {
+		t.Fatalf("want ErrUnreleasedConnections, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "(idle in transaction for ") ||
+		!strings.Contains(err.Error(), "SELECT 42") {
+		t.Errorf("no activity of the backend in error: %v", err)
+	}
+
+	if err = tx.Rollback(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err = cleanupFn(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,31 @@
+package go_test_pg
+
+import "time"
+
+// Timeout of administrative statements in Yugabyte mode. DDL in a
+// distributed cluster is much slower than in PostgreSQL.
+const yugabyteTimeout = 5 * time.Minute
+
+// Returns timeout of administrative statements and fixture loading.
+func (p *Pgpool) timeout() time.Duration {
+	if p.Yugabyte {
+		return yugabyteTimeout
+	}
+	return defaultTimeout
+}
+
+// Returns CREATE DATABASE statement for database name cloned from template
+// tmplName. If tmplName is empty, the database is created empty.
+func (p *Pgpool) createDatabaseSQL(name, tmplName string) string {
+	query := `CREATE DATABASE ` + quote(name)
+	if tmplName != "" {
+		query += ` WITH TEMPLATE ` + quote(tmplName)
+	}
+	if tablespace := p.opts().tablespace; tablespace != "" {
+		query += ` TABLESPACE ` + quote(tablespace)
+	}
+	if p.Yugabyte && p.Colocated {
+		query += ` COLOCATION = true`
+	}
+	return query
+}
@@ -0,0 +1,44 @@
+package go_test_pg
+
+import "testing"
+
+func TestPgpool_createDatabaseSQL(t *testing.T) {
+	testCases := []struct {
+		pool *Pgpool
+		tmpl string
+		want string
+	}{
+		{&Pgpool{}, "tmpl", `CREATE DATABASE "db" WITH TEMPLATE "tmpl"`},
+		{&Pgpool{Tablespace: "ram"}, "",
+			`CREATE DATABASE "db" TABLESPACE "ram"`},
+		{&Pgpool{Colocated: true}, "", `CREATE DATABASE "db"`},
+		{&Pgpool{Yugabyte: true, Colocated: true}, "",
+			`CREATE DATABASE "db" COLOCATION = true`},
+	}
+	for _, tc := range testCases {
+		if got := tc.pool.createDatabaseSQL("db", tc.tmpl); got != tc.want {
+			t.Errorf("want %v, got %v", tc.want, got)
+		}
+	}
+}
+
+func TestPgpool_Yugabyte(t *testing.T) {
+	p := Pgpool{
+		BaseName:   "go_test_pg",
+		SchemaFile: "./testdata/schema1.sql",
+		Yugabyte:   true,
+	}
+	if p.timeout() != yugabyteTimeout {
+		t.Fatalf("want timeout %v, got %v", yugabyteTimeout, p.timeout())
+	}
+
+	// Template database is not created in Yugabyte mode, so its name is
+	// known without connecting to the server.
+	tmpl, err := p.createTemplateDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmpl == "" || tmpl == "template1" {
+		t.Fatalf("unexpected template name %v", tmpl)
+	}
+}